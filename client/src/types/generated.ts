@@ -6,14 +6,49 @@ export type ServerMessage =
 	| CountdownMessage
 	| BetAcceptedMessage
 	| BetRejectedMessage
+	| BetsAcceptedMessage
+	| BetsRejectedMessage
 	| ResultMessage
 	| BetPlacedMessage
+	| BetRemovedMessage
+	| ClientSeedMessage
 	| PlayerListMessage
 	| PlayerJoinedMessage
 	| PlayerLeftMessage
 	| PlayerBalanceUpdatedMessage
-	| SessionExpiredMessage;
-export type ClientMessage = PlaceBetAction | SetNameAction | ReconnectAction;
+	| RoundVoidedMessage
+	| DealerAckMessage
+	| HistoryMessage
+	| SessionExpiredMessage
+	| TablesMessage
+	| TableLeftMessage
+	| TableRejectedMessage
+	| PrivateTableMessage
+	| WatchingMessage
+	| SpectatorsMessage
+	| RoundResultMessage
+	| QueuePositionMessage;
+export type ClientMessage =
+	| PlaceBetAction
+	| PlaceBetsAction
+	| SetClientSeedAction
+	| CancelBetAction
+	| UndoLastBetAction
+	| ClearBetsAction
+	| RebetAction
+	| RebetDoubleAction
+	| SetNameAction
+	| DealerResultAction
+	| DealerVoidAction
+	| ReconnectAction
+	| ListTablesAction
+	| JoinTableAction
+	| LeaveTableAction
+	| CreateTableAction
+	| UpdateTableAction
+	| KickPlayerAction
+	| CloseTableAction
+	| WatchTableAction;
 
 //////////
 // source: messages.go
@@ -24,7 +59,45 @@ export type ClientMessage = PlaceBetAction | SetNameAction | ReconnectAction;
 export const GamePhaseBetting = "BETTING";
 export const GamePhaseSpinning = "SPINNING";
 export const GamePhaseResult = "RESULT";
-export type GamePhase = typeof GamePhaseBetting | typeof GamePhaseSpinning | typeof GamePhaseResult;
+/**
+ * GamePhasePaused holds the table between rounds after the RNG monitor
+ * flagged the wheel's outcomes, until an admin resumes it.
+ */
+export const GamePhasePaused = "PAUSED";
+export type GamePhase =
+	| typeof GamePhaseBetting
+	| typeof GamePhaseSpinning
+	| typeof GamePhaseResult
+	| typeof GamePhasePaused;
+/**
+ * WheelVariant names the roulette wheel a table spins.
+ */
+export const WheelVariantEuropean = "european";
+export const WheelVariantAmerican = "american";
+export const WheelVariantTripleZero = "triple_zero";
+export type WheelVariant =
+	| typeof WheelVariantEuropean
+	| typeof WheelVariantAmerican
+	| typeof WheelVariantTripleZero;
+/**
+ * ZeroRule is the house rule for even-money bets when a zero comes up.
+ */
+export const ZeroRuleNone = "none";
+export const ZeroRuleLaPartage = "la_partage";
+export const ZeroRuleEnPrison = "en_prison";
+export type ZeroRule = typeof ZeroRuleNone | typeof ZeroRuleLaPartage | typeof ZeroRuleEnPrison;
+/**
+ * PayoutOutcome marks payouts that a zero rule changed.
+ */
+export const PayoutOutcomeHalved = "halved"; // La Partage refunded half the stake
+export const PayoutOutcomeImprisoned = "imprisoned"; // En Prison holds the bet for the next spin
+export const PayoutOutcomeReleased = "released"; // an imprisoned bet won and its stake is returned
+export const PayoutOutcomeForfeited = "forfeited"; // an imprisoned bet lost
+export type PayoutOutcome =
+	| typeof PayoutOutcomeHalved
+	| typeof PayoutOutcomeImprisoned
+	| typeof PayoutOutcomeReleased
+	| typeof PayoutOutcomeForfeited;
 /**
  * BetType represents the type of bet a player can place.
  */
@@ -32,26 +105,92 @@ export const BetTypeStraight = "straight";
 export const BetTypeColor = "color";
 export const BetTypeEvenOdd = "even_odd";
 export const BetTypeDozens = "dozens";
+export const BetTypeColumns = "columns";
+export const BetTypeHighLow = "high_low";
+export const BetTypeSplit = "split";
+export const BetTypeStreet = "street";
+export const BetTypeCorner = "corner";
+export const BetTypeSixLine = "six_line";
+export const BetTypeTopLine = "top_line";
+/**
+ * Call (racetrack) bets expand into the inside bets above.
+ */
+export const BetTypeVoisins = "voisins";
+export const BetTypeTiers = "tiers";
+export const BetTypeOrphelins = "orphelins";
+export const BetTypeNeighbours = "neighbours";
+/**
+ * Finale bets cover numbers by their last digit and also expand into inside bets.
+ */
+export const BetTypeFinaleEnPlein = "finale_en_plein";
+export const BetTypeFinaleACheval = "finale_a_cheval";
 export type BetType =
 	| typeof BetTypeStraight
 	| typeof BetTypeColor
 	| typeof BetTypeEvenOdd
-	| typeof BetTypeDozens;
+	| typeof BetTypeDozens
+	| typeof BetTypeColumns
+	| typeof BetTypeHighLow
+	| typeof BetTypeSplit
+	| typeof BetTypeStreet
+	| typeof BetTypeCorner
+	| typeof BetTypeSixLine
+	| typeof BetTypeTopLine
+	| typeof BetTypeVoisins
+	| typeof BetTypeTiers
+	| typeof BetTypeOrphelins
+	| typeof BetTypeNeighbours
+	| typeof BetTypeFinaleEnPlein
+	| typeof BetTypeFinaleACheval;
+/**
+ * BetRejectCode says why a bet was rejected, so clients can explain which limit was hit.
+ */
+export const BetRejectInvalidBet = "invalid_bet";
+export const BetRejectBettingClosed = "betting_closed";
+export const BetRejectInsufficientBalance = "insufficient_balance";
+export const BetRejectBelowMinimum = "below_minimum";
+export const BetRejectAboveMaximum = "above_maximum";
+export const BetRejectPlayerStakeLimit = "player_stake_limit";
+export const BetRejectTableLiabilityLimit = "table_liability_limit";
+export const BetRejectBetNotFound = "bet_not_found";
+export const BetRejectNoPreviousBets = "no_previous_bets";
+export const BetRejectTooManyBets = "too_many_bets";
+export type BetRejectCode =
+	| typeof BetRejectInvalidBet
+	| typeof BetRejectBettingClosed
+	| typeof BetRejectInsufficientBalance
+	| typeof BetRejectBelowMinimum
+	| typeof BetRejectAboveMaximum
+	| typeof BetRejectPlayerStakeLimit
+	| typeof BetRejectTableLiabilityLimit
+	| typeof BetRejectBetNotFound
+	| typeof BetRejectNoPreviousBets
+	| typeof BetRejectTooManyBets;
 /**
- * Bet represents a single bet placed by a user.
+ * Bet represents a single bet placed by a user. ID is assigned by the server.
+ * A call bet lists the chips it expanded into as Components; its Amount is the total stake
+ * and ChipAmount the stake on each chip.
  */
 export interface Bet {
+	id?: string;
 	user_id: string;
 	type: BetType;
 	value: string;
 	amount: number /* int64 */;
+	chip_amount?: number /* int64 */;
+	components?: Bet[];
 }
 /**
  * Payout represents the result of a bet after a spin.
+ * Refund is stake returned by a zero rule, on top of any winnings.
+ * A call bet's payout groups one payout per chip under Components.
  */
 export interface Payout {
 	bet: Bet;
 	winnings: number /* int64 */;
+	refund?: number /* int64 */;
+	outcome?: PayoutOutcome;
+	components?: Payout[];
 }
 /**
  * Player represents a connected player at the table.
@@ -62,18 +201,104 @@ export interface Player {
 	balance: number /* int64 */;
 	connected: boolean;
 }
+/**
+ * TableInfo describes a table in the lobby. MaxSeats is 0 for a table
+ * without a seat limit; Queued is how many are waiting for a seat.
+ */
+export interface TableInfo {
+	id: string;
+	name: string;
+	variant: WheelVariant;
+	zero_rule: ZeroRule;
+	live_dealer: boolean;
+	state: GamePhase;
+	players: number /* int */;
+	spectators: number /* int */;
+	max_seats: number /* int */;
+	queued: number /* int */;
+}
+/**
+ * TablesResponse is the body of GET /tables. It lists the public tables.
+ */
+export interface TablesResponse {
+	tables: TableInfo[];
+}
+/**
+ * TableSettings are what the owner of a private table chooses. A MaxSeats
+ * of 0 means no limit, and 0 seconds keeps a phase's default length.
+ */
+export interface TableSettings {
+	password?: string;
+	max_seats: number /* int */;
+	betting_seconds: number /* int */;
+	spinning_seconds: number /* int */;
+	result_seconds: number /* int */;
+}
+/**
+ * WelcomeMessage seats the player at table TableID. OwnerID is set at a
+ * private table.
+ */
 export interface WelcomeMessage {
 	type: "welcome";
 	user_id: string;
 	session_token: string;
+	table_id: string;
+	owner_id?: string;
 	balance: number /* int64 */;
 	players: Player[];
+	spectators: number /* int */;
+	variant: WheelVariant;
+	zero_rule: ZeroRule;
+	live_dealer: boolean;
 }
+/**
+ * Pocket numbers above 36 encode extra zeros (37 is "00", 38 is "000");
+ * WinningPocket carries the label players should see.
+ */
 export interface GameStateMessage {
 	type: "game_state";
 	state: GamePhase;
 	winning_number?: number /* int */;
+	winning_pocket?: string;
 	countdown?: number /* int */;
+	/**
+	 * ServerSeedHash commits to the current round's server seed; Nonce is the round number.
+	 */
+	server_seed_hash?: string;
+	nonce?: number /* int64 */;
+	/**
+	 * Trajectory is sent with SPINNING when the table simulates its wheel.
+	 */
+	trajectory?: Trajectory;
+	/**
+	 * Reason says why the table is PAUSED.
+	 */
+	reason?: string;
+}
+/**
+ * Trajectory is a server-simulated spin for clients to animate. It ends with
+ * the ball in the winning pocket.
+ * Angles are in degrees clockwise from a fixed mark on the stator and are
+ * cumulative, so clients can interpolate between keyframes and take them
+ * modulo 360 to draw. RotorAngle is where the start of the first pocket in
+ * Pockets sits; the rest follow clockwise, each 360/len(Pockets) wide.
+ * BallRadius is a fraction of the ball track's radius: 1 on the track, 0.8
+ * at the diamonds and 0.6 in a pocket.
+ */
+export interface Trajectory {
+	pockets: string[];
+	drop: number /* float64 */; // seconds until the ball leaves the track
+	landed: number /* float64 */; // seconds until the ball settles
+	keyframes: TrajectoryKeyframe[];
+}
+/**
+ * TrajectoryKeyframe is the wheel T seconds after the spin started.
+ */
+export interface TrajectoryKeyframe {
+	t: number /* float64 */;
+	rotor_angle: number /* float64 */;
+	ball_angle: number /* float64 */;
+	ball_radius: number /* float64 */;
 }
 export interface CountdownMessage {
 	type: "countdown";
@@ -82,29 +307,353 @@ export interface CountdownMessage {
 }
 export interface BetAcceptedMessage {
 	type: "bet_accepted";
+	bet_id: string;
 	bet_type: BetType;
 	bet_value: string;
 	amount: number /* int64 */;
+	components?: Bet[];
 	balance: number /* int64 */;
 }
 export interface BetRejectedMessage {
 	type: "bet_rejected";
+	code: BetRejectCode;
+	reason: string;
+}
+/**
+ * BetsAcceptedMessage confirms a place_bets batch. Bets are in the order
+ * they were sent, each with its server-assigned ID.
+ */
+export interface BetsAcceptedMessage {
+	type: "bets_accepted";
+	bets: Bet[];
+	balance: number /* int64 */;
+}
+/**
+ * BetsRejectedMessage rejects a whole place_bets batch. Index is the
+ * position of the bet that failed, or absent when the batch as a whole was
+ * refused (e.g. the summed stake exceeds the balance).
+ */
+export interface BetsRejectedMessage {
+	type: "bets_rejected";
+	index?: number /* int */;
+	code: BetRejectCode;
 	reason: string;
 }
 export interface ResultMessage {
 	type: "result";
 	winning_number: number /* int */;
+	winning_pocket: string;
 	payouts: Payout[];
 	total_won: number /* int64 */;
 	balance: number /* int64 */;
+	/**
+	 * Fairness reveals the round's server seed so the spin can be verified.
+	 */
+	fairness?: FairnessReveal;
+}
+/**
+ * FairnessReveal discloses a settled round's seeds. The SHA-256 of ServerSeed
+ * (hex) is ServerSeedHash, published when betting opened. The pocket comes
+ * from an HMAC-SHA256 keyed with the server seed over the client seeds joined
+ * by commas, a colon and the nonce.
+ */
+export interface FairnessReveal {
+	server_seed: string;
+	server_seed_hash: string;
+	client_seeds: string[];
+	nonce: number /* int64 */;
+}
+/**
+ * ClientSeedMessage tells a player which client seed they contribute to spins.
+ * Reason is set when a requested seed was rejected.
+ */
+export interface ClientSeedMessage {
+	type: "client_seed";
+	client_seed: string;
+	reason?: string;
+}
+/**
+ * FairnessVerifyRequest is the body of POST /fairness/verify.
+ */
+export interface FairnessVerifyRequest {
+	variant: WheelVariant;
+	server_seed: string;
+	server_seed_hash: string;
+	client_seeds: string[];
+	nonce: number /* int64 */;
+}
+/**
+ * FairnessVerifyResponse reports whether a revealed seed matches its hash and,
+ * if so, the pocket the round must have landed on.
+ */
+export interface FairnessVerifyResponse {
+	valid: boolean;
+	winning_number?: number /* int */;
+	winning_pocket?: string;
+	reason?: string;
+}
+/**
+ * Round is a round as kept in the history. A voided live round has no
+ * winning pocket and its bets were refunded. Payouts include any En Prison
+ * bets from the round before that were settled in this one.
+ */
+export interface Round {
+	id: number /* int64 */;
+	variant: WheelVariant;
+	started_at: string /* RFC3339 */;
+	ended_at: string /* RFC3339 */;
+	winning_number?: number /* int */;
+	winning_pocket?: string;
+	voided?: boolean;
+	void_reason?: string;
+	bets: Bet[];
+	payouts: Payout[];
+}
+/**
+ * RoundsResponse is the body of GET /rounds, newest first. Pass the ID of
+ * the last round as before to get the next page.
+ */
+export interface RoundsResponse {
+	rounds: Round[];
+}
+/**
+ * RoundResult is where one round landed.
+ */
+export interface RoundResult {
+	round_id: number /* int64 */;
+	winning_number: number /* int */;
+	winning_pocket: string;
+}
+/**
+ * HistoryMessage is sent on connect with the latest results, newest first.
+ */
+export interface HistoryMessage {
+	type: "history";
+	results: RoundResult[];
+}
+/**
+ * TransactionKind says why a balance changed.
+ */
+export const TransactionOpening = "opening"; // starting balance for a new user
+export const TransactionBet = "bet"; // stake placed on a bet
+export const TransactionWin = "win"; // winnings plus the returned stake
+export const TransactionRefund = "refund"; // stake returned: cancelled bet, voided round or zero rule
+export const TransactionRefill = "refill"; // top-up after the balance hit zero
+export const TransactionAdjustment = "adjustment"; // made by an admin
+export type TransactionKind =
+	| typeof TransactionOpening
+	| typeof TransactionBet
+	| typeof TransactionWin
+	| typeof TransactionRefund
+	| typeof TransactionRefill
+	| typeof TransactionAdjustment;
+/**
+ * Transaction is one entry in the balance ledger. Each moves Amount from
+ * the Debit account to the Credit account: a user's ID or "house", so every
+ * transaction balances. BalanceBefore and BalanceAfter are the user's.
+ * RoundID is a round at table TableID.
+ */
+export interface Transaction {
+	id: number /* int64 */;
+	user_id: string;
+	table_id?: string;
+	round_id?: number /* int64 */;
+	kind: TransactionKind;
+	debit: string;
+	credit: string;
+	amount: number /* int64 */;
+	balance_before: number /* int64 */;
+	balance_after: number /* int64 */;
+	bet_id?: string;
+	note?: string;
+	created_at: string /* RFC3339 */;
+}
+/**
+ * TransactionsResponse is the body of GET /users/{id}/transactions, newest
+ * first. Pass the ID of the last transaction as before to get the next page.
+ */
+export interface TransactionsResponse {
+	transactions: Transaction[];
+}
+/**
+ * AdjustBalanceRequest is the body of POST /admin/users/{id}/adjust. Amount
+ * is added to the balance; a negative amount takes money off.
+ */
+export interface AdjustBalanceRequest {
+	amount: number /* int64 */;
+	note: string;
+}
+/**
+ * LedgerMismatch is a user whose balance differs from the sum of their
+ * ledger entries.
+ */
+export interface LedgerMismatch {
+	user_id: string;
+	balance: number /* int64 */;
+	ledger_balance: number /* int64 */;
+}
+/**
+ * ReconcileResponse is the body of GET /admin/ledger/reconcile.
+ */
+export interface ReconcileResponse {
+	checked: number /* int */;
+	mismatches: LedgerMismatch[];
+}
+/**
+ * EventType names a change to the table's state.
+ */
+export const EventPhaseChanged = "phase_changed"; // the round moved to Phase
+export const EventBetPlaced = "bet_placed"; // Bet was placed
+export const EventBetCancelled = "bet_cancelled"; // Bet was taken back and its stake refunded
+export const EventWheelSpun = "wheel_spun"; // the round landed on WinningNumber
+export const EventPayoutCredited = "payout_credited"; // a win or refund on BetID was paid
+export const EventUserRegistered = "user_registered"; // a new user opened with the starting balance
+export const EventUserJoined = "user_joined"; // a user sat down at the table with Amount
+export const EventUserLeft = "user_left"; // a user left the table
+export const EventBalanceRefilled = "balance_refilled"; // a broke user was topped up
+export const EventBalanceAdjusted = "balance_adjusted"; // an admin changed a balance
+export type EventType =
+	| typeof EventPhaseChanged
+	| typeof EventBetPlaced
+	| typeof EventBetCancelled
+	| typeof EventWheelSpun
+	| typeof EventPayoutCredited
+	| typeof EventUserRegistered
+	| typeof EventUserJoined
+	| typeof EventUserLeft
+	| typeof EventBalanceRefilled
+	| typeof EventBalanceAdjusted;
+/**
+ * Event is one change to the table's state, in the order Seq gives. Amount
+ * is what the event adds to UserID's balance, negative for a stake, except
+ * for user_joined, where it is the balance they sit down with.
+ */
+export interface Event {
+	seq: number /* int64 */;
+	type: EventType;
+	round: number /* int64 */;
+	at: string /* RFC3339 */;
+	phase?: GamePhase;
+	user_id?: string;
+	bet?: Bet;
+	bet_id?: string;
+	winning_number?: number /* int */;
+	amount?: number /* int64 */;
+}
+/**
+ * TableState is the table as rebuilt from its events: the current round,
+ * the bets in it and the balance of every user at the table. Seq is the
+ * last event applied.
+ */
+export interface TableState {
+	seq: number /* int64 */;
+	round: number /* int64 */;
+	phase: GamePhase;
+	winning_number?: number /* int */;
+	bets: Bet[];
+	balances: { [key: string]: number /* int64 */};
+}
+/**
+ * EventsResponse is the body of GET /admin/events, oldest first. Pass the
+ * Seq of the last event as after to get the next page.
+ */
+export interface EventsResponse {
+	events: Event[];
+}
+/**
+ * RoundReplay is the body of GET /admin/rounds/{id}/replay: the table as the
+ * round opened, its events, and the table they leave behind.
+ */
+export interface RoundReplay {
+	round: number /* int64 */;
+	start: TableState;
+	events: Event[];
+	end: TableState;
+}
+/**
+ * RNGTest names a statistical test the RNG monitor runs on the outcomes.
+ */
+export const RNGTestChiSquare = "chi_square"; // every pocket comes up equally often
+export const RNGTestRuns = "runs"; // low and high pockets don't cluster or alternate
+export const RNGTestGap = "gap"; // spins between repeats of a pocket are geometric
+export type RNGTest =
+	| typeof RNGTestChiSquare
+	| typeof RNGTestRuns
+	| typeof RNGTestGap;
+/**
+ * RNGTestResult is one test over the most recent Window spins. PValue is the
+ * chance an unbiased wheel would look at least this far off; the test fails
+ * when it is below the monitor's alpha. Failures counts consecutive failures.
+ */
+export interface RNGTestResult {
+	test: RNGTest;
+	window: number /* int */;
+	statistic: number /* float64 */;
+	p_value: number /* float64 */;
+	passed: boolean;
+	failures: number /* int */;
+}
+/**
+ * RNGReport is the body of GET /admin/rng: the latest result of each test
+ * over each window, and whether the monitor has paused the table.
+ */
+export interface RNGReport {
+	spins: number /* int64 */;
+	alpha: number /* float64 */;
+	tolerance: number /* int */;
+	paused: boolean;
+	reason?: string;
+	results: RNGTestResult[];
 }
 export interface BetPlacedMessage {
 	type: "bet_placed";
+	bet_id: string;
 	user_id: string;
 	player_name: string;
 	bet_type: BetType;
 	bet_value: string;
 	amount: number /* int64 */;
+	components?: Bet[];
+}
+/**
+ * BetRemovedMessage tells every client which of a player's bets were taken off the table.
+ */
+export interface BetRemovedMessage {
+	type: "bet_removed";
+	user_id: string;
+	bet_ids: string[];
+	balance: number /* int64 */;
+}
+/**
+ * RoundVoidedMessage tells a player the round was called off without a
+ * result and their bets in it (Refunded in total) were returned.
+ */
+export interface RoundVoidedMessage {
+	type: "round_voided";
+	reason: string;
+	refunded: number /* int64 */;
+	balance: number /* int64 */;
+}
+/**
+ * DealerStep is a croupier action in live dealer mode.
+ */
+export const DealerStepEnter = "enter";
+export const DealerStepConfirm = "confirm";
+export const DealerStepVoid = "void";
+export type DealerStep =
+	| typeof DealerStepEnter
+	| typeof DealerStepConfirm
+	| typeof DealerStepVoid;
+/**
+ * DealerAckMessage answers a croupier action, over WebSocket or HTTP.
+ * Pocket is the result that was entered or confirmed.
+ */
+export interface DealerAckMessage {
+	type: "dealer_ack";
+	step: DealerStep;
+	ok: boolean;
+	pocket?: string;
+	reason?: string;
 }
 export interface PlayerListMessage {
 	type: "player_list";
@@ -127,19 +676,241 @@ export interface SessionExpiredMessage {
 	type: "session_expired";
 	reason: string;
 }
+/**
+ * TablesMessage answers list_tables.
+ */
+export interface TablesMessage {
+	type: "tables";
+	tables: TableInfo[];
+}
+/**
+ * TableLeftMessage puts the player back in the lobby: after leave_table, or
+ * with a Reason when the table's owner kicked them or the table closed. A
+ * player waiting for a seat gets one too if the table closes.
+ */
+export interface TableLeftMessage {
+	type: "table_left";
+	table_id: string;
+	reason?: string;
+}
+/**
+ * PrivateTableMessage tells the owner of a private table how others join it
+ * and what it is set to, after create_table and update_table.
+ */
+export interface PrivateTableMessage {
+	type: "private_table";
+	table_id: string;
+	invite_code: string;
+	settings: TableSettings;
+}
+/**
+ * QueuePositionMessage tells a player turned away from a full table where
+ * they stand in line for a seat there, when they join the line and as it
+ * moves. They are seated, with a welcome, at the end of a round once a
+ * seat is free.
+ */
+export interface QueuePositionMessage {
+	type: "queue_position";
+	table_id: string;
+	position: number /* int */;
+}
+/**
+ * WatchingMessage answers watch_table: the spectator is now watching the
+ * table, followed by its game_state and history as a player would get.
+ */
+export interface WatchingMessage {
+	type: "watching";
+	table_id: string;
+	players: Player[];
+	spectators: number /* int */;
+	variant: WheelVariant;
+	zero_rule: ZeroRule;
+	live_dealer: boolean;
+}
+/**
+ * SpectatorsMessage tells the table how many are watching it. It is sent
+ * at the start of a round when the count has changed.
+ */
+export interface SpectatorsMessage {
+	type: "spectators";
+	count: number /* int */;
+}
+/**
+ * RoundResultMessage shows spectators a round's result. Players get their
+ * own ResultMessage instead. TotalWon is what the round returned to every
+ * player at the table.
+ */
+export interface RoundResultMessage {
+	type: "round_result";
+	winning_number: number /* int */;
+	winning_pocket: string;
+	total_won: number /* int64 */;
+	/**
+	 * Fairness reveals the round's server seed so the spin can be verified.
+	 */
+	fairness?: FairnessReveal;
+}
+/**
+ * TableRejectedMessage says why the player couldn't join or leave a table.
+ */
+export interface TableRejectedMessage {
+	type: "table_rejected";
+	table_id: string;
+	reason: string;
+}
 export interface PlaceBetAction {
 	action: "place_bet";
 	bet_type: BetType;
 	bet_value: string;
 	amount: number /* int64 */;
 }
+/**
+ * BetSpec is one bet in a place_bets batch.
+ */
+export interface BetSpec {
+	bet_type: BetType;
+	bet_value: string;
+	amount: number /* int64 */;
+}
+export interface PlaceBetsAction {
+	action: "place_bets";
+	bets: BetSpec[];
+}
+export interface SetClientSeedAction {
+	action: "set_client_seed";
+	client_seed: string;
+}
+export interface CancelBetAction {
+	action: "cancel_bet";
+	bet_id: string;
+}
+export interface UndoLastBetAction {
+	action: "undo_last_bet";
+}
+export interface ClearBetsAction {
+	action: "clear_bets";
+}
+export interface RebetAction {
+	action: "rebet";
+}
+export interface RebetDoubleAction {
+	action: "rebet_double";
+}
+/**
+ * SetNameAction joins as a new player at TableID, or the first table if
+ * it is empty. A private table is joined by InviteCode instead, with its
+ * Password if it has one.
+ */
 export interface SetNameAction {
 	action: "set_name";
 	name: string;
+	table_id?: string;
+	invite_code?: string;
+	password?: string;
+}
+/**
+ * DealerRequest is the body of POST /admin/dealer.
+ */
+export interface DealerRequest {
+	step: DealerStep;
+	pocket: string;
+	reason: string;
+}
+/**
+ * DealerResultAction enters (step "enter") or confirms (step "confirm") the
+ * winning pocket in live dealer mode at table TableID, or else the table the
+ * connection is at or watching, or the first table. The two entries must
+ * match. The croupier doesn't need a seat.
+ */
+export interface DealerResultAction {
+	action: "dealer_result";
+	admin_token: string;
+	table_id?: string;
+	step: DealerStep;
+	pocket: string;
+}
+/**
+ * DealerVoidAction calls off the current live round at the same table as
+ * DealerResultAction and refunds its bets.
+ */
+export interface DealerVoidAction {
+	action: "dealer_void";
+	admin_token: string;
+	table_id?: string;
+	reason: string;
 }
+/**
+ * ReconnectAction returns a player to the table they were at, or else to
+ * TableID, or the first table if it is empty.
+ */
 export interface ReconnectAction {
 	action: "reconnect";
 	user_id: string;
 	session_token: string;
 	name: string;
+	table_id?: string;
+}
+export interface ListTablesAction {
+	action: "list_tables";
+}
+/**
+ * JoinTableAction moves the player to another table, or seats them from
+ * the lobby. A private table is joined by InviteCode instead of TableID,
+ * with its Password if it has one.
+ */
+export interface JoinTableAction {
+	action: "join_table";
+	table_id?: string;
+	invite_code?: string;
+	password?: string;
+}
+/**
+ * LeaveTableAction takes the player, or a spectator, back to the lobby, or
+ * out of line for a seat. It is refused while a player has bets in play.
+ */
+export interface LeaveTableAction {
+	action: "leave_table";
+}
+/**
+ * CreateTableAction opens a private table owned by the player and moves
+ * them to it.
+ */
+export interface CreateTableAction {
+	action: "create_table";
+	name: string;
+	settings: TableSettings;
+}
+/**
+ * UpdateTableAction changes the settings of the private table the player
+ * owns. New phase lengths apply from the next phase.
+ */
+export interface UpdateTableAction {
+	action: "update_table";
+	settings: TableSettings;
+}
+/**
+ * KickPlayerAction sends a player at the owner's private table back to the
+ * lobby. They can't rejoin it.
+ */
+export interface KickPlayerAction {
+	action: "kick_player";
+	user_id: string;
+}
+/**
+ * WatchTableAction watches a table as a spectator, without a seat or a
+ * balance. A seated player gives up their seat to watch. A private table is
+ * watched by InviteCode instead of TableID, with its Password if it has one.
+ */
+export interface WatchTableAction {
+	action: "watch_table";
+	table_id?: string;
+	invite_code?: string;
+	password?: string;
+}
+/**
+ * CloseTableAction closes the owner's private table once no bets are in
+ * play, sending everyone at it back to the lobby.
+ */
+export interface CloseTableAction {
+	action: "close_table";
 }
@@ -17,9 +17,16 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	server := handlers.NewServer(cfg.AllowedOrigins)
+	server, err := handlers.NewServer(cfg)
+	if err != nil {
+		slog.Error("Server failed to start", "error", err)
+		os.Exit(1)
+	}
 
-	slog.Info("Roulette Server starting", "port", cfg.Port, "allowedOrigins", cfg.AllowedOrigins)
+	slog.Info("Roulette Server starting", "port", cfg.Port, "allowedOrigins", cfg.AllowedOrigins, "tables", len(cfg.Tables))
+	for _, t := range cfg.Tables {
+		slog.Info("Table configured", "table_id", t.ID, "wheelVariant", t.WheelVariant, "zeroRule", t.ZeroRule, "liveDealer", t.LiveDealer)
+	}
 	if err := server.Start(ctx, ":"+cfg.Port); err != nil {
 		slog.Error("Server failed", "error", err)
 		os.Exit(1)
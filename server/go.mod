@@ -7,4 +7,9 @@ require (
 	github.com/go-chi/cors v1.2.2
 )
 
-require github.com/coder/websocket v1.8.14
+require (
+	github.com/coder/websocket v1.8.14
+	go.etcd.io/bbolt v1.4.3
+)
+
+require golang.org/x/sys v0.29.0 // indirect
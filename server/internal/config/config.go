@@ -1,13 +1,199 @@
 package config
 
 import (
+	"encoding/json"
+	"log/slog"
+	"maps"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// BetLimit is a stake range for one bet type. Zero means no bound.
+type BetLimit struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// TableConfig sets up one table. Zero phase durations keep the defaults,
+// and zero MaxSeats seats any number of players.
+type TableConfig struct {
+	ID              string              `json:"id"`
+	Name            string              `json:"name"`
+	WheelVariant    string              `json:"wheel_variant"`
+	ZeroRule        string              `json:"zero_rule"`
+	BetLimits       map[string]BetLimit `json:"bet_limits"`
+	MaxPlayerStake  int64               `json:"max_player_stake"`
+	MaxLiability    int64               `json:"max_liability"`
+	PhysicsWheel    bool                `json:"physics_wheel"`
+	LiveDealer      bool                `json:"live_dealer"`
+	BettingSeconds  int                 `json:"betting_seconds"`
+	SpinningSeconds int                 `json:"spinning_seconds"`
+	ResultSeconds   int                 `json:"result_seconds"`
+	MaxSeats        int                 `json:"max_seats"`
+}
+
 type Config struct {
-	Port           string
-	AllowedOrigins []string
+	Port             string
+	AllowedOrigins   []string
+	AdminToken       string
+	DealerTimeout    time.Duration
+	RNGMonitor       bool
+	RNGWindows       []int
+	RNGInterval      int
+	RNGAlpha         float64
+	RNGTolerance     int
+	DBPath           string
+	Tables           []TableConfig
+	PrivateTableIdle time.Duration
+	SeatGrace        time.Duration
+	ScriptedSpins    []string // pocket labels every table lands on in turn; development only
+}
+
+// envInt64 reads a non-negative integer from the environment, returning 0 if unset or invalid.
+func envInt64(name string) int64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < 0 {
+		slog.Warn("ignoring invalid integer setting", "name", name, "value", raw)
+		return 0
+	}
+	return n
+}
+
+// envBool reads a boolean from the environment, returning false if unset or invalid.
+func envBool(name string) bool {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		slog.Warn("ignoring invalid boolean setting", "name", name, "value", raw)
+		return false
+	}
+	return b
+}
+
+// envFloat reads a positive number from the environment, returning 0 if unset or invalid.
+func envFloat(name string) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f <= 0 {
+		slog.Warn("ignoring invalid number setting", "name", name, "value", raw)
+		return 0
+	}
+	return f
+}
+
+// parseWindows parses a comma-separated list of positive spin counts,
+// e.g. "370,3700". Invalid entries are skipped.
+func parseWindows(raw string) []int {
+	var windows []int
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		n, err := strconv.Atoi(entry)
+		if err != nil || n <= 0 {
+			slog.Warn("ignoring invalid RNG monitor window", "entry", entry)
+			continue
+		}
+		windows = append(windows, n)
+	}
+	return windows
+}
+
+// parseBetLimits parses "type:min:max" entries separated by commas,
+// e.g. "straight:10:5000,color:100:100000". Invalid entries, and those
+// with a min above the max, are skipped.
+func parseBetLimits(raw string) map[string]BetLimit {
+	limits := make(map[string]BetLimit)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ":")
+		if len(fields) != 3 {
+			slog.Warn("ignoring invalid bet limit", "entry", entry)
+			continue
+		}
+		lo, errMin := strconv.ParseInt(fields[1], 10, 64)
+		hi, errMax := strconv.ParseInt(fields[2], 10, 64)
+		if errMin != nil || errMax != nil || lo < 0 || hi < 0 {
+			slog.Warn("ignoring invalid bet limit", "entry", entry)
+			continue
+		}
+		// A max of 0 means no bound; any other below the min would leave
+		// no stake the table accepts.
+		if hi > 0 && lo > hi {
+			slog.Warn("ignoring bet limit with min above max", "entry", entry)
+			continue
+		}
+		limits[fields[0]] = BetLimit{Min: lo, Max: hi}
+	}
+	return limits
+}
+
+// loadTables reads a JSON array of tables from path. Each table starts from
+// base, so it only needs the settings where it differs. Returns nil if the
+// file can't be read or a table has no ID.
+func loadTables(path string, base TableConfig) []TableConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		slog.Warn("ignoring unreadable tables file", "path", path, "error", err)
+		return nil
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		slog.Warn("ignoring invalid tables file", "path", path, "error", err)
+		return nil
+	}
+	tables := make([]TableConfig, 0, len(raw))
+	for _, r := range raw {
+		t := base
+		t.BetLimits = maps.Clone(base.BetLimits)
+		if err := json.Unmarshal(r, &t); err != nil || t.ID == "" {
+			slog.Warn("ignoring invalid tables file", "path", path, "entry", string(r))
+			return nil
+		}
+		if t.Name == "" {
+			t.Name = t.ID
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// scriptedSpins reads SCRIPTED_SPINS, a comma-separated list of pockets
+// such as "17,0,32" for the tables to land on in turn instead of spinning.
+// It is ignored unless DEV_MODE=true, so a stray setting can't rig a real
+// table.
+func scriptedSpins() []string {
+	raw := os.Getenv("SCRIPTED_SPINS")
+	if raw == "" {
+		return nil
+	}
+	if os.Getenv("DEV_MODE") != "true" {
+		slog.Warn("ignoring SCRIPTED_SPINS without DEV_MODE=true")
+		return nil
+	}
+	var pockets []string
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			pockets = append(pockets, entry)
+		}
+	}
+	return pockets
 }
 
 func Load() *Config {
@@ -29,8 +215,65 @@ func Load() *Config {
 		}
 	}
 
+	wheelVariant := os.Getenv("WHEEL_VARIANT")
+	if wheelVariant == "" {
+		wheelVariant = "european"
+	}
+
+	zeroRule := os.Getenv("ZERO_RULE")
+	if zeroRule == "" {
+		zeroRule = "none"
+	}
+
+	dealerTimeout := time.Duration(envInt64("DEALER_TIMEOUT_SECONDS")) * time.Second
+	if dealerTimeout == 0 {
+		dealerTimeout = 2 * time.Minute
+	}
+
+	// Without a tables file the server runs one table with these settings.
+	table := TableConfig{
+		ID:             "main",
+		Name:           "Main",
+		WheelVariant:   wheelVariant,
+		ZeroRule:       zeroRule,
+		BetLimits:      parseBetLimits(os.Getenv("BET_LIMITS")),
+		MaxPlayerStake: envInt64("MAX_PLAYER_STAKE"),
+		MaxLiability:   envInt64("MAX_LIABILITY"),
+		PhysicsWheel:   envBool("PHYSICS_WHEEL"),
+		LiveDealer:     envBool("LIVE_DEALER"),
+		MaxSeats:       int(envInt64("MAX_SEATS")),
+	}
+	tables := []TableConfig{table}
+	if path := os.Getenv("TABLES_FILE"); path != "" {
+		if loaded := loadTables(path, table); len(loaded) > 0 {
+			tables = loaded
+		}
+	}
+
+	privateTableIdle := time.Duration(envInt64("PRIVATE_TABLE_IDLE_SECONDS")) * time.Second
+	if privateTableIdle == 0 {
+		privateTableIdle = 5 * time.Minute
+	}
+
+	seatGrace := time.Duration(envInt64("SEAT_GRACE_SECONDS")) * time.Second
+	if seatGrace == 0 {
+		seatGrace = 30 * time.Second
+	}
+
 	return &Config{
-		Port:           port,
-		AllowedOrigins: allowedOrigins,
+		Port:             port,
+		AllowedOrigins:   allowedOrigins,
+		AdminToken:       os.Getenv("ADMIN_TOKEN"),
+		DealerTimeout:    dealerTimeout,
+		RNGMonitor:       envBool("RNG_MONITOR"),
+		RNGWindows:       parseWindows(os.Getenv("RNG_MONITOR_WINDOWS")),
+		RNGInterval:      int(envInt64("RNG_MONITOR_INTERVAL")),
+		RNGAlpha:         envFloat("RNG_MONITOR_ALPHA"),
+		RNGTolerance:     int(envInt64("RNG_MONITOR_TOLERANCE")),
+		DBPath:           os.Getenv("DB_PATH"),
+		Tables:           tables,
+		PrivateTableIdle: privateTableIdle,
+		SeatGrace:        seatGrace,
+		ScriptedSpins:    scriptedSpins(),
 	}
 }
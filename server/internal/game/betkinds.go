@@ -0,0 +1,162 @@
+package game
+
+import (
+	"fmt"
+
+	"roulette/internal/messages"
+)
+
+// BetKind describes a single bet type: which values it accepts, which
+// pockets a value covers, and what a winning bet pays. Kinds are told which
+// wheel the table uses so they can accept values such as "00".
+type BetKind interface {
+	// Type is the wire name of the bet, e.g. "straight".
+	Type() messages.BetType
+	// Validate returns an error wrapping ErrInvalidBetValue if value is not
+	// a legal encoding for this bet type on w.
+	Validate(w *Wheel, value string) error
+	// Covered returns the pockets a validated value wins on.
+	Covered(w *Wheel, value string) []int
+	// Multiplier is the payout ratio N:1 applied to the stake on a win on w.
+	Multiplier(w *Wheel) int64
+}
+
+// numberListKind is an inside bet whose value is a dash-separated list of
+// numbers (e.g. "1-2-4-5") that must satisfy a layout shape.
+type numberListKind struct {
+	betType    messages.BetType
+	multiplier int64
+	shape      func(*Wheel, []int) bool
+	hint       string
+}
+
+func (k numberListKind) Type() messages.BetType  { return k.betType }
+func (k numberListKind) Multiplier(*Wheel) int64 { return k.multiplier }
+
+func (k numberListKind) Validate(w *Wheel, value string) error {
+	if nums, ok := parseNumbers(w, value); !ok || !k.shape(w, nums) {
+		return fmt.Errorf("%w: %s bet %s (%s)", ErrInvalidBetValue, k.betType, value, k.hint)
+	}
+	return nil
+}
+
+func (k numberListKind) Covered(w *Wheel, value string) []int {
+	nums, _ := parseNumbers(w, value) // already validated
+	return nums
+}
+
+// topLineKind is the top line. It pays 6:1 while it covers four or five
+// pockets, but 5:1 across the six on a triple-zero wheel, as a six line
+// does; 6:1 there would give the player the edge.
+type topLineKind struct {
+	numberListKind
+}
+
+func (k topLineKind) Multiplier(w *Wheel) int64 {
+	if len(w.TopLine) > 5 {
+		return 5
+	}
+	return k.multiplier
+}
+
+// namedKind is an outside bet whose value picks one of a fixed set of
+// named sections of the layout (e.g. "red", "first").
+type namedKind struct {
+	betType    messages.BetType
+	multiplier int64
+	sections   map[string][]int
+	hint       string
+}
+
+func (k namedKind) Type() messages.BetType  { return k.betType }
+func (k namedKind) Multiplier(*Wheel) int64 { return k.multiplier }
+
+func (k namedKind) Validate(_ *Wheel, value string) error {
+	if _, ok := k.sections[value]; !ok {
+		return fmt.Errorf("%w: %s bet %s (%s)", ErrInvalidBetValue, k.betType, value, k.hint)
+	}
+	return nil
+}
+
+func (k namedKind) Covered(_ *Wheel, value string) []int {
+	return k.sections[value]
+}
+
+// numbersWhere returns every number 1-36 for which keep returns true.
+// Zeros are never part of an outside bet.
+func numbersWhere(keep func(n int) bool) []int {
+	var nums []int
+	for n := 1; n <= 36; n++ {
+		if keep(n) {
+			nums = append(nums, n)
+		}
+	}
+	return nums
+}
+
+// StandardBetKinds returns every bet kind offered on a standard table.
+func StandardBetKinds() []BetKind {
+	return []BetKind{
+		numberListKind{
+			betType: messages.BetTypeStraight, multiplier: 35, hint: "must be 0-36 or one of the wheel's zeros",
+			shape: func(_ *Wheel, nums []int) bool { return len(nums) == 1 },
+		},
+		numberListKind{
+			betType: messages.BetTypeSplit, multiplier: 17, hint: "must be two adjacent numbers, e.g. 1-2",
+			shape: isSplit,
+		},
+		numberListKind{
+			betType: messages.BetTypeStreet, multiplier: 11, hint: "must be a row of three, e.g. 1-2-3",
+			shape: isStreet,
+		},
+		numberListKind{
+			betType: messages.BetTypeCorner, multiplier: 8, hint: "must be four numbers meeting at a corner, e.g. 1-2-4-5",
+			shape: isCorner,
+		},
+		numberListKind{
+			betType: messages.BetTypeSixLine, multiplier: 5, hint: "must be two adjacent rows, e.g. 1-2-3-4-5-6",
+			shape: isSixLine,
+		},
+		topLineKind{numberListKind{
+			betType: messages.BetTypeTopLine, multiplier: 6, hint: "must be the zeros and the first row, e.g. 0-1-2-3",
+			shape: isTopLine,
+		}},
+		namedKind{
+			betType: messages.BetTypeColor, multiplier: 1, hint: "must be red or black",
+			sections: map[string][]int{
+				"red":   numbersWhere(func(n int) bool { return RedNumbers[n] }),
+				"black": numbersWhere(func(n int) bool { return !RedNumbers[n] }),
+			},
+		},
+		namedKind{
+			betType: messages.BetTypeEvenOdd, multiplier: 1, hint: "must be even or odd",
+			sections: map[string][]int{
+				"even": numbersWhere(func(n int) bool { return n%2 == 0 }),
+				"odd":  numbersWhere(func(n int) bool { return n%2 == 1 }),
+			},
+		},
+		namedKind{
+			betType: messages.BetTypeHighLow, multiplier: 1, hint: "must be low or high",
+			sections: map[string][]int{
+				"low":  numbersWhere(func(n int) bool { return n <= 18 }),
+				"high": numbersWhere(func(n int) bool { return n >= 19 }),
+			},
+		},
+		namedKind{
+			betType: messages.BetTypeDozens, multiplier: 2, hint: "must be first, second, or third",
+			sections: map[string][]int{
+				"first":  numbersWhere(func(n int) bool { return n <= 12 }),
+				"second": numbersWhere(func(n int) bool { return n >= 13 && n <= 24 }),
+				"third":  numbersWhere(func(n int) bool { return n >= 25 }),
+			},
+		},
+		namedKind{
+			betType: messages.BetTypeColumns, multiplier: 2, hint: "must be first, second, or third",
+			sections: map[string][]int{
+				"first":  numbersWhere(func(n int) bool { return n%3 == 1 }),
+				"second": numbersWhere(func(n int) bool { return n%3 == 2 }),
+				"third":  numbersWhere(func(n int) bool { return n%3 == 0 }),
+			},
+		},
+	}
+}
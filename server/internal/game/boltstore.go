@@ -0,0 +1,634 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"roulette/internal/messages"
+)
+
+var (
+	// usersBucket holds one JSON UserRecord per user ID.
+	usersBucket = []byte("users")
+	// roundsBucket holds one JSON messages.Round per round, keyed by its
+	// big-endian ID so the keys sort in round order.
+	roundsBucket = []byte("rounds")
+	// ledgerBucket holds a bucket per user of their JSON
+	// messages.Transactions, keyed by big-endian ID. Its sequence numbers
+	// the transactions.
+	ledgerBucket = []byte("ledger")
+	// ledgerBalancesBucket holds the sum of each user's ledger entries,
+	// big-endian, kept with every transaction so it needn't be summed.
+	ledgerBalancesBucket = []byte("ledger_balances")
+	// roundLedgerBucket indexes the ledger by round: the key is the round ID
+	// then the transaction ID, both big-endian, and the value the user ID.
+	roundLedgerBucket = []byte("round_ledger")
+	// settlementBucket holds the JSON Settlement of the latest round.
+	settlementBucket = []byte("settlement")
+	// eventsBucket holds one JSON messages.Event per event, keyed by its
+	// big-endian Seq.
+	eventsBucket = []byte("events")
+	// roundEventsBucket indexes the events by round: the key is the round ID
+	// then the Seq, both big-endian.
+	roundEventsBucket = []byte("round_events")
+	// snapshotsBucket holds JSON messages.TableState snapshots keyed by the
+	// big-endian Seq they were taken after.
+	snapshotsBucket = []byte("snapshots")
+	// tablesBucket holds a bucket per table, other than the default, of
+	// its own tableBuckets.
+	tablesBucket = []byte("tables")
+)
+
+// tableBuckets are the buckets each table keeps apart from the others.
+var tableBuckets = [][]byte{roundsBucket, settlementBucket, eventsBucket, roundEventsBucket, snapshotsBucket}
+
+// closedKey marks a table's bucket in tablesBucket as belonging to a table
+// that has been closed.
+var closedKey = []byte("closed")
+
+// settlementKey is the key of the settlement in settlementBucket.
+var settlementKey = []byte("latest")
+
+// BoltStore persists users, rounds, the ledger and the event log in an
+// embedded bbolt database file. It is safe for concurrent use.
+type BoltStore struct {
+	db    *bolt.DB
+	table []byte // the table whose buckets this view uses; nil for the default
+}
+
+// OpenBoltStore opens, or creates, the database at path. Only one process
+// can have it open at a time.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{usersBucket, ledgerBucket, roundLedgerBucket, tablesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		for _, name := range tableBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if tx.Bucket(ledgerBalancesBucket) == nil {
+			return sumLedgerBalances(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create buckets: %w", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Table returns a view of the store for table id. It shares the users and
+// the ledger, but keeps the table's rounds, settlement and events apart.
+// The default table uses the top-level buckets, so a database from a
+// single-table server carries on as its default table. Close the store, not
+// its views.
+func (s *BoltStore) Table(id string) (*BoltStore, error) {
+	if id == DefaultTableID {
+		return s, nil
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		table, err := tx.Bucket(tablesBucket).CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return err
+		}
+		for _, name := range tableBuckets {
+			if _, err := table.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create buckets for table %s: %w", id, err)
+	}
+	return &BoltStore{db: s.db, table: []byte(id)}, nil
+}
+
+// TableIDs returns the IDs of the tables, other than the default, that have
+// buckets in the store.
+func (s *BoltStore) TableIDs() ([]string, error) {
+	var ids []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tablesBucket).ForEachBucket(func(k []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list tables: %w", err)
+	}
+	return ids, nil
+}
+
+// MarkTableClosed records that table id has been closed, so its buckets are
+// only kept until the next startup drops them.
+func (s *BoltStore) MarkTableClosed(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		table := tx.Bucket(tablesBucket).Bucket([]byte(id))
+		if table == nil {
+			return nil
+		}
+		return table.Put(closedKey, []byte{1})
+	})
+	if err != nil {
+		return fmt.Errorf("mark table %s closed: %w", id, err)
+	}
+	return nil
+}
+
+// TableClosed reports whether table id has been marked closed.
+func (s *BoltStore) TableClosed(id string) (bool, error) {
+	var closed bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if table := tx.Bucket(tablesBucket).Bucket([]byte(id)); table != nil {
+			closed = table.Get(closedKey) != nil
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("read table %s: %w", id, err)
+	}
+	return closed, nil
+}
+
+// DropTable deletes table id's buckets. Nothing may still be using a view
+// of the table.
+func (s *BoltStore) DropTable(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		tables := tx.Bucket(tablesBucket)
+		if tables.Bucket([]byte(id)) == nil {
+			return nil
+		}
+		return tables.DeleteBucket([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("drop table %s: %w", id, err)
+	}
+	return nil
+}
+
+// bucket returns the view's own bucket of one of the tableBuckets.
+func (s *BoltStore) bucket(tx *bolt.Tx, name []byte) *bolt.Bucket {
+	if s.table == nil {
+		return tx.Bucket(name)
+	}
+	return tx.Bucket(tablesBucket).Bucket(s.table).Bucket(name)
+}
+
+// GetUser returns the user with id.
+func (s *BoltStore) GetUser(id string) (UserRecord, bool, error) {
+	var rec UserRecord
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		rec, ok, err = getUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return UserRecord{}, false, fmt.Errorf("get user %s: %w", id, err)
+	}
+	return rec, ok, nil
+}
+
+// getUser reads the user with id in tx.
+func getUser(tx *bolt.Tx, id string) (UserRecord, bool, error) {
+	data := tx.Bucket(usersBucket).Get([]byte(id))
+	if data == nil {
+		return UserRecord{}, false, nil
+	}
+	var rec UserRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return UserRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+// PutUser creates or replaces a user.
+func (s *BoltStore) PutUser(rec UserRecord) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return putUser(tx, rec)
+	})
+	if err != nil {
+		return fmt.Errorf("put user %s: %w", rec.ID, err)
+	}
+	return nil
+}
+
+// putUser creates or replaces a user in tx.
+func putUser(tx *bolt.Tx, rec UserRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(usersBucket).Put([]byte(rec.ID), data)
+}
+
+// DeleteUser removes a user.
+func (s *BoltStore) DeleteUser(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("delete user %s: %w", id, err)
+	}
+	return nil
+}
+
+// idKey is the key of a round or transaction ID.
+func idKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+// lastBefore moves c to the last key below before, or to the last key if
+// before is 0.
+func lastBefore(c *bolt.Cursor, before int64) ([]byte, []byte) {
+	if before == 0 {
+		return c.Last()
+	}
+	// Seek lands on the first key at or after before; step back from it.
+	if k, _ := c.Seek(idKey(before)); k == nil {
+		return c.Last()
+	}
+	return c.Prev()
+}
+
+// PutRound stores a round, replacing any with the same ID.
+func (s *BoltStore) PutRound(r messages.Round) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("put round %d: %w", r.ID, err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return s.bucket(tx, roundsBucket).Put(idKey(r.ID), data)
+	})
+	if err != nil {
+		return fmt.Errorf("put round %d: %w", r.ID, err)
+	}
+	return nil
+}
+
+// GetRound returns the round with id.
+func (s *BoltStore) GetRound(id int64) (messages.Round, bool, error) {
+	var r messages.Round
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := s.bucket(tx, roundsBucket).Get(idKey(id))
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &r)
+	})
+	if err != nil {
+		return messages.Round{}, false, fmt.Errorf("get round %d: %w", id, err)
+	}
+	return r, ok, nil
+}
+
+// Rounds returns up to limit rounds with IDs below before, newest first.
+func (s *BoltStore) Rounds(before int64, limit int) ([]messages.Round, error) {
+	rounds := []messages.Round{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := s.bucket(tx, roundsBucket).Cursor()
+		for k, v := lastBefore(c, before); k != nil && len(rounds) < limit; k, v = c.Prev() {
+			var r messages.Round
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			rounds = append(rounds, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list rounds: %w", err)
+	}
+	return rounds, nil
+}
+
+// AppendTransactions assigns IDs to txs in order, setting them in txs, and
+// records them.
+func (s *BoltStore) AppendTransactions(txs []messages.Transaction) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return appendTransactions(tx, txs)
+	})
+	if err != nil {
+		return fmt.Errorf("append transactions: %w", err)
+	}
+	return nil
+}
+
+// Post records txs and saves users in one transaction. Transactions for
+// other users are added to their stored balances.
+func (s *BoltStore) Post(txs []messages.Transaction, users []UserRecord) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		stored, err := addToStored(txs, users, func(id string) (UserRecord, bool, error) {
+			return getUser(tx, id)
+		})
+		if err != nil {
+			return err
+		}
+		if err := appendTransactions(tx, txs); err != nil {
+			return err
+		}
+		for _, rec := range slices.Concat(users, stored) {
+			if err := putUser(tx, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("post transactions: %w", err)
+	}
+	return nil
+}
+
+// appendTransactions assigns IDs to txs in order, setting them in txs, and
+// records them in tx, adding them to each user's ledger balance.
+func appendTransactions(tx *bolt.Tx, txs []messages.Transaction) error {
+	ledger := tx.Bucket(ledgerBucket)
+	byRound := tx.Bucket(roundLedgerBucket)
+	balances := tx.Bucket(ledgerBalancesBucket)
+	for i := range txs {
+		id, err := ledger.NextSequence()
+		if err != nil {
+			return err
+		}
+		txs[i].ID = int64(id)
+		t := txs[i]
+		data, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		user, err := ledger.CreateBucketIfNotExists([]byte(t.UserID))
+		if err != nil {
+			return err
+		}
+		if err := user.Put(idKey(t.ID), data); err != nil {
+			return err
+		}
+		if t.RoundID != 0 {
+			key := append(idKey(t.RoundID), idKey(t.ID)...)
+			if err := byRound.Put(key, []byte(t.UserID)); err != nil {
+				return err
+			}
+		}
+		balance := ledgerBalance(balances, t.UserID) + entryAmount(t, t.UserID)
+		if err := balances.Put([]byte(t.UserID), binary.BigEndian.AppendUint64(nil, uint64(balance))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ledgerBalance reads a user's balance from ledgerBalancesBucket.
+func ledgerBalance(balances *bolt.Bucket, userID string) int64 {
+	v := balances.Get([]byte(userID))
+	if v == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(v))
+}
+
+// sumLedgerBalances creates ledgerBalancesBucket for a database written
+// before it was kept, summing each user's entries.
+func sumLedgerBalances(tx *bolt.Tx) error {
+	balances, err := tx.CreateBucket(ledgerBalancesBucket)
+	if err != nil {
+		return err
+	}
+	ledger := tx.Bucket(ledgerBucket)
+	return ledger.ForEachBucket(func(userID []byte) error {
+		var sum int64
+		err := ledger.Bucket(userID).ForEach(func(_, v []byte) error {
+			var t messages.Transaction
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			sum += entryAmount(t, string(userID))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return balances.Put(userID, binary.BigEndian.AppendUint64(nil, uint64(sum)))
+	})
+}
+
+// Transactions returns up to limit of a user's transactions with IDs below
+// before, newest first.
+func (s *BoltStore) Transactions(userID string, before int64, limit int) ([]messages.Transaction, error) {
+	txs := []messages.Transaction{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		user := tx.Bucket(ledgerBucket).Bucket([]byte(userID))
+		if user == nil {
+			return nil
+		}
+		c := user.Cursor()
+		for k, v := lastBefore(c, before); k != nil && len(txs) < limit; k, v = c.Prev() {
+			var t messages.Transaction
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			txs = append(txs, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list transactions for %s: %w", userID, err)
+	}
+	return txs, nil
+}
+
+// LedgerBalance returns the sum of a user's entries, kept as they are
+// recorded.
+func (s *BoltStore) LedgerBalance(userID string) (int64, error) {
+	var sum int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		sum = ledgerBalance(tx.Bucket(ledgerBalancesBucket), userID)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("sum ledger for %s: %w", userID, err)
+	}
+	return sum, nil
+}
+
+// RoundTransactions returns every user's transactions in a round, in order.
+func (s *BoltStore) RoundTransactions(round int64) ([]messages.Transaction, error) {
+	var txs []messages.Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		ledger := tx.Bucket(ledgerBucket)
+		prefix := idKey(round)
+		c := tx.Bucket(roundLedgerBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			user := ledger.Bucket(v)
+			if user == nil {
+				return fmt.Errorf("transaction %x: no ledger for user %s", k[len(prefix):], v)
+			}
+			var t messages.Transaction
+			if err := json.Unmarshal(user.Get(k[len(prefix):]), &t); err != nil {
+				return err
+			}
+			txs = append(txs, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list round %d transactions: %w", round, err)
+	}
+	return txs, nil
+}
+
+// PutSettlement replaces the stored settlement.
+func (s *BoltStore) PutSettlement(st Settlement) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("put settlement of round %d: %w", st.Round, err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return s.bucket(tx, settlementBucket).Put(settlementKey, data)
+	})
+	if err != nil {
+		return fmt.Errorf("put settlement of round %d: %w", st.Round, err)
+	}
+	return nil
+}
+
+// GetSettlement returns the stored settlement.
+func (s *BoltStore) GetSettlement() (Settlement, bool, error) {
+	var st Settlement
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := s.bucket(tx, settlementBucket).Get(settlementKey)
+		if data == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(data, &st)
+	})
+	if err != nil {
+		return Settlement{}, false, fmt.Errorf("get settlement: %w", err)
+	}
+	return st, ok, nil
+}
+
+// AppendEvents records events.
+func (s *BoltStore) AppendEvents(events []messages.Event) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		log := s.bucket(tx, eventsBucket)
+		byRound := s.bucket(tx, roundEventsBucket)
+		for _, e := range events {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if err := log.Put(idKey(e.Seq), data); err != nil {
+				return err
+			}
+			if err := byRound.Put(append(idKey(e.Round), idKey(e.Seq)...), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("append events: %w", err)
+	}
+	return nil
+}
+
+// Events returns up to limit events with Seq above after, oldest first.
+func (s *BoltStore) Events(after int64, limit int) ([]messages.Event, error) {
+	events := []messages.Event{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := s.bucket(tx, eventsBucket).Cursor()
+		for k, v := c.Seek(idKey(after + 1)); k != nil && len(events) < limit; k, v = c.Next() {
+			var e messages.Event
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list events after %d: %w", after, err)
+	}
+	return events, nil
+}
+
+// RoundEvents returns a round's events, oldest first.
+func (s *BoltStore) RoundEvents(round int64) ([]messages.Event, error) {
+	var events []messages.Event
+	err := s.db.View(func(tx *bolt.Tx) error {
+		log := s.bucket(tx, eventsBucket)
+		prefix := idKey(round)
+		c := s.bucket(tx, roundEventsBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			var e messages.Event
+			if err := json.Unmarshal(log.Get(k[len(prefix):]), &e); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list round %d events: %w", round, err)
+	}
+	return events, nil
+}
+
+// PutSnapshot records the state after the event st.Seq.
+func (s *BoltStore) PutSnapshot(st messages.TableState) error {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return fmt.Errorf("put snapshot at %d: %w", st.Seq, err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return s.bucket(tx, snapshotsBucket).Put(idKey(st.Seq), data)
+	})
+	if err != nil {
+		return fmt.Errorf("put snapshot at %d: %w", st.Seq, err)
+	}
+	return nil
+}
+
+// Snapshot returns the latest snapshot taken before the event before.
+func (s *BoltStore) Snapshot(before int64) (messages.TableState, bool, error) {
+	var st messages.TableState
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		k, v := lastBefore(s.bucket(tx, snapshotsBucket).Cursor(), before)
+		if k == nil {
+			return nil
+		}
+		ok = true
+		return json.Unmarshal(v, &st)
+	})
+	if err != nil {
+		return messages.TableState{}, false, fmt.Errorf("get snapshot before %d: %w", before, err)
+	}
+	return st, ok, nil
+}
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"roulette/internal/messages"
+)
+
+// maxNeighbours is the widest neighbours bet offered (the number plus 9 either side).
+const maxNeighbours = 9
+
+// CallBetKind is a racetrack bet that expands into several chips on the
+// layout. Each chip is an ordinary bet of a kind in the same registry.
+type CallBetKind interface {
+	// Type is the wire name of the bet, e.g. "voisins".
+	Type() messages.BetType
+	// Expand returns the component bets for value on w. Each component's
+	// Amount is a chip count; the caller multiplies it by the per-chip stake.
+	Expand(w *Wheel, value string) ([]Bet, error)
+}
+
+// chip is one placement in a fixed call bet.
+type chip struct {
+	betType messages.BetType
+	value   string
+	chips   int64
+}
+
+// fixedCallKind is a French call bet with a fixed chip pattern, defined
+// against the European wheel head.
+type fixedCallKind struct {
+	betType messages.BetType
+	pattern []chip
+}
+
+func (k fixedCallKind) Type() messages.BetType { return k.betType }
+
+func (k fixedCallKind) Expand(w *Wheel, value string) ([]Bet, error) {
+	if w.Variant != messages.WheelVariantEuropean {
+		return nil, fmt.Errorf("%w: %s is only offered on a european wheel", ErrUnknownBetType, k.betType)
+	}
+	if value != "" {
+		return nil, fmt.Errorf("%w: %s bet %s (takes no value)", ErrInvalidBetValue, k.betType, value)
+	}
+	bets := make([]Bet, len(k.pattern))
+	for i, c := range k.pattern {
+		bets[i] = Bet{Type: c.betType, Value: c.value, Amount: c.chips}
+	}
+	return bets, nil
+}
+
+// neighboursKind is a straight-up chip on a number and the K pockets either
+// side of it on the wheel head. Its value is "N:K", e.g. "17:2".
+type neighboursKind struct{}
+
+func (neighboursKind) Type() messages.BetType { return messages.BetTypeNeighbours }
+
+func (neighboursKind) Expand(w *Wheel, value string) ([]Bet, error) {
+	invalid := fmt.Errorf("%w: neighbours bet %s (must be N:K with K 1-%d, e.g. 17:2)", ErrInvalidBetValue, value, maxNeighbours)
+
+	label, width, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, invalid
+	}
+	n, ok := w.ParsePocket(label)
+	if !ok {
+		return nil, invalid
+	}
+	k, err := strconv.Atoi(width)
+	if err != nil || k < 1 || k > maxNeighbours {
+		return nil, invalid
+	}
+
+	pockets := w.Neighbours(n, k)
+	bets := make([]Bet, len(pockets))
+	for i, p := range pockets {
+		bets[i] = Bet{Type: messages.BetTypeStraight, Value: PocketLabel(p), Amount: 1}
+	}
+	return bets, nil
+}
+
+// endingIn returns the numbers 0-36 whose last digit is d.
+func endingIn(d int) []int {
+	var nums []int
+	for n := d; n <= 36; n += 10 {
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// finaleEnPleinKind is a straight-up chip on every number ending in one digit.
+// Its value is the digit, e.g. "7" for 7, 17 and 27.
+type finaleEnPleinKind struct{}
+
+func (finaleEnPleinKind) Type() messages.BetType { return messages.BetTypeFinaleEnPlein }
+
+func (finaleEnPleinKind) Expand(_ *Wheel, value string) ([]Bet, error) {
+	d, err := strconv.Atoi(value)
+	if err != nil || len(value) != 1 || d < 0 {
+		return nil, fmt.Errorf("%w: finale_en_plein bet %s (must be a digit 0-9)", ErrInvalidBetValue, value)
+	}
+
+	var bets []Bet
+	for _, n := range endingIn(d) {
+		bets = append(bets, Bet{Type: messages.BetTypeStraight, Value: strconv.Itoa(n), Amount: 1})
+	}
+	return bets, nil
+}
+
+// finaleAChevalKind covers every number ending in two adjacent digits with
+// splits. Its value is the digit pair, e.g. "1-2" or "0-3". A number whose
+// partner is not next to it on the layout (21 and 22, say) gets a
+// straight-up chip instead.
+type finaleAChevalKind struct{}
+
+func (finaleAChevalKind) Type() messages.BetType { return messages.BetTypeFinaleACheval }
+
+func (finaleAChevalKind) Expand(w *Wheel, value string) ([]Bet, error) {
+	invalid := fmt.Errorf("%w: finale_a_cheval bet %s (must be two adjacent digits, e.g. 1-2 or 0-3)", ErrInvalidBetValue, value)
+
+	first, second, ok := strings.Cut(value, "-")
+	if !ok || len(first) != 1 || len(second) != 1 {
+		return nil, invalid
+	}
+	a, errA := strconv.Atoi(first)
+	b, errB := strconv.Atoi(second)
+	if errA != nil || errB != nil || a < 0 || b < 0 {
+		return nil, invalid
+	}
+	if a > b {
+		a, b = b, a
+	}
+	// Digits are adjacent if numbers ending in them can sit side by side (1-2)
+	// or one above the other (1-4) on the layout.
+	if !((b == a+1 && (a == 0 || !lastInRow(a))) || b == a+3) {
+		return nil, invalid
+	}
+
+	var bets []Bet
+	paired := make(map[int]bool)
+	for _, n := range endingIn(a) {
+		m := n + b - a
+		if m <= 36 && isSplit(w, []int{n, m}) {
+			bets = append(bets, Bet{Type: messages.BetTypeSplit, Value: fmt.Sprintf("%d-%d", n, m), Amount: 1})
+			paired[n], paired[m] = true, true
+		}
+	}
+	for _, n := range append(endingIn(a), endingIn(b)...) {
+		if !paired[n] {
+			bets = append(bets, Bet{Type: messages.BetTypeStraight, Value: strconv.Itoa(n), Amount: 1})
+		}
+	}
+	return bets, nil
+}
+
+// StandardCallBetKinds returns the racetrack bets offered on a standard table.
+func StandardCallBetKinds() []CallBetKind {
+	return []CallBetKind{
+		// Voisins du Zéro: the 17 pockets from 22 to 25 around zero.
+		fixedCallKind{betType: messages.BetTypeVoisins, pattern: []chip{
+			{messages.BetTypeStreet, "0-2-3", 2},
+			{messages.BetTypeSplit, "4-7", 1},
+			{messages.BetTypeSplit, "12-15", 1},
+			{messages.BetTypeSplit, "18-21", 1},
+			{messages.BetTypeSplit, "19-22", 1},
+			{messages.BetTypeCorner, "25-26-28-29", 2},
+			{messages.BetTypeSplit, "32-35", 1},
+		}},
+		// Tiers du Cylindre: the 12 pockets from 27 to 33 opposite zero.
+		fixedCallKind{betType: messages.BetTypeTiers, pattern: []chip{
+			{messages.BetTypeSplit, "5-8", 1},
+			{messages.BetTypeSplit, "10-11", 1},
+			{messages.BetTypeSplit, "13-16", 1},
+			{messages.BetTypeSplit, "23-24", 1},
+			{messages.BetTypeSplit, "27-30", 1},
+			{messages.BetTypeSplit, "33-36", 1},
+		}},
+		// Orphelins: the 8 pockets left over, in two arcs (17-34-6 and 1-20-14-31-9).
+		fixedCallKind{betType: messages.BetTypeOrphelins, pattern: []chip{
+			{messages.BetTypeStraight, "1", 1},
+			{messages.BetTypeSplit, "6-9", 1},
+			{messages.BetTypeSplit, "14-17", 1},
+			{messages.BetTypeSplit, "17-20", 1},
+			{messages.BetTypeSplit, "31-34", 1},
+		}},
+		neighboursKind{},
+		finaleEnPleinKind{},
+		finaleAChevalKind{},
+	}
+}
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"slices"
+
+	"roulette/internal/messages"
+)
+
+// CancelBet removes one of the user's bets by ID and refunds its stake.
+// Returns the removed bet and the user's new balance.
+func (m *Manager) CancelBet(userID, betID string) (Bet, int64, error) {
+	removed, balance, err := m.removeBets(userID, func(bets []Bet) []int {
+		i := slices.IndexFunc(bets, func(b Bet) bool { return b.ID == betID && b.UserID == userID })
+		if i < 0 {
+			return nil
+		}
+		return []int{i}
+	})
+	if err != nil {
+		return Bet{}, 0, err
+	}
+	return removed[0], balance, nil
+}
+
+// UndoLastBet removes the user's most recent bet this round and refunds its stake.
+// Returns the removed bet and the user's new balance.
+func (m *Manager) UndoLastBet(userID string) (Bet, int64, error) {
+	removed, balance, err := m.removeBets(userID, func(bets []Bet) []int {
+		for i := len(bets) - 1; i >= 0; i-- {
+			if bets[i].UserID == userID {
+				return []int{i}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Bet{}, 0, err
+	}
+	return removed[0], balance, nil
+}
+
+// ClearBets removes all of the user's bets this round and refunds them.
+// Returns the removed bets and the user's new balance.
+func (m *Manager) ClearBets(userID string) ([]Bet, int64, error) {
+	return m.removeBets(userID, func(bets []Bet) []int {
+		var idx []int
+		for i, b := range bets {
+			if b.UserID == userID {
+				idx = append(idx, i)
+			}
+		}
+		return idx
+	})
+}
+
+// removeBets takes the bets at the indexes pick returns out of the round and
+// refunds their stakes. It follows PlaceBet's locking: the session RLock keeps
+// the round in the betting phase, and the session lock guards the bet list.
+func (m *Manager) removeBets(userID string, pick func([]Bet) []int) ([]Bet, int64, error) {
+	m.sessionMu.RLock()
+	defer m.sessionMu.RUnlock()
+
+	if m.session.State != StateBetting {
+		return nil, 0, ErrBettingClosed
+	}
+
+	user := m.GetUser(userID)
+	if user == nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	removed, balance, err := m.takeBets(user, pick)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Recorded outside the session lock, as PlaceBet's are.
+	events := make([]messages.Event, len(removed))
+	for i := range removed {
+		events[i] = messages.Event{
+			Type:   messages.EventBetCancelled,
+			Round:  m.session.round,
+			UserID: userID,
+			Bet:    &removed[i],
+			Amount: removed[i].Amount,
+		}
+	}
+	m.emit(events...)
+
+	return removed, balance, nil
+}
+
+// takeBets refunds and takes out of the round the bets at the indexes pick
+// returns. It returns them and the user's new balance. The caller must hold
+// the sessionMu read lock.
+//
+// As in addBets, the bets come out of the round under the session lock but
+// the refund is posted to the ledger after it is released, so other
+// players' bets don't wait on the write. If the post fails the bets are put
+// back.
+func (m *Manager) takeBets(user *User, pick func([]Bet) []int) ([]Bet, int64, error) {
+	m.session.mu.Lock()
+	idx := pick(m.session.Bets)
+	// Remove from the back so earlier indexes stay valid, then restore bet order.
+	removed := make([]Bet, 0, len(idx))
+	exposures := make([]map[int]int64, 0, len(idx))
+	for _, i := range slices.Backward(idx) {
+		exposure := m.betKinds.Exposure(m.wheel, m.session.Bets[i])
+		removed = append(removed, m.session.removeBet(i, exposure))
+		exposures = append(exposures, exposure)
+	}
+	m.session.mu.Unlock()
+	if len(idx) == 0 {
+		return nil, 0, ErrBetNotFound
+	}
+	slices.Reverse(removed)
+	slices.Reverse(exposures)
+
+	postings := make([]posting, len(removed))
+	for i, bet := range removed {
+		postings[i] = posting{kind: messages.TransactionRefund, amount: bet.Amount, betID: bet.ID, note: "cancelled"}
+	}
+	user.mu.Lock()
+	_, err := m.post(user, m.session.round, postings...)
+	balance := user.Balance
+	user.mu.Unlock()
+	if err != nil {
+		m.putBack(removed, exposures, idx)
+		return nil, 0, err
+	}
+	return removed, balance, nil
+}
+
+// putBack returns bets, whose refund could not be recorded, to the round at
+// the indexes takeBets took them from, as near as bets placed or cancelled
+// meanwhile allow. The caller must hold the sessionMu read lock.
+func (m *Manager) putBack(bets []Bet, exposures []map[int]int64, idx []int) {
+	m.session.mu.Lock()
+	defer m.session.mu.Unlock()
+	for j, bet := range bets {
+		i := min(idx[j], len(m.session.Bets))
+		m.session.Bets = slices.Insert(m.session.Bets, i, bet)
+		m.session.reserve(bet, exposures[j])
+	}
+}
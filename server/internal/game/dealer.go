@@ -0,0 +1,234 @@
+package game
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"roulette/internal/messages"
+)
+
+// In live dealer mode a croupier spins a real wheel. Once betting closes
+// the game loop waits for the result to be entered twice, as EnterResult
+// then a matching ConfirmResult, before settling. If no confirmed result
+// arrives within the table's timeout, or the croupier calls VoidRound
+// (a "no spin"), every bet in the round is refunded instead.
+
+// voidTimeoutReason is the void reason when no result is confirmed in time.
+const voidTimeoutReason = "no result entered in time"
+
+// dealerDecision is how a live round ended: a confirmed pocket or a void.
+type dealerDecision struct {
+	pocket int
+	void   bool
+	reason string
+}
+
+// LiveDealer reports whether results come from a croupier rather than the RNG.
+func (m *Manager) LiveDealer() bool {
+	return m.dealerTimeout > 0
+}
+
+// CheckAdminToken reports whether token matches the table's admin token.
+// It is always false if no admin token is set.
+func (m *Manager) CheckAdminToken(token string) bool {
+	return m.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(m.adminToken)) == 1
+}
+
+// AwaitingResult reports whether a live round is waiting for the croupier.
+func (m *Manager) AwaitingResult() bool {
+	m.dealerMu.Lock()
+	defer m.dealerMu.Unlock()
+	return m.awaiting
+}
+
+// EnterResult records the croupier's first entry of the winning pocket.
+// It replaces any earlier unconfirmed entry.
+func (m *Manager) EnterResult(label string) (int, error) {
+	pocket, err := m.parseResult(label)
+	if err != nil {
+		return 0, err
+	}
+	m.dealerMu.Lock()
+	defer m.dealerMu.Unlock()
+	if !m.awaiting {
+		return 0, ErrNotAwaitingResult
+	}
+	m.pending = &pocket
+	return pocket, nil
+}
+
+// ConfirmResult is the second entry of the winning pocket. If it matches
+// the first the round is settled on it; if not, both entries are discarded
+// and ErrResultMismatch is returned so the croupier starts again.
+func (m *Manager) ConfirmResult(label string) (int, error) {
+	pocket, err := m.parseResult(label)
+	if err != nil {
+		return 0, err
+	}
+	m.dealerMu.Lock()
+	defer m.dealerMu.Unlock()
+	if !m.awaiting {
+		return 0, ErrNotAwaitingResult
+	}
+	if m.pending == nil {
+		return 0, ErrNoPendingResult
+	}
+	if *m.pending != pocket {
+		first := *m.pending
+		m.pending = nil
+		return 0, fmt.Errorf("%w: entered %s, confirmed %s", ErrResultMismatch, PocketLabel(first), PocketLabel(pocket))
+	}
+	m.decide(dealerDecision{pocket: pocket})
+	return pocket, nil
+}
+
+// VoidRound declares a "no spin": the round is not settled and every bet
+// in it is refunded.
+func (m *Manager) VoidRound(reason string) error {
+	if !m.LiveDealer() {
+		return ErrNotLiveDealer
+	}
+	m.dealerMu.Lock()
+	defer m.dealerMu.Unlock()
+	if !m.awaiting {
+		return ErrNotAwaitingResult
+	}
+	if reason == "" {
+		reason = "no spin"
+	}
+	m.decide(dealerDecision{void: true, reason: reason})
+	return nil
+}
+
+// ApplyDealerStep performs a croupier action and acknowledges it. Callers
+// must have checked the admin token.
+func (m *Manager) ApplyDealerStep(step messages.DealerStep, pocket, reason string) messages.DealerAckMessage {
+	ack := messages.DealerAckMessage{Type: "dealer_ack", Step: step}
+	var n int
+	var err error
+	switch step {
+	case messages.DealerStepEnter:
+		n, err = m.EnterResult(pocket)
+	case messages.DealerStepConfirm:
+		n, err = m.ConfirmResult(pocket)
+	case messages.DealerStepVoid:
+		err = m.VoidRound(reason)
+	default:
+		err = fmt.Errorf("unknown dealer step %q", step)
+	}
+	if err != nil {
+		slog.Warn("dealer step rejected", "step", step, "pocket", pocket, "error", err)
+		ack.Reason = err.Error()
+		return ack
+	}
+	slog.Info("dealer step", "step", step, "pocket", pocket, "reason", reason)
+	ack.OK = true
+	if step != messages.DealerStepVoid {
+		ack.Pocket = PocketLabel(n)
+	}
+	return ack
+}
+
+// parseResult checks a live result can be entered at all.
+func (m *Manager) parseResult(label string) (int, error) {
+	if !m.LiveDealer() {
+		return 0, ErrNotLiveDealer
+	}
+	pocket, ok := m.wheel.ParsePocket(label)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q on a %s wheel", ErrInvalidPocket, label, m.wheel.Variant)
+	}
+	return pocket, nil
+}
+
+// decide ends the wait. The caller must hold dealerMu and have checked awaiting.
+func (m *Manager) decide(d dealerDecision) {
+	m.awaiting = false
+	m.pending = nil
+	m.dealerCh <- d
+}
+
+// awaitDealer opens the round to the croupier and waits for their decision,
+// voiding it if the timeout passes first. It returns false if the game is
+// stopping.
+func (m *Manager) awaitDealer() (dealerDecision, bool) {
+	m.dealerMu.Lock()
+	m.awaiting = true
+	m.pending = nil
+	m.dealerMu.Unlock()
+
+	select {
+	case d := <-m.dealerCh:
+		return d, true
+	case <-m.clock.After(m.dealerTimeout):
+	case <-m.stopCh:
+		m.dealerMu.Lock()
+		m.awaiting = false
+		m.dealerMu.Unlock()
+		return dealerDecision{}, false
+	}
+
+	m.dealerMu.Lock()
+	defer m.dealerMu.Unlock()
+	if !m.awaiting {
+		// A decision came in as the timeout fired; honour it.
+		return <-m.dealerCh, true
+	}
+	m.awaiting = false
+	m.pending = nil
+	return dealerDecision{void: true, reason: voidTimeoutReason}, true
+}
+
+// voidRound refunds every bet in the current round and tells each player.
+// Bets held En Prison from earlier rounds stay where they are.
+func (m *Manager) voidRound(reason string) {
+	m.sessionMu.Lock()
+	session := m.session
+	m.session.mu.Lock()
+	bets := m.session.Bets
+	m.session.Bets = nil
+	m.session.stakes = nil
+	m.session.liability = nil
+	m.session.mu.Unlock()
+	m.sessionMu.Unlock()
+
+	refunds := make(map[string]int64)
+	credits := make([]Credit, len(bets))
+	for i, bet := range bets {
+		refunds[bet.UserID] += bet.Amount
+		credits[i] = Credit{
+			UserID: bet.UserID,
+			BetID:  bet.ID,
+			Kind:   messages.TransactionRefund,
+			Amount: bet.Amount,
+			Note:   "round voided",
+		}
+	}
+	m.settleRound(session, credits, m.imprisoned)
+	slog.Info("round voided", "reason", reason, "bets", len(bets))
+	m.recordRound(session, bets, nil, nil, reason)
+
+	m.usersMu.RLock()
+	for userID, user := range m.users {
+		user.mu.Lock()
+		balance := user.Balance
+		user.mu.Unlock()
+
+		msg, err := json.Marshal(messages.RoundVoidedMessage{
+			Type:     "round_voided",
+			Reason:   reason,
+			Refunded: refunds[userID],
+			Balance:  balance,
+		})
+		if err != nil {
+			slog.Error("failed to marshal round voided message", "error", err, "user_id", userID)
+			continue
+		}
+		m.sendToUser(userID, msg)
+	}
+	m.usersMu.RUnlock()
+
+	m.BroadcastPlayerList()
+}
@@ -0,0 +1,337 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// Every change to the table is emitted as an event and appended to an event
+// log. Applying the events in order to an empty TableState rebuilds the
+// table; snapshots of the state every so often mean a rebuild only has to
+// replay the events since the latest one. A user sitting down records the
+// balance they bring, so the state has the balance of everyone at the table
+// without the log holding their whole history; the ledger stays the record
+// of what they are owed.
+
+// snapshotInterval is how many events apart the manager snapshots its state.
+const snapshotInterval = 500
+
+// eventPage is how many events a rebuild reads at a time.
+const eventPage = 1000
+
+// latestSeq asks stateAt for the state after every event in the log.
+const latestSeq = math.MaxInt64
+
+// EventLog is an append-only log of table events and snapshots of the state
+// they build.
+type EventLog interface {
+	// AppendEvents records events, which carry their sequence numbers.
+	AppendEvents(events []messages.Event) error
+	// Events returns up to limit events with Seq above after, oldest first.
+	Events(after int64, limit int) ([]messages.Event, error)
+	// RoundEvents returns a round's events, oldest first.
+	RoundEvents(round int64) ([]messages.Event, error)
+	// PutSnapshot records the state after the event s.Seq.
+	PutSnapshot(s messages.TableState) error
+	// Snapshot returns the latest snapshot taken before the event before,
+	// or the latest of all if before is 0. ok is false if there is none.
+	Snapshot(before int64) (s messages.TableState, ok bool, err error)
+}
+
+// MemoryEventLog keeps the event log in memory: the events of the last
+// memoryRounds rounds, and the snapshots they can be rebuilt from. It is
+// safe for concurrent use.
+type MemoryEventLog struct {
+	mu        sync.RWMutex
+	events    []messages.Event      // in Seq order
+	byRound   map[int64][]int64     // each round's event Seqs, in order
+	rounds    []int64               // the rounds in byRound, oldest first
+	snapshots []messages.TableState // in Seq order
+}
+
+// NewMemoryEventLog creates an empty in-memory event log.
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{byRound: make(map[int64][]int64)}
+}
+
+// AppendEvents records events, dropping the oldest round's once the log
+// holds more than memoryRounds.
+func (l *MemoryEventLog) AppendEvents(events []messages.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range events {
+		l.events = append(l.events, e)
+		if _, ok := l.byRound[e.Round]; !ok {
+			l.rounds = append(l.rounds, e.Round)
+		}
+		l.byRound[e.Round] = append(l.byRound[e.Round], e.Seq)
+	}
+	if len(l.rounds) > memoryRounds {
+		l.trim()
+	}
+	return nil
+}
+
+// trim drops the rounds beyond memoryRounds, oldest first, and the events
+// and snapshots from before the latest snapshot the rounds left can be
+// rebuilt from. The caller must hold l.mu.
+func (l *MemoryEventLog) trim() {
+	drop := len(l.rounds) - memoryRounds
+	for _, round := range l.rounds[:drop] {
+		delete(l.byRound, round)
+	}
+	l.rounds = slices.Delete(l.rounds, 0, drop)
+
+	first := l.byRound[l.rounds[0]][0]
+	i, _ := slices.BinarySearchFunc(l.snapshots, first, func(s messages.TableState, seq int64) int {
+		return cmp.Compare(s.Seq, seq)
+	})
+	if i == 0 {
+		// No snapshot to rebuild from but the start of the log.
+		return
+	}
+	base := l.snapshots[i-1].Seq
+	l.snapshots = slices.Delete(l.snapshots, 0, i-1)
+	j, found := slices.BinarySearchFunc(l.events, base, func(e messages.Event, seq int64) int {
+		return cmp.Compare(e.Seq, seq)
+	})
+	if found {
+		j++
+	}
+	l.events = slices.Delete(l.events, 0, j)
+}
+
+// Events returns up to limit events with Seq above after, oldest first.
+func (l *MemoryEventLog) Events(after int64, limit int) ([]messages.Event, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	i, found := slices.BinarySearchFunc(l.events, after, func(e messages.Event, seq int64) int {
+		return cmp.Compare(e.Seq, seq)
+	})
+	if found {
+		i++
+	}
+	return slices.Clone(l.events[i:min(i+limit, len(l.events))]), nil
+}
+
+// RoundEvents returns a round's events, oldest first.
+func (l *MemoryEventLog) RoundEvents(round int64) ([]messages.Event, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	seqs := l.byRound[round]
+	events := make([]messages.Event, 0, len(seqs))
+	for _, seq := range seqs {
+		i, found := slices.BinarySearchFunc(l.events, seq, func(e messages.Event, seq int64) int {
+			return cmp.Compare(e.Seq, seq)
+		})
+		if found {
+			events = append(events, l.events[i])
+		}
+	}
+	return events, nil
+}
+
+// PutSnapshot records the state after the event s.Seq.
+func (l *MemoryEventLog) PutSnapshot(s messages.TableState) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snapshots = append(l.snapshots, cloneState(s))
+	return nil
+}
+
+// Snapshot returns the latest snapshot taken before the event before.
+func (l *MemoryEventLog) Snapshot(before int64) (messages.TableState, bool, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	end := len(l.snapshots)
+	if before > 0 {
+		end, _ = slices.BinarySearchFunc(l.snapshots, before, func(s messages.TableState, seq int64) int {
+			return cmp.Compare(s.Seq, seq)
+		})
+	}
+	if end == 0 {
+		return messages.TableState{}, false, nil
+	}
+	return cloneState(l.snapshots[end-1]), true, nil
+}
+
+// cloneState copies s so changes to the copy leave s alone.
+func cloneState(s messages.TableState) messages.TableState {
+	s.Bets = append([]Bet{}, s.Bets...)
+	s.Balances = maps.Clone(s.Balances)
+	if s.Balances == nil {
+		s.Balances = make(map[string]int64)
+	}
+	return s
+}
+
+// applyEvent updates s with e.
+func applyEvent(s *messages.TableState, e messages.Event) {
+	s.Seq = e.Seq
+	if s.Balances == nil {
+		s.Balances = make(map[string]int64)
+	}
+	switch e.Type {
+	case messages.EventUserJoined:
+		s.Balances[e.UserID] = e.Amount
+	case messages.EventUserLeft:
+		delete(s.Balances, e.UserID)
+	default:
+		// Money moved for a user who isn't at the table, such as a payout
+		// to one who has left, is in the ledger only.
+		if _, ok := s.Balances[e.UserID]; ok {
+			s.Balances[e.UserID] += e.Amount
+		}
+	}
+	switch e.Type {
+	case messages.EventPhaseChanged:
+		s.Phase = e.Phase
+		if e.Phase == messages.GamePhaseBetting {
+			s.Round = e.Round
+			s.Bets = nil
+			s.WinningNumber = nil
+		}
+	case messages.EventBetPlaced:
+		s.Bets = append(s.Bets, *e.Bet)
+	case messages.EventBetCancelled:
+		s.Bets = slices.DeleteFunc(s.Bets, func(b Bet) bool { return b.ID == e.Bet.ID })
+	case messages.EventWheelSpun:
+		s.WinningNumber = e.WinningNumber
+	}
+}
+
+// Replay applies events in order to a copy of from.
+func Replay(from messages.TableState, events []messages.Event) messages.TableState {
+	s := cloneState(from)
+	for _, e := range events {
+		applyEvent(&s, e)
+	}
+	return s
+}
+
+// SetEventLog sets where table events are recorded and rebuilds the table
+// state from it, so the table carries on from the round the log reached.
+// No one is seated after a restart, so anyone the log left at the table is
+// recorded as having left. Call before RunGameLoop and before any user
+// registers.
+func (m *Manager) SetEventLog(l EventLog) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.events = l
+	s, err := m.stateAt(latestSeq)
+	if err != nil {
+		slog.Error("failed to rebuild the table state", "error", err)
+		return
+	}
+	m.state = s
+	m.snapshotSeq = s.Seq
+	m.round = max(m.round, s.Round)
+
+	var left []messages.Event
+	for _, userID := range slices.Sorted(maps.Keys(s.Balances)) {
+		left = append(left, messages.Event{Type: messages.EventUserLeft, UserID: userID})
+	}
+	if len(left) > 0 {
+		m.emitLocked(left)
+	}
+}
+
+// stateAt rebuilds the state after the event seq from the latest snapshot
+// taken by then and the events since.
+func (m *Manager) stateAt(seq int64) (messages.TableState, error) {
+	var before int64
+	if seq != latestSeq {
+		before = seq + 1
+	}
+	s, _, err := m.events.Snapshot(before)
+	if err != nil {
+		return messages.TableState{}, fmt.Errorf("read snapshot: %w", err)
+	}
+	for {
+		events, err := m.events.Events(s.Seq, eventPage)
+		if err != nil {
+			return messages.TableState{}, fmt.Errorf("read events after %d: %w", s.Seq, err)
+		}
+		for _, e := range events {
+			if e.Seq > seq {
+				return s, nil
+			}
+			applyEvent(&s, e)
+		}
+		if len(events) < eventPage {
+			return s, nil
+		}
+	}
+}
+
+// emit stamps events with the next sequence numbers and the time, applies
+// them to the table state and appends them to the event log. An event with
+// no round belongs to the current one.
+func (m *Manager) emit(events ...messages.Event) {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	m.emitLocked(events)
+}
+
+// emitLocked is emit for a caller that holds eventsMu.
+func (m *Manager) emitLocked(events []messages.Event) {
+	now := time.Now()
+	for i := range events {
+		e := &events[i]
+		e.Seq = m.state.Seq + 1
+		e.At = now
+		if e.Round == 0 {
+			e.Round = m.state.Round
+		}
+		applyEvent(&m.state, *e)
+	}
+	if err := m.events.AppendEvents(events); err != nil {
+		slog.Error("failed to record events", "error", err, "count", len(events))
+	}
+	if m.state.Seq-m.snapshotSeq >= snapshotInterval {
+		if err := m.events.PutSnapshot(cloneState(m.state)); err != nil {
+			slog.Error("failed to record snapshot", "error", err, "seq", m.state.Seq)
+			return
+		}
+		m.snapshotSeq = m.state.Seq
+	}
+}
+
+// TableState returns the table state built from the events so far.
+func (m *Manager) TableState() messages.TableState {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	return cloneState(m.state)
+}
+
+// Events returns up to limit events with Seq above after, oldest first.
+func (m *Manager) Events(after int64, limit int) ([]messages.Event, error) {
+	return m.events.Events(after, limit)
+}
+
+// ReplayRound rebuilds the table as a round opened and replays the round's
+// events on it. ok is false if the log has no events for the round.
+func (m *Manager) ReplayRound(round int64) (messages.RoundReplay, bool, error) {
+	events, err := m.events.RoundEvents(round)
+	if err != nil || len(events) == 0 {
+		return messages.RoundReplay{}, false, err
+	}
+	start, err := m.stateAt(events[0].Seq - 1)
+	if err != nil {
+		return messages.RoundReplay{}, false, err
+	}
+	return messages.RoundReplay{
+		Round:  round,
+		Start:  start,
+		Events: events,
+		End:    Replay(start, events),
+	}, true, nil
+}
@@ -0,0 +1,181 @@
+package game
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"roulette/internal/messages"
+)
+
+// Spins are provably fair through commit-reveal. When betting opens the
+// server picks a secret seed and publishes its SHA-256 hash. Players may add
+// client seeds until the wheel spins. The pocket is taken from
+// HMAC-SHA256(server seed, client seeds + nonce), and the server seed is
+// revealed with the result so anyone can check it against the hash and
+// recompute the pocket with VerifySpin.
+
+// maxClientSeedLength bounds a client seed so the HMAC input stays small.
+const maxClientSeedLength = 64
+
+// roundSeed is the commitment for one round. clientSeeds is filled in when
+// the wheel spins.
+type roundSeed struct {
+	serverSeed  []byte
+	nonce       int64
+	clientSeeds []string
+}
+
+// newRoundSeed picks a fresh 32-byte server seed for round nonce.
+func newRoundSeed(nonce int64) roundSeed {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		// crypto/rand does not fail on supported platforms; an all-zero seed
+		// would still be committed to and revealed, just not secret.
+		slog.Error("failed to generate server seed", "error", err)
+	}
+	return roundSeed{serverSeed: seed, nonce: nonce}
+}
+
+// hash is the commitment published when betting opens.
+func (s roundSeed) hash() string {
+	return HashServerSeed(s.serverSeed)
+}
+
+// reveal discloses the round's seeds once it has been settled.
+func (s roundSeed) reveal() *messages.FairnessReveal {
+	return &messages.FairnessReveal{
+		ServerSeed:     hex.EncodeToString(s.serverSeed),
+		ServerSeedHash: s.hash(),
+		ClientSeeds:    s.clientSeeds,
+		Nonce:          s.nonce,
+	}
+}
+
+// SetClientSeed sets the seed the user adds to every spin from the next one on.
+func (m *Manager) SetClientSeed(userID, seed string) error {
+	if !validClientSeed(seed) {
+		return ErrInvalidClientSeed
+	}
+	user := m.GetUser(userID)
+	if user == nil {
+		return ErrUserNotFound
+	}
+	user.mu.Lock()
+	user.clientSeed = seed
+	user.mu.Unlock()
+	return nil
+}
+
+// ClientSeed returns the user's client seed, or "" if they have not set one.
+func (m *Manager) ClientSeed(userID string) string {
+	user := m.GetUser(userID)
+	if user == nil {
+		return ""
+	}
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	return user.clientSeed
+}
+
+// collectClientSeeds returns every registered user's client seed.
+func (m *Manager) collectClientSeeds() []string {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	seeds := []string{}
+	for _, user := range m.users {
+		user.mu.Lock()
+		if user.clientSeed != "" {
+			seeds = append(seeds, user.clientSeed)
+		}
+		user.mu.Unlock()
+	}
+	slices.Sort(seeds)
+	return seeds
+}
+
+// Commitment returns the hash of the current round's server seed and the
+// round's nonce. The hash is empty until the first round opens, and when
+// outcomes come from an injected RNG or a live dealer instead of the seeds.
+func (m *Manager) Commitment() (string, int64) {
+	m.sessionMu.RLock()
+	defer m.sessionMu.RUnlock()
+	if !m.provablyFair() || m.session.seed.serverSeed == nil {
+		return "", 0
+	}
+	return m.session.seed.hash(), m.session.seed.nonce
+}
+
+// provablyFair reports whether outcomes are derived from the round seeds.
+func (m *Manager) provablyFair() bool {
+	return m.rng == nil && !m.LiveDealer()
+}
+
+// spin picks the round's pocket: from the seeds, or from the injected RNG.
+// If the RNG fails the round falls back to the seeds rather than stall.
+func (m *Manager) spin(seed roundSeed) int {
+	if m.rng != nil {
+		n, err := m.rng.Spin(m.wheel)
+		if err == nil && m.wheel.Has(n) {
+			return n
+		}
+		slog.Error("wheel spin failed, using the fair spin", "error", err, "pocket", n, "variant", m.wheel.Variant)
+	}
+	return FairSpin(m.wheel, seed.serverSeed, seed.clientSeeds, seed.nonce)
+}
+
+// HashServerSeed returns the hex SHA-256 of a server seed.
+func HashServerSeed(serverSeed []byte) string {
+	sum := sha256.Sum256(serverSeed)
+	return hex.EncodeToString(sum[:])
+}
+
+// validClientSeed reports whether seed can be used as a client seed. The
+// character set keeps seeds free of the separators in the HMAC input.
+func validClientSeed(seed string) bool {
+	if len(seed) == 0 || len(seed) > maxClientSeedLength {
+		return false
+	}
+	for _, r := range seed {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// fairMessage is the HMAC input: the client seeds sorted and joined with
+// commas, a colon, then the nonce. Sorting means the order seeds were
+// collected in doesn't matter.
+func fairMessage(clientSeeds []string, nonce int64) string {
+	seeds := slices.Sorted(slices.Values(clientSeeds))
+	return strings.Join(seeds, ",") + ":" + strconv.FormatInt(nonce, 10)
+}
+
+// FairSpin derives the winning pocket on w from a server seed, the round's
+// client seeds and its nonce. The first 8 bytes of the HMAC are read as a
+// big-endian integer and reduced modulo the wheel size (a bias below 1e-17),
+// then mapped to a pocket with the zeros first, followed by 1-36.
+func FairSpin(w *Wheel, serverSeed []byte, clientSeeds []string, nonce int64) int {
+	mac := hmac.New(sha256.New, serverSeed)
+	mac.Write([]byte(fairMessage(clientSeeds, nonce)))
+	n := binary.BigEndian.Uint64(mac.Sum(nil))
+	return w.pocketAt(int(n % uint64(w.Size())))
+}
+
+// VerifySpin recomputes a revealed round on w. serverSeed and serverSeedHash
+// are hex, as published. It returns ErrSeedMismatch if the seed does not hash
+// to the commitment, and otherwise the pocket the round must have landed on.
+func VerifySpin(w *Wheel, serverSeed, serverSeedHash string, clientSeeds []string, nonce int64) (int, error) {
+	seed, err := hex.DecodeString(serverSeed)
+	if err != nil || !strings.EqualFold(HashServerSeed(seed), serverSeedHash) {
+		return 0, ErrSeedMismatch
+	}
+	return FairSpin(w, seed, clientSeeds, nonce), nil
+}
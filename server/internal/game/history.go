@@ -0,0 +1,169 @@
+package game
+
+import (
+	"cmp"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// HistoryLength is how many recent results the history message carries.
+const HistoryLength = 20
+
+// RoundStore keeps the history of settled and voided rounds. Round IDs
+// increase, so they double as the history's order.
+type RoundStore interface {
+	// PutRound stores a round, replacing any with the same ID.
+	PutRound(r messages.Round) error
+	// GetRound returns the round with id. ok is false if there is none.
+	GetRound(id int64) (r messages.Round, ok bool, err error)
+	// Rounds returns up to limit rounds with IDs below before, newest
+	// first. A before of 0 starts from the latest round.
+	Rounds(before int64, limit int) ([]messages.Round, error)
+}
+
+// MemoryRoundStore keeps the most recent rounds in memory. It is safe for
+// concurrent use.
+type MemoryRoundStore struct {
+	mu       sync.RWMutex
+	capacity int
+	rounds   []messages.Round // oldest first
+}
+
+// NewMemoryRoundStore creates a store that keeps the last capacity rounds.
+func NewMemoryRoundStore(capacity int) *MemoryRoundStore {
+	return &MemoryRoundStore{capacity: max(capacity, 1)}
+}
+
+// PutRound stores a round, dropping the oldest once the store is full.
+func (s *MemoryRoundStore) PutRound(r messages.Round) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, found := slices.BinarySearchFunc(s.rounds, r.ID, func(r messages.Round, id int64) int {
+		return cmp.Compare(r.ID, id)
+	})
+	if found {
+		s.rounds[i] = r
+		return nil
+	}
+	s.rounds = slices.Insert(s.rounds, i, r)
+	if len(s.rounds) > s.capacity {
+		s.rounds = slices.Delete(s.rounds, 0, len(s.rounds)-s.capacity)
+	}
+	return nil
+}
+
+// GetRound returns the round with id.
+func (s *MemoryRoundStore) GetRound(id int64) (messages.Round, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i, found := slices.BinarySearchFunc(s.rounds, id, func(r messages.Round, id int64) int {
+		return cmp.Compare(r.ID, id)
+	})
+	if !found {
+		return messages.Round{}, false, nil
+	}
+	return s.rounds[i], true, nil
+}
+
+// Rounds returns up to limit rounds with IDs below before, newest first.
+func (s *MemoryRoundStore) Rounds(before int64, limit int) ([]messages.Round, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	end := len(s.rounds)
+	if before > 0 {
+		end, _ = slices.BinarySearchFunc(s.rounds, before, func(r messages.Round, id int64) int {
+			return cmp.Compare(r.ID, id)
+		})
+	}
+	rounds := make([]messages.Round, 0, max(min(limit, end), 0))
+	for i := end - 1; i >= 0 && len(rounds) < limit; i-- {
+		rounds = append(rounds, s.rounds[i])
+	}
+	return rounds, nil
+}
+
+// SetRoundStore sets where rounds are kept. Round IDs carry on from the
+// latest round in the store. Call before RunGameLoop.
+func (m *Manager) SetRoundStore(s RoundStore) {
+	m.rounds = s
+	latest, err := s.Rounds(0, 1)
+	if err != nil {
+		slog.Error("failed to read the latest round", "error", err)
+		return
+	}
+	if len(latest) > 0 {
+		m.round = latest[0].ID
+	}
+}
+
+// Round returns a round from the history.
+func (m *Manager) Round(id int64) (messages.Round, bool, error) {
+	return m.rounds.GetRound(id)
+}
+
+// Rounds returns up to limit rounds from the history with IDs below
+// before, newest first. A before of 0 starts from the latest round.
+func (m *Manager) Rounds(before int64, limit int) ([]messages.Round, error) {
+	return m.rounds.Rounds(before, limit)
+}
+
+// RecentResults returns where the last n rounds that were not voided
+// landed, newest first.
+func (m *Manager) RecentResults(n int) ([]messages.RoundResult, error) {
+	results := make([]messages.RoundResult, 0, n)
+	var before int64
+	for len(results) < n {
+		rounds, err := m.rounds.Rounds(before, n)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rounds {
+			if r.WinningNumber != nil && len(results) < n {
+				results = append(results, messages.RoundResult{
+					RoundID:       r.ID,
+					WinningNumber: *r.WinningNumber,
+					WinningPocket: PocketLabel(*r.WinningNumber),
+				})
+			}
+		}
+		if len(rounds) < n {
+			break
+		}
+		before = rounds[len(rounds)-1].ID
+	}
+	return results, nil
+}
+
+// recordRound adds a finished round to the history: the bets settled or
+// refunded in session and their payouts. A nil winningNumber marks the
+// round voided.
+func (m *Manager) recordRound(session *GameSession, bets []Bet, payouts []Payout, winningNumber *int, voidReason string) {
+	round := messages.Round{
+		ID:            session.round,
+		Variant:       m.wheel.Variant,
+		StartedAt:     session.startedAt,
+		EndedAt:       time.Now(),
+		WinningNumber: winningNumber,
+		Voided:        winningNumber == nil,
+		VoidReason:    voidReason,
+		Bets:          bets,
+		Payouts:       payouts,
+	}
+	if winningNumber != nil {
+		label := PocketLabel(*winningNumber)
+		round.WinningPocket = &label
+	}
+	if round.Bets == nil {
+		round.Bets = []Bet{}
+	}
+	if round.Payouts == nil {
+		round.Payouts = []Payout{}
+	}
+	if err := m.rounds.PutRound(round); err != nil {
+		slog.Error("failed to record round", "error", err, "round", round.ID)
+	}
+}
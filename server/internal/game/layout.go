@@ -0,0 +1,99 @@
+package game
+
+import (
+	"slices"
+	"strings"
+)
+
+// The betting layout is 12 rows of 3 numbers: row r holds 3r+1, 3r+2, 3r+3.
+// The wheel's zeros sit above the first row; which numbers they touch
+// depends on the variant (see Wheel).
+
+// parseNumbers parses a dash-separated pocket list such as "1-2-4-5" or "0-00".
+// The result is sorted ascending. Duplicates and pockets not on w are rejected.
+func parseNumbers(w *Wheel, value string) ([]int, bool) {
+	parts := strings.Split(value, "-")
+	nums := make([]int, 0, len(parts))
+	for _, p := range parts {
+		n, ok := w.ParsePocket(p)
+		if !ok {
+			return nil, false
+		}
+		nums = append(nums, n)
+	}
+	slices.Sort(nums)
+	if len(slices.Compact(slices.Clone(nums))) != len(nums) {
+		return nil, false
+	}
+	return nums, true
+}
+
+// lastInRow reports whether n is in the right-hand column of the layout.
+func lastInRow(n int) bool {
+	return n%3 == 0
+}
+
+// hasZero reports whether any of nums is one of w's zeros.
+func hasZero(w *Wheel, nums []int) bool {
+	return slices.ContainsFunc(nums, w.IsZero)
+}
+
+// isOneOf reports whether nums equals one of the sorted groups.
+func isOneOf(nums []int, groups [][]int) bool {
+	return slices.ContainsFunc(groups, func(g []int) bool { return slices.Equal(nums, g) })
+}
+
+// isSplit reports whether nums are two pockets sharing an edge on the layout.
+func isSplit(w *Wheel, nums []int) bool {
+	if len(nums) != 2 {
+		return false
+	}
+	if hasZero(w, nums) {
+		return isOneOf(nums, w.ZeroSplits)
+	}
+	a, b := nums[0], nums[1]
+	return (b == a+1 && !lastInRow(a)) || b == a+3
+}
+
+// isStreet reports whether nums form a row of three, or one of w's zero trios.
+func isStreet(w *Wheel, nums []int) bool {
+	if len(nums) != 3 {
+		return false
+	}
+	if hasZero(w, nums) {
+		return isOneOf(nums, w.ZeroStreets)
+	}
+	a := nums[0]
+	return a%3 == 1 && nums[1] == a+1 && nums[2] == a+2
+}
+
+// isCorner reports whether nums are four numbers meeting at a corner.
+func isCorner(w *Wheel, nums []int) bool {
+	if len(nums) != 4 || hasZero(w, nums) {
+		return false
+	}
+	a := nums[0]
+	return !lastInRow(a) && nums[1] == a+1 && nums[2] == a+3 && nums[3] == a+4
+}
+
+// isSixLine reports whether nums are two adjacent rows.
+func isSixLine(w *Wheel, nums []int) bool {
+	if len(nums) != 6 || hasZero(w, nums) {
+		return false
+	}
+	a := nums[0]
+	if a%3 != 1 {
+		return false
+	}
+	for i, n := range nums {
+		if n != a+i {
+			return false
+		}
+	}
+	return true
+}
+
+// isTopLine reports whether nums are w's zeros plus the first row.
+func isTopLine(w *Wheel, nums []int) bool {
+	return slices.Equal(nums, w.TopLine)
+}
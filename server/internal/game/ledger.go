@@ -0,0 +1,343 @@
+package game
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// Every balance change is a double-entry transaction between the user and
+// the house, kept in an append-only ledger. A user's balance is always the
+// sum of their entries, credits less debits; Reconcile checks that it is.
+
+// HouseAccount is the ledger account on the other side of every user's
+// transactions.
+const HouseAccount = "house"
+
+// LedgerStore is an append-only log of balance transactions.
+type LedgerStore interface {
+	// AppendTransactions assigns IDs to txs in order, setting them in txs,
+	// and records them, all or none.
+	AppendTransactions(txs []messages.Transaction) error
+	// Transactions returns up to limit of a user's transactions with IDs
+	// below before, newest first. A before of 0 starts from the latest.
+	Transactions(userID string, before int64, limit int) ([]messages.Transaction, error)
+	// LedgerBalance sums a user's entries: credits to them less debits from them.
+	LedgerBalance(userID string) (int64, error)
+	// RoundTransactions returns every user's transactions in rounds with
+	// the ID round, at any table, in order.
+	RoundTransactions(round int64) ([]messages.Transaction, error)
+}
+
+// memoryTransactions is how many of each user's transactions the memory
+// ledger keeps.
+const memoryTransactions = 1000
+
+// MemoryLedger keeps the ledger in memory: each user's last
+// memoryTransactions transactions, and the last memoryRounds rounds'
+// transactions by round. A user's ledger balance still counts the ones it
+// has dropped. It is safe for concurrent use.
+type MemoryLedger struct {
+	mu       sync.RWMutex
+	lastID   int64
+	byUser   map[string][]messages.Transaction // oldest first
+	balances map[string]int64                  // the sum of each user's entries
+	byRound  map[int64][]messages.Transaction  // in ID order
+	rounds   []int64                           // the indexed rounds, oldest first
+}
+
+// NewMemoryLedger creates an empty in-memory ledger.
+func NewMemoryLedger() *MemoryLedger {
+	return &MemoryLedger{
+		byUser:   make(map[string][]messages.Transaction),
+		balances: make(map[string]int64),
+		byRound:  make(map[int64][]messages.Transaction),
+	}
+}
+
+// AppendTransactions records txs, dropping the oldest once a user's
+// history or the round index is full.
+func (l *MemoryLedger) AppendTransactions(txs []messages.Transaction) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range txs {
+		l.lastID++
+		txs[i].ID = l.lastID
+		tx := txs[i]
+		log := append(l.byUser[tx.UserID], tx)
+		if len(log) > memoryTransactions {
+			log = slices.Delete(log, 0, len(log)-memoryTransactions)
+		}
+		l.byUser[tx.UserID] = log
+		l.balances[tx.UserID] += entryAmount(tx, tx.UserID)
+		if tx.RoundID == 0 {
+			continue
+		}
+		if _, ok := l.byRound[tx.RoundID]; !ok {
+			l.rounds = append(l.rounds, tx.RoundID)
+			if len(l.rounds) > memoryRounds {
+				delete(l.byRound, l.rounds[0])
+				l.rounds = slices.Delete(l.rounds, 0, 1)
+			}
+		}
+		l.byRound[tx.RoundID] = append(l.byRound[tx.RoundID], tx)
+	}
+	return nil
+}
+
+// Transactions returns up to limit of a user's transactions with IDs below
+// before, newest first.
+func (l *MemoryLedger) Transactions(userID string, before int64, limit int) ([]messages.Transaction, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	log := l.byUser[userID]
+	end := len(log)
+	if before > 0 {
+		end, _ = slices.BinarySearchFunc(log, before, func(tx messages.Transaction, id int64) int {
+			return cmp.Compare(tx.ID, id)
+		})
+	}
+	txs := make([]messages.Transaction, 0, max(min(limit, end), 0))
+	for i := end - 1; i >= 0 && len(txs) < limit; i-- {
+		txs = append(txs, log[i])
+	}
+	return txs, nil
+}
+
+// LedgerBalance sums a user's entries.
+func (l *MemoryLedger) LedgerBalance(userID string) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.balances[userID], nil
+}
+
+// RoundTransactions returns every user's transactions in a round, in order.
+func (l *MemoryLedger) RoundTransactions(round int64) ([]messages.Transaction, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Clone(l.byRound[round]), nil
+}
+
+// entryAmount is what tx adds to account's balance.
+func entryAmount(tx messages.Transaction, account string) int64 {
+	switch account {
+	case tx.Credit:
+		return tx.Amount
+	case tx.Debit:
+		return -tx.Amount
+	}
+	return 0
+}
+
+// posting is one change to a user's balance.
+type posting struct {
+	kind   messages.TransactionKind
+	amount int64 // added to the balance; negative takes money off
+	betID  string
+	note   string
+}
+
+// Poster is a LedgerStore that also keeps the users, and can record
+// transactions and save the users they change in one write. Without one a
+// crash between the two writes could leave a stored balance behind the
+// ledger.
+type Poster interface {
+	// Post records txs as AppendTransactions does and saves users, all or
+	// none. A transaction for a user not in users is added to their stored
+	// balance instead, as addToStored does.
+	Post(txs []messages.Transaction, users []UserRecord) error
+}
+
+// addToStored adds the transactions of users not in saved to their stored
+// balances, read with get, and sets each one's BalanceBefore and
+// BalanceAfter from them. It returns the records to save with the
+// transactions.
+func addToStored(txs []messages.Transaction, saved []UserRecord, get func(id string) (UserRecord, bool, error)) ([]UserRecord, error) {
+	stored := make(map[string]*UserRecord)
+	var order []string
+	for i, tx := range txs {
+		if slices.ContainsFunc(saved, func(rec UserRecord) bool { return rec.ID == tx.UserID }) {
+			continue
+		}
+		rec, ok := stored[tx.UserID]
+		if !ok {
+			r, found, err := get(tx.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("%w: %s", ErrUserNotFound, tx.UserID)
+			}
+			rec = &r
+			stored[tx.UserID] = rec
+			order = append(order, tx.UserID)
+		}
+		txs[i].BalanceBefore = rec.Balance
+		rec.Balance += entryAmount(tx, tx.UserID)
+		txs[i].BalanceAfter = rec.Balance
+	}
+	recs := make([]UserRecord, len(order))
+	for i, id := range order {
+		recs[i] = *stored[id]
+	}
+	return recs, nil
+}
+
+// post applies postings to the user's balance in order, records each as a
+// transaction with the house in round and saves the user with them, and
+// returns the transactions recorded. If the write fails the balance is left
+// as it was and ErrLedgerWrite returned. The caller must hold user.mu.
+func (m *Manager) post(user *User, round int64, postings ...posting) ([]messages.Transaction, error) {
+	txs, balance := m.transactions(user, round, postings)
+	if len(txs) == 0 {
+		return nil, nil
+	}
+	rec := user.record()
+	rec.Balance = balance
+	if err := m.writePostings(txs, []UserRecord{rec}); err != nil {
+		return nil, err
+	}
+	user.Balance = balance
+	return txs, nil
+}
+
+// transactions turns postings into transactions with the house in round,
+// applied in order from the user's balance, and returns the balance they
+// leave. It changes nothing. The caller must hold user.mu.
+func (m *Manager) transactions(user *User, round int64, postings []posting) ([]messages.Transaction, int64) {
+	now := time.Now()
+	balance := user.Balance
+	txs := make([]messages.Transaction, 0, len(postings))
+	for _, p := range postings {
+		if p.amount == 0 {
+			continue
+		}
+		tx := messages.Transaction{
+			UserID:        user.ID,
+			TableID:       m.tableID,
+			RoundID:       round,
+			Kind:          p.kind,
+			Debit:         HouseAccount,
+			Credit:        user.ID,
+			Amount:        p.amount,
+			BalanceBefore: balance,
+			BetID:         p.betID,
+			Note:          p.note,
+			CreatedAt:     now,
+		}
+		if p.amount < 0 {
+			tx.Debit, tx.Credit, tx.Amount = user.ID, HouseAccount, -p.amount
+		}
+		balance += p.amount
+		tx.BalanceAfter = balance
+		txs = append(txs, tx)
+	}
+	return txs, balance
+}
+
+// writePostings records txs and saves users, in one write if the ledger is
+// a Poster that keeps the users too. Transactions for users not in users
+// are added to their stored balances, as Post does. The in-memory stores
+// are written one after the other, as a crash loses both anyway.
+func (m *Manager) writePostings(txs []messages.Transaction, users []UserRecord) error {
+	var err error
+	if p, ok := m.ledger.(Poster); ok && any(m.ledger) == any(m.store) {
+		err = p.Post(txs, users)
+	} else {
+		var stored []UserRecord
+		if stored, err = addToStored(txs, users, m.store.GetUser); err == nil {
+			err = m.ledger.AppendTransactions(txs)
+		}
+		for _, rec := range slices.Concat(users, stored) {
+			if err != nil {
+				break
+			}
+			err = m.store.PutUser(rec)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to record transactions", "error", err, "count", len(txs))
+		return ErrLedgerWrite
+	}
+	return nil
+}
+
+// SetLedger sets where balance transactions are recorded. Call before
+// RunGameLoop and before any user registers.
+func (m *Manager) SetLedger(l LedgerStore) {
+	m.ledger = l
+}
+
+// Transactions returns up to limit of a user's transactions with IDs below
+// before, newest first. A before of 0 starts from the latest.
+func (m *Manager) Transactions(userID string, before int64, limit int) ([]messages.Transaction, error) {
+	return m.ledger.Transactions(userID, before, limit)
+}
+
+// AdjustBalance adds amount to a user's balance as an admin adjustment, or
+// takes it off if negative. The user must be at the table or recently
+// disconnected. Returns the transaction it recorded, whose BalanceAfter is
+// the new balance.
+func (m *Manager) AdjustBalance(userID string, amount int64, note string) (messages.Transaction, error) {
+	if amount == 0 {
+		return messages.Transaction{}, ErrInvalidAmount
+	}
+	user := m.GetUser(userID)
+	if user == nil {
+		return messages.Transaction{}, ErrUserNotFound
+	}
+	user.mu.Lock()
+	if user.Balance+amount < 0 {
+		user.mu.Unlock()
+		return messages.Transaction{}, ErrInsufficientBalance
+	}
+	txs, err := m.post(user, 0, posting{kind: messages.TransactionAdjustment, amount: amount, note: note})
+	user.mu.Unlock()
+	if err != nil {
+		return messages.Transaction{}, err
+	}
+	tx := txs[0]
+	m.emit(messages.Event{Type: messages.EventBalanceAdjusted, UserID: userID, Amount: amount})
+
+	slog.Info("balance adjusted", "user_id", userID, "amount", amount, "note", note, "balance", tx.BalanceAfter)
+	m.NotifyBalanceUpdated(userID, tx.BalanceAfter)
+	return tx, nil
+}
+
+// Reconcile checks every user in memory against the ledger and reports
+// those whose balance is not the sum of their entries.
+func (m *Manager) Reconcile() (messages.ReconcileResponse, error) {
+	m.usersMu.RLock()
+	users := maps.Clone(m.users)
+	m.usersMu.RUnlock()
+
+	res := messages.ReconcileResponse{Mismatches: []messages.LedgerMismatch{}}
+	for _, userID := range slices.Sorted(maps.Keys(users)) {
+		user := users[userID]
+		// Hold the user so no transaction lands between the two reads.
+		user.mu.Lock()
+		balance := user.Balance
+		sum, err := m.ledger.LedgerBalance(userID)
+		user.mu.Unlock()
+		if err != nil {
+			return messages.ReconcileResponse{}, err
+		}
+
+		res.Checked++
+		if sum != balance {
+			slog.Error("balance does not match the ledger", "user_id", userID, "balance", balance, "ledger_balance", sum)
+			res.Mismatches = append(res.Mismatches, messages.LedgerMismatch{
+				UserID:        userID,
+				Balance:       balance,
+				LedgerBalance: sum,
+			})
+		}
+	}
+	return res, nil
+}
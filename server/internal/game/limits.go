@@ -0,0 +1,112 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"roulette/internal/messages"
+)
+
+// BetLimit bounds the stake on a single bet. Zero means no bound.
+type BetLimit struct {
+	Min int64
+	Max int64
+}
+
+// TableLimits caps what players can stake and what the house can owe in one
+// round. Zero values mean no limit.
+type TableLimits struct {
+	// Bets bounds each bet by type. A call bet is bounded by its total stake.
+	Bets map[messages.BetType]BetLimit
+	// MaxPlayerStake caps the total a single player can stake in one round.
+	MaxPlayerStake int64
+	// MaxLiability caps what the table would pay out (stakes plus winnings)
+	// if any single pocket came up.
+	MaxLiability int64
+}
+
+// checkBet enforces the per-type minimum and maximum.
+func (l TableLimits) checkBet(bet Bet) error {
+	limit := l.Bets[bet.Type]
+	if limit.Min > 0 && bet.Amount < limit.Min {
+		return fmt.Errorf("%w: %s minimum is %d", ErrBetBelowMinimum, bet.Type, limit.Min)
+	}
+	if limit.Max > 0 && bet.Amount > limit.Max {
+		return fmt.Errorf("%w: %s maximum is %d", ErrBetAboveMaximum, bet.Type, limit.Max)
+	}
+	return nil
+}
+
+// checkRound enforces the round-wide limits given what is already on the table.
+// staked is the player's stake so far this round; liability is the table's
+// current payout per pocket.
+func (l TableLimits) checkRound(bet Bet, exposure map[int]int64, staked int64, liability map[int]int64) error {
+	if l.MaxPlayerStake > 0 && staked+bet.Amount > l.MaxPlayerStake {
+		return fmt.Errorf("%w: at most %d per round, %d already staked", ErrPlayerStakeLimit, l.MaxPlayerStake, staked)
+	}
+	if l.MaxLiability > 0 {
+		// Check pockets in order so the error names the same pocket every time.
+		pockets := make([]int, 0, len(exposure))
+		for p := range exposure {
+			pockets = append(pockets, p)
+		}
+		slices.Sort(pockets)
+		for _, p := range pockets {
+			if liability[p]+exposure[p] > l.MaxLiability {
+				return fmt.Errorf("%w: pocket %s", ErrTableLiabilityLimit, PocketLabel(p))
+			}
+		}
+	}
+	return nil
+}
+
+// Exposure returns, for each pocket bet wins on, what it would return
+// (stake plus winnings) if that pocket came up.
+func (r *BetRegistry) Exposure(w *Wheel, bet Bet) map[int]int64 {
+	exposure := make(map[int]int64)
+	r.addExposure(w, bet, exposure)
+	return exposure
+}
+
+func (r *BetRegistry) addExposure(w *Wheel, bet Bet, exposure map[int]int64) {
+	if len(bet.Components) > 0 {
+		for _, c := range bet.Components {
+			r.addExposure(w, c, exposure)
+		}
+		return
+	}
+	kind, ok := r.kinds[bet.Type]
+	if !ok {
+		return
+	}
+	for _, p := range kind.Covered(w, bet.Value) {
+		exposure[p] += bet.Amount * (kind.Multiplier(w) + 1)
+	}
+}
+
+// RejectCode classifies a bet rejection for clients.
+func RejectCode(err error) messages.BetRejectCode {
+	switch {
+	case errors.Is(err, ErrBetBelowMinimum):
+		return messages.BetRejectBelowMinimum
+	case errors.Is(err, ErrBetAboveMaximum):
+		return messages.BetRejectAboveMaximum
+	case errors.Is(err, ErrPlayerStakeLimit):
+		return messages.BetRejectPlayerStakeLimit
+	case errors.Is(err, ErrTableLiabilityLimit):
+		return messages.BetRejectTableLiabilityLimit
+	case errors.Is(err, ErrInsufficientBalance):
+		return messages.BetRejectInsufficientBalance
+	case errors.Is(err, ErrBettingClosed):
+		return messages.BetRejectBettingClosed
+	case errors.Is(err, ErrBetNotFound):
+		return messages.BetRejectBetNotFound
+	case errors.Is(err, ErrNoPreviousBets):
+		return messages.BetRejectNoPreviousBets
+	case errors.Is(err, ErrTooManyBets):
+		return messages.BetRejectTooManyBets
+	default:
+		return messages.BetRejectInvalidBet
+	}
+}
@@ -1,12 +1,18 @@
 package game
 
 import (
+	"cmp"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	mathrand "math/rand/v2"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 
@@ -17,6 +23,10 @@ const maxNameLength = 20
 const cleanupInterval = 1 * time.Minute
 const disconnectGracePeriod = 15 * time.Minute
 
+// memoryRounds is how many rounds the default round store and event log
+// keep, and the memory ledger indexes.
+const memoryRounds = 1000
+
 func sanitizeName(name string) string {
 	name = strings.Map(func(r rune) rune {
 		if unicode.IsControl(r) {
@@ -66,8 +76,44 @@ type Manager struct {
 	currentCountdown int // Track countdown for mid-join sync
 	broadcast        BroadcastFunc
 	sendToUser       SendToUserFunc
+	onSeated         func(userID string)
+	sendSpectators   BroadcastFunc // nil sends spectators nothing of their own
+	spectators       atomic.Int64
+	lastSpectators   int // the spectator count last broadcast; game loop only
 	connChecker      ConnectionChecker
 	clock            Clock
+	rng              WheelRNG      // nil spins with the provably fair derivation
+	physics          *PhysicsWheel // nil sends no trajectory
+	rngMonitor       *RNGMonitor   // nil runs no bias tests
+	store            UserStore     // every user, including those no longer in users
+	rounds           RoundStore
+	ledger           LedgerStore
+	settlements      SettlementStore
+	events           EventLog
+	eventsMu         sync.Mutex          // guards state and snapshotSeq; taken last
+	state            messages.TableState // built from the events emitted so far
+	snapshotSeq      int64               // Seq of the latest snapshot
+	resumeCh         chan struct{}
+	adminToken       string
+	dealerTimeout    time.Duration // non-zero in live dealer mode
+	dealerMu         sync.Mutex
+	awaiting         bool // a live round is waiting for the croupier
+	pending          *int // first entry of the live result, awaiting confirmation
+	dealerCh         chan dealerDecision
+	betKinds         *BetRegistry
+	wheel            *Wheel
+	zeroRule         messages.ZeroRule
+	limits           TableLimits
+	durationsMu      sync.Mutex
+	durations        PhaseDurations  // read with phaseDurations
+	maxSeats         int             // 0 for no limit; guarded by usersMu
+	queue            []string        // users waiting for a seat, first in line first; guarded by usersMu
+	reserved         map[string]bool // users moving here from another table, whose seats are held; guarded by usersMu
+	seatGrace        time.Duration   // 0 keeps a disconnected player's seat until they are evicted
+	closed           bool            // the table admits no one; guarded by usersMu
+	tableID          string          // recorded on the table's transactions
+	imprisoned       []Bet           // En Prison bets carried into the next round; game loop only
+	round            int64           // rounds opened so far, used as the fairness nonce; game loop only
 	stopCh           chan struct{}
 	cleanupTicker    *time.Ticker
 	cleanupStopCh    chan struct{}
@@ -77,10 +123,22 @@ type Manager struct {
 func NewManager(broadcastAll BroadcastFunc, sendToUser SendToUserFunc) *Manager {
 	m := &Manager{
 		users:         make(map[string]*User),
+		reserved:      make(map[string]bool),
 		session:       &GameSession{State: StateBetting},
 		broadcast:     broadcastAll,
 		sendToUser:    sendToUser,
 		clock:         realClock{},
+		store:         NewMemoryUserStore(),
+		rounds:        NewMemoryRoundStore(memoryRounds),
+		ledger:        NewMemoryLedger(),
+		settlements:   NewMemorySettlementStore(),
+		events:        NewMemoryEventLog(),
+		betKinds:      DefaultBetRegistry(),
+		wheel:         EuropeanWheel,
+		zeroRule:      messages.ZeroRuleNone,
+		durations:     DefaultPhaseDurations(),
+		dealerCh:      make(chan dealerDecision, 1),
+		resumeCh:      make(chan struct{}, 1),
 		stopCh:        make(chan struct{}),
 		cleanupStopCh: make(chan struct{}),
 	}
@@ -102,6 +160,136 @@ func (m *Manager) SetClock(c Clock) {
 	m.clock = c
 }
 
+// SetRNG replaces the provably fair spin with rng. Rounds spun this way
+// publish no seed commitment and reveal no seeds. Call before RunGameLoop.
+// Intended for tests, replays and QA runs that need to control outcomes.
+func (m *Manager) SetRNG(rng WheelRNG) {
+	m.rng = rng
+}
+
+// SetPhysics makes the table simulate each spin with p and send the ball's
+// trajectory to clients when the wheel spins. The outcome still comes from
+// the table's RNG. Each trajectory lasts the table's spinning phase at the
+// time plus the start of the result phase, whatever p's Duration. Call
+// before RunGameLoop.
+func (m *Manager) SetPhysics(p *PhysicsWheel) {
+	m.physics = p
+}
+
+// SetLiveDealer switches the table to live dealer mode: results are entered
+// by a croupier instead of spun, and a round with no confirmed result after
+// timeout is voided. Call before RunGameLoop.
+func (m *Manager) SetLiveDealer(timeout time.Duration) {
+	m.dealerTimeout = timeout
+}
+
+// SetAdminToken sets the secret that authorizes croupier and other admin
+// actions. An empty token disables them. Call before RunGameLoop.
+func (m *Manager) SetAdminToken(token string) {
+	m.adminToken = token
+}
+
+// SetBetRegistry replaces the bet kinds accepted at this table. Call before RunGameLoop.
+func (m *Manager) SetBetRegistry(r *BetRegistry) {
+	m.betKinds = r
+}
+
+// SetWheel selects the wheel variant this table spins. Call before RunGameLoop.
+func (m *Manager) SetWheel(w *Wheel) {
+	m.wheel = w
+}
+
+// Wheel returns the wheel variant this table spins.
+func (m *Manager) Wheel() *Wheel {
+	return m.wheel
+}
+
+// SetZeroRule selects how even-money bets are treated when a zero comes up.
+// Call before RunGameLoop.
+func (m *Manager) SetZeroRule(rule messages.ZeroRule) {
+	m.zeroRule = rule
+}
+
+// ZeroRule returns the table's zero rule.
+func (m *Manager) ZeroRule() messages.ZeroRule {
+	return m.zeroRule
+}
+
+// SetLimits sets the table's betting limits. Call before RunGameLoop.
+func (m *Manager) SetLimits(l TableLimits) {
+	m.limits = l
+}
+
+// SetPhaseDurations sets how long each phase of a round lasts. Zero
+// durations keep the defaults. A change while the game loop runs takes
+// effect from the next phase.
+func (m *Manager) SetPhaseDurations(d PhaseDurations) {
+	def := DefaultPhaseDurations()
+	m.durationsMu.Lock()
+	defer m.durationsMu.Unlock()
+	m.durations = PhaseDurations{
+		Betting:  cmp.Or(d.Betting, def.Betting),
+		Spinning: cmp.Or(d.Spinning, def.Spinning),
+		Result:   cmp.Or(d.Result, def.Result),
+	}
+}
+
+// phaseDurations returns how long each phase of a round lasts.
+func (m *Manager) phaseDurations() PhaseDurations {
+	m.durationsMu.Lock()
+	defer m.durationsMu.Unlock()
+	return m.durations
+}
+
+// SetMaxSeats caps how many players the table admits, counting those
+// disconnected within the grace period. 0 means no limit. Players already
+// seated keep their seats if the cap drops below them.
+func (m *Manager) SetMaxSeats(n int) {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	m.maxSeats = n
+}
+
+// admitting returns why the table can't take another player, or nil if it
+// can. While anyone is waiting for a seat, newcomers wait behind them. The
+// caller must hold usersMu.
+func (m *Manager) admitting() error {
+	switch {
+	case m.closed:
+		return ErrTableClosed
+	case !m.seatFree() || len(m.queue) > 0:
+		return ErrTableFull
+	}
+	return nil
+}
+
+// seatFree reports whether the table has a seat for one more player. The
+// caller must hold usersMu.
+func (m *Manager) seatFree() bool {
+	return !m.closed && (m.maxSeats == 0 || len(m.users)+len(m.reserved) < m.maxSeats)
+}
+
+// SetTableID sets the ID of the table the manager runs, which its
+// transactions are recorded against. Call before RunGameLoop.
+func (m *Manager) SetTableID(id string) {
+	m.tableID = id
+}
+
+// TableID returns the ID of the table the manager runs.
+func (m *Manager) TableID() string {
+	return m.tableID
+}
+
+// generateBetID creates a random 8-byte hex ID for a placed bet.
+func generateBetID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		slog.Error("failed to generate bet ID", "error", err)
+		return "fallback-bet"
+	}
+	return hex.EncodeToString(b)
+}
+
 // generateSessionToken creates a cryptographically random 16-byte hex token.
 func generateSessionToken() string {
 	b := make([]byte, 16)
@@ -114,23 +302,70 @@ func generateSessionToken() string {
 	return hex.EncodeToString(b)
 }
 
-// RegisterUser creates a new user with the starting balance and a session token.
+// RegisterUser creates a new user with the starting balance and a session
+// token. A user the table or the user store already knows is returned as
+// they are, so registering twice doesn't open a second balance. Returns nil
+// if the user store can't be read or the opening balance recorded.
 func (m *Manager) RegisterUser(userID string) *User {
 	m.usersMu.Lock()
 	defer m.usersMu.Unlock()
+	user, err := m.registerUser(userID)
+	if err != nil {
+		slog.Error("failed to register user", "error", err, "user_id", userID)
+	}
+	return user
+}
+
+// JoinUser registers a new user like RegisterUser, unless the table is full
+// or closed.
+func (m *Manager) JoinUser(userID string) (*User, error) {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	if err := m.admitting(); err != nil {
+		return nil, err
+	}
+	return m.registerUser(userID)
+}
+
+// registerUser seats a known user, or creates a new one. The caller must
+// hold usersMu.
+func (m *Manager) registerUser(userID string) (*User, error) {
+	user, err := m.loadUserLocked(userID, func(UserRecord) bool { return true })
+	if err != nil || user != nil {
+		return user, err
+	}
+	if user, err = m.newUser(userID); err != nil {
+		return nil, err
+	}
+	m.users[userID] = user
+	m.emit(messages.Event{Type: messages.EventUserJoined, UserID: userID, Amount: StartingBalance})
+	return user, nil
+}
 
+// newUser creates a new user with the starting balance and saves them,
+// without seating them.
+func (m *Manager) newUser(userID string) (*User, error) {
 	user := &User{
 		ID:           userID,
-		Balance:      StartingBalance,
 		SessionToken: generateSessionToken(),
 	}
-	m.users[userID] = user
-	return user
+	user.mu.Lock()
+	_, err := m.post(user, 0, posting{kind: messages.TransactionOpening, amount: StartingBalance})
+	user.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	m.emit(messages.Event{Type: messages.EventUserRegistered, UserID: userID, Amount: StartingBalance})
+	return user, nil
 }
 
 // ValidateSessionToken returns true if the given token matches the stored token for userID.
+// A user who is no longer in memory is restored from the user store.
 func (m *Manager) ValidateSessionToken(userID, token string) bool {
 	user := m.GetUser(userID)
+	if user == nil {
+		user = m.restoreUser(userID, token)
+	}
 	if user == nil {
 		return false
 	}
@@ -139,11 +374,17 @@ func (m *Manager) ValidateSessionToken(userID, token string) bool {
 	return user.SessionToken != "" && user.SessionToken == token
 }
 
-// UnregisterUser removes a user from the manager.
+// UnregisterUser removes a user from the manager and the user store.
 func (m *Manager) UnregisterUser(userID string) {
 	m.usersMu.Lock()
 	defer m.usersMu.Unlock()
-	delete(m.users, userID)
+	if _, ok := m.users[userID]; ok {
+		delete(m.users, userID)
+		m.leftTable(userID)
+	}
+	if err := m.store.DeleteUser(userID); err != nil {
+		slog.Error("failed to delete user", "error", err, "user_id", userID)
+	}
 }
 
 // MarkUserDisconnected marks a user as disconnected without removing them.
@@ -179,7 +420,7 @@ func (m *Manager) GetUser(userID string) *User {
 
 // SetUserName sets a display name for the user, appending #<first 4 chars of userID>.
 func (m *Manager) SetUserName(userID, name string) {
-	name = sanitizeName(name)
+	name = displayName(userID, name)
 	if name == "" {
 		return
 	}
@@ -187,13 +428,24 @@ func (m *Manager) SetUserName(userID, name string) {
 	if user == nil {
 		return
 	}
+	user.mu.Lock()
+	user.Name = name
+	m.saveUser(user)
+	user.mu.Unlock()
+}
+
+// displayName is name as shown at the table, or "" if nothing is left of it
+// once sanitized.
+func displayName(userID, name string) string {
+	name = sanitizeName(name)
+	if name == "" {
+		return ""
+	}
 	suffix := userID
 	if len(suffix) > 4 {
 		suffix = suffix[:4]
 	}
-	user.mu.Lock()
-	user.Name = name + "#" + suffix
-	user.mu.Unlock()
+	return name + "#" + suffix
 }
 
 // GetUserName returns the display name for a user, or empty string if not found.
@@ -246,6 +498,14 @@ func (m *Manager) GetAllPlayers() []messages.Player {
 	return players
 }
 
+// PlayerCount returns how many players are at the table, including those
+// disconnected within the grace period.
+func (m *Manager) PlayerCount() int {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	return len(m.users)
+}
+
 // GetCurrentGameState returns the current game state for syncing new players.
 func (m *Manager) GetCurrentGameState() (state messages.GamePhase, winningNumber *int, countdown *int) {
 	m.sessionMu.RLock()
@@ -265,11 +525,23 @@ func (m *Manager) GetCurrentGameState() (state messages.GamePhase, winningNumber
 		if m.session.WinningNumber >= 0 {
 			winningNumber = &m.session.WinningNumber
 		}
+	case StatePaused:
+		state = messages.GamePhasePaused
 	}
 
 	return state, winningNumber, countdown
 }
 
+// Trajectory returns the simulated spin while the wheel is spinning, or nil.
+func (m *Manager) Trajectory() *messages.Trajectory {
+	m.sessionMu.RLock()
+	defer m.sessionMu.RUnlock()
+	if m.session.State != StateSpinning {
+		return nil
+	}
+	return m.session.trajectory
+}
+
 // BroadcastPlayerList sends the full player list to all clients.
 func (m *Manager) BroadcastPlayerList() {
 	players := m.GetAllPlayers()
@@ -316,6 +588,7 @@ func (m *Manager) NotifyPlayerJoined(userID string) {
 	}
 	// Broadcast to everyone EXCEPT the joining player
 	m.broadcastExcept(userID, msg)
+	m.spectate(msg)
 }
 
 // NotifyPlayerLeft broadcasts when a player disconnects.
@@ -345,12 +618,89 @@ func (m *Manager) NotifyBalanceUpdated(userID string, balance int64) {
 	m.broadcast(msg)
 }
 
-// PlaceBet validates and places a bet for a user.
-// Returns the user's new balance and an error if the bet was rejected.
-func (m *Manager) PlaceBet(userID, betType, betValue string, amount int64) (int64, error) {
-	// Validate bet (pure function, no lock needed)
-	if err := ValidateBet(betType, betValue, amount); err != nil {
-		return 0, err
+// BetRequest is a bet as a player asks for it, before validation.
+type BetRequest struct {
+	Type   string
+	Value  string
+	Amount int64
+}
+
+// PlaceBet validates and places a bet for a user. Call bets are expanded into
+// their component chips and the whole stake is debited at once, or not at all.
+// The table's limits are enforced here: a bet that would break one is
+// rejected with ErrBetBelowMinimum, ErrBetAboveMaximum, ErrPlayerStakeLimit
+// or ErrTableLiabilityLimit.
+// Returns the placed bet, the user's new balance and an error if the bet was rejected.
+func (m *Manager) PlaceBet(userID, betType, betValue string, amount int64) (Bet, int64, error) {
+	bets, newBalance, err := m.PlaceBets(userID, []BetRequest{{Type: betType, Value: betValue, Amount: amount}})
+	// A single bet needs no index; report the underlying error.
+	var betErr *BetError
+	if errors.As(err, &betErr) {
+		err = betErr.Err
+	}
+	if err != nil {
+		return Bet{}, 0, err
+	}
+	return bets[0], newBalance, nil
+}
+
+// resolveBet validates a request and builds the bet it places for userID.
+func (m *Manager) resolveBet(userID string, req BetRequest) (Bet, error) {
+	bet, err := m.betKinds.Resolve(m.wheel, req.Type, req.Value, req.Amount)
+	if err != nil {
+		return Bet{}, err
+	}
+	bet.ID = generateBetID()
+	bet.UserID = userID
+	for i := range bet.Components {
+		bet.Components[i].UserID = userID
+	}
+	if err := m.limits.checkBet(bet); err != nil {
+		return Bet{}, err
+	}
+	return bet, nil
+}
+
+// MaxBatchBets is the most bets a player can place in one batch: a straight
+// on every pocket of a triple-zero wheel, with room for call bets.
+const MaxBatchBets = 64
+
+// PlaceBets places every request or none of them: each is validated and
+// checked against the table limits with the others counted in, and the
+// summed stake is debited in one step. A rejection caused by one bet is
+// returned as a *BetError naming it; ErrInsufficientBalance, ErrTooManyBets
+// and ErrInvalidAmount, for stakes too large to add up, apply to the batch
+// as a whole.
+// Returns the placed bets, in request order, and the user's new balance.
+func (m *Manager) PlaceBets(userID string, reqs []BetRequest) ([]Bet, int64, error) {
+	if len(reqs) > MaxBatchBets {
+		return nil, 0, fmt.Errorf("%w: %d, at most %d", ErrTooManyBets, len(reqs), MaxBatchBets)
+	}
+	return m.placeBets(userID, reqs)
+}
+
+// placeBets is PlaceBets without the cap on the batch size, for rebets of
+// bets the table has already taken.
+func (m *Manager) placeBets(userID string, reqs []BetRequest) ([]Bet, int64, error) {
+	if len(reqs) == 0 {
+		return nil, 0, ErrNoBets
+	}
+
+	// Validate bets (pure functions, no lock needed)
+	bets := make([]Bet, len(reqs))
+	exposures := make([]map[int]int64, len(reqs))
+	var total int64
+	for i, req := range reqs {
+		bet, err := m.resolveBet(userID, req)
+		if err != nil {
+			return nil, 0, &BetError{Index: i, Err: err}
+		}
+		bets[i] = bet
+		exposures[i] = m.betKinds.Exposure(m.wheel, bet)
+		var ok bool
+		if total, ok = addStake(total, bet.Amount); !ok {
+			return nil, 0, fmt.Errorf("%w: the stakes add up to more than can be staked", ErrInvalidAmount)
+		}
 	}
 
 	// Hold session RLock for the entire state-check + bet-append window.
@@ -360,38 +710,102 @@ func (m *Manager) PlaceBet(userID, betType, betValue string, amount int64) (int6
 	defer m.sessionMu.RUnlock()
 
 	if m.session.State != StateBetting {
-		return 0, ErrBettingClosed
+		return nil, 0, ErrBettingClosed
 	}
 
 	// Find user
 	user := m.GetUser(userID)
 	if user == nil {
-		return 0, ErrUserNotFound
+		return nil, 0, ErrUserNotFound
+	}
+
+	events, newBalance, err := m.addBets(user, bets, exposures, total)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Recorded outside the session lock, so the write to the event log
+	// doesn't hold up other players' bets.
+	m.emit(events...)
+
+	return bets, newBalance, nil
+}
+
+// addBets checks bets against the round's limits, takes their stakes off the
+// user's balance and adds them to the round. It returns the events to record
+// and the user's new balance. The caller must hold the sessionMu read lock.
+//
+// Room under the limits is reserved under the session lock, but the stakes
+// are posted to the ledger after it is released, so other players' bets
+// don't wait on the write. If the post fails the reservation is given back.
+func (m *Manager) addBets(user *User, bets []Bet, exposures []map[int]int64, total int64) ([]messages.Event, int64, error) {
+	if err := m.reserveBets(user.ID, bets, exposures); err != nil {
+		return nil, 0, err
 	}
 
 	// Deduct balance
 	user.mu.Lock()
-	if user.Balance < amount {
-		user.mu.Unlock()
-		return 0, ErrInsufficientBalance
+	err := ErrInsufficientBalance
+	if user.Balance >= total {
+		postings := make([]posting, len(bets))
+		for i, bet := range bets {
+			postings[i] = posting{kind: messages.TransactionBet, amount: -bet.Amount, betID: bet.ID}
+		}
+		_, err = m.post(user, m.session.round, postings...)
 	}
-	user.Balance -= amount
 	newBalance := user.Balance
 	user.mu.Unlock()
 
-	// Record bet
-	bet := Bet{
-		UserID: userID,
-		Type:   messages.BetType(betType),
-		Value:  betValue,
-		Amount: amount,
+	m.session.mu.Lock()
+	defer m.session.mu.Unlock()
+	if err != nil {
+		for i, bet := range bets {
+			m.session.release(bet, exposures[i])
+		}
+		return nil, 0, err
+	}
+
+	// Record bets
+	m.session.Bets = append(m.session.Bets, bets...)
+	events := make([]messages.Event, len(bets))
+	for i, bet := range bets {
+		events[i] = messages.Event{
+			Type:   messages.EventBetPlaced,
+			Round:  m.session.round,
+			UserID: user.ID,
+			Bet:    &bets[i],
+			Amount: -bet.Amount,
+		}
 	}
+	return events, newBalance, nil
+}
 
+// reserveBets checks bets against the round's stake and liability limits
+// and reserves the room they take. The caller must hold the sessionMu read
+// lock.
+func (m *Manager) reserveBets(userID string, bets []Bet, exposures []map[int]int64) error {
+	// Hold the session lock from the limit check to the reservation so
+	// concurrent bets can't both squeeze under the same limit.
 	m.session.mu.Lock()
-	m.session.Bets = append(m.session.Bets, bet)
-	m.session.mu.Unlock()
+	defer m.session.mu.Unlock()
 
-	return newBalance, nil
+	staked := m.session.stakes[userID]
+	liability := make(map[int]int64, len(m.session.liability))
+	for p, amount := range m.session.liability {
+		liability[p] = amount
+	}
+	for i, bet := range bets {
+		if err := m.limits.checkRound(bet, exposures[i], staked, liability); err != nil {
+			return &BetError{Index: i, Err: err}
+		}
+		staked += bet.Amount
+		for p, amount := range exposures[i] {
+			liability[p] += amount
+		}
+	}
+	for i, bet := range bets {
+		m.session.reserve(bet, exposures[i])
+	}
+	return nil
 }
 
 // RunGameLoop runs the infinite game loop cycling through phases.
@@ -403,13 +817,20 @@ func (m *Manager) RunGameLoop() {
 		default:
 		}
 
+		if m.rngMonitor != nil && !m.rngMonitor.Healthy() {
+			m.runPausedPhase()
+			continue
+		}
 		m.runBettingPhase()
-		m.runSpinningPhase()
-		m.runResultPhase()
+		if m.runSpinningPhase() {
+			m.runResultPhase()
+		}
+		m.fillSeats()
 	}
 }
 
-// runCleanup periodically removes users who have been disconnected for too long.
+// runCleanup periodically evicts users who have been disconnected for too
+// long. They stay in the user store and are restored if they reconnect.
 func (m *Manager) runCleanup() {
 	m.cleanupTicker = time.NewTicker(cleanupInterval)
 	defer m.cleanupTicker.Stop()
@@ -419,26 +840,63 @@ func (m *Manager) runCleanup() {
 		case <-m.cleanupStopCh:
 			return
 		case <-m.cleanupTicker.C:
-			now := time.Now()
-			m.usersMu.Lock()
-			for userID, user := range m.users {
-				user.mu.Lock()
-				lastDisconnect := user.LastDisconnect
-				user.mu.Unlock()
-
-				if lastDisconnect != nil && now.Sub(*lastDisconnect) > disconnectGracePeriod {
-					slog.Info("cleaning up disconnected user",
-						"user_id", userID,
-						"name", user.Name,
-						"disconnected_for", now.Sub(*lastDisconnect))
-					delete(m.users, userID)
-				}
+			m.evictDisconnected(time.Now())
+
+			// Mismatches are logged by Reconcile.
+			if _, err := m.Reconcile(); err != nil {
+				slog.Error("failed to reconcile the ledger", "error", err)
 			}
-			m.usersMu.Unlock()
 		}
 	}
 }
 
+// evictDisconnected drops users disconnected for longer than the grace
+// period. As in fillSeats, it keeps those the table holds bets of, in this
+// round or En Prison, so their payouts are credited to them here rather
+// than to the store while they may be at another table.
+func (m *Manager) evictDisconnected(now time.Time) {
+	// The write lock waits out any bet being placed, as in ReleaseUser.
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+
+	idle := func(user *User) bool {
+		user.mu.Lock()
+		defer user.mu.Unlock()
+		return user.LastDisconnect != nil && now.Sub(*user.LastDisconnect) > disconnectGracePeriod
+	}
+	m.usersMu.RLock()
+	users := maps.Clone(m.users)
+	m.usersMu.RUnlock()
+	var evict []string
+	for userID, user := range users {
+		if !idle(user) {
+			continue
+		}
+		if err := m.checkNoBets(func(b Bet) bool { return b.UserID == userID }); err != nil {
+			if !errors.Is(err, ErrBetsInPlay) {
+				slog.Error("failed to check for bets in play", "error", err, "user_id", userID)
+			}
+			continue
+		}
+		evict = append(evict, userID)
+	}
+
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	var evicted []string
+	for _, userID := range evict {
+		// They may have reconnected meanwhile.
+		user, ok := m.users[userID]
+		if !ok || !idle(user) {
+			continue
+		}
+		slog.Info("evicting disconnected user", "user_id", userID, "name", user.Name)
+		delete(m.users, userID)
+		evicted = append(evicted, userID)
+	}
+	m.leftTable(evicted...)
+}
+
 // Stop shuts down the game loop.
 func (m *Manager) Stop() {
 	close(m.stopCh)
@@ -449,16 +907,28 @@ func (m *Manager) Stop() {
 
 func (m *Manager) runBettingPhase() {
 	// Reset session
+	m.round++
 	m.sessionMu.Lock()
-	m.session = &GameSession{State: StateBetting}
-	m.currentCountdown = int(BettingDuration.Seconds())
+	m.session = &GameSession{
+		State:     StateBetting,
+		seed:      newRoundSeed(m.round),
+		round:     m.round,
+		startedAt: time.Now(),
+	}
+	session := m.session
+	durations := m.phaseDurations()
+	m.currentCountdown = int(durations.Betting.Seconds())
+	// Emitted under the lock so the round opens before any bet lands in it.
+	m.emit(messages.Event{Type: messages.EventPhaseChanged, Round: session.round, Phase: messages.GamePhaseBetting})
 	m.sessionMu.Unlock()
+	m.openRound(session)
 
 	// Broadcast betting state
-	m.broadcastGameState(messages.GamePhaseBetting, 0, int(BettingDuration.Seconds()))
+	m.broadcastGameState(messages.GamePhaseBetting, 0, int(durations.Betting.Seconds()))
+	m.broadcastSpectatorCount()
 
 	// Countdown
-	remaining := int(BettingDuration.Seconds())
+	remaining := int(durations.Betting.Seconds())
 	tickC, stopTick := m.clock.NewTicker(1 * time.Second)
 	defer stopTick()
 	for remaining > 0 {
@@ -475,21 +945,41 @@ func (m *Manager) runBettingPhase() {
 	}
 }
 
-func (m *Manager) runSpinningPhase() {
-	// Transition to spinning — blocks until all PlaceBet RLocks are released
+// runSpinningPhase closes betting and decides the winning pocket. It returns
+// false if the round has no result to settle: a live round that was voided
+// or interrupted.
+func (m *Manager) runSpinningPhase() bool {
+	// Transition to spinning — blocks until all PlaceBet RLocks are released.
+	// Client seeds are fixed from here on.
 	m.sessionMu.Lock()
 	m.session.State = StateSpinning
+	m.session.seed.clientSeeds = m.collectClientSeeds()
+	seed := m.session.seed
 	m.sessionMu.Unlock()
+	m.emit(messages.Event{Type: messages.EventPhaseChanged, Phase: messages.GamePhaseSpinning})
+
+	if m.LiveDealer() {
+		return m.runLiveSpin()
+	}
 
 	// Spin the wheel
-	winningNumber, err := SpinWheel()
-	if err != nil {
-		slog.Error("SpinWheel failed", "error", err)
-		return
+	winningNumber := m.spin(seed)
+	m.emit(messages.Event{Type: messages.EventWheelSpun, WinningNumber: &winningNumber})
+	if m.rngMonitor != nil {
+		m.rngMonitor.Record(winningNumber)
+	}
+	var trajectory *messages.Trajectory
+	if m.physics != nil {
+		physics := *m.physics
+		physics.Duration = m.phaseDurations().Spinning + resultOverrun
+		rnd := mathrand.New(mathrand.NewPCG(mathrand.Uint64(), mathrand.Uint64()))
+		spin := physics.SimulateTo(m.wheel, winningNumber, rnd)
+		trajectory = &spin.Trajectory
 	}
 
 	m.sessionMu.Lock()
 	m.session.WinningNumber = winningNumber
+	m.session.trajectory = trajectory
 	m.sessionMu.Unlock()
 
 	// Broadcast spinning state
@@ -498,43 +988,84 @@ func (m *Manager) runSpinningPhase() {
 	// Wait for spinning duration
 	select {
 	case <-m.stopCh:
-		return
-	case <-m.clock.After(SpinningDuration):
+	case <-m.clock.After(m.phaseDurations().Spinning):
 	}
+	return true
+}
+
+// runLiveSpin waits for the croupier's result, voiding the round if none comes.
+func (m *Manager) runLiveSpin() bool {
+	m.broadcastGameState(messages.GamePhaseSpinning, 0, 0)
+
+	decision, ok := m.awaitDealer()
+	if !ok {
+		return false
+	}
+	if decision.void {
+		m.voidRound(decision.reason)
+		return false
+	}
+
+	m.sessionMu.Lock()
+	m.session.WinningNumber = decision.pocket
+	m.sessionMu.Unlock()
+	m.emit(messages.Event{Type: messages.EventWheelSpun, WinningNumber: &decision.pocket})
+	return true
 }
 
 func (m *Manager) runResultPhase() {
 	m.sessionMu.Lock()
+	session := m.session
 	m.session.State = StateResult
 	winningNumber := m.session.WinningNumber
+	seed := m.session.seed
 
 	m.session.mu.Lock()
 	bets := make([]Bet, len(m.session.Bets))
 	copy(bets, m.session.Bets)
 	m.session.mu.Unlock()
 	m.sessionMu.Unlock()
+	m.emit(messages.Event{Type: messages.EventPhaseChanged, Phase: messages.GamePhaseResult})
+
+	// Calculate payouts, settling last round's imprisoned bets alongside this round's
+	payouts := m.betKinds.SettleImprisoned(m.wheel, winningNumber, m.imprisoned)
+	current := m.betKinds.CalculatePayouts(m.wheel, winningNumber, bets)
+	m.imprisoned = m.betKinds.ApplyZeroRule(m.wheel, m.zeroRule, winningNumber, current)
+	payouts = append(payouts, current...)
 
-	// Calculate payouts
-	payouts := CalculatePayouts(winningNumber, bets)
+	m.rememberBets(bets)
+	m.recordRound(session, bets, payouts, &winningNumber, "")
 
 	// Group payouts by user and credit winnings
 	userPayouts := make(map[string][]Payout)
 	userTotalWon := make(map[string]int64)
+	var credits []Credit
 
 	for _, p := range payouts {
 		userPayouts[p.Bet.UserID] = append(userPayouts[p.Bet.UserID], p)
-		if p.Winnings > 0 {
-			totalReturn := p.Winnings + p.Bet.Amount
+		if totalReturn := payoutReturn(p); totalReturn > 0 {
 			userTotalWon[p.Bet.UserID] += totalReturn
 
-			user := m.GetUser(p.Bet.UserID)
-			if user != nil {
-				user.mu.Lock()
-				user.Balance += totalReturn
-				user.mu.Unlock()
+			kind := messages.TransactionWin
+			if p.Winnings == 0 {
+				kind = messages.TransactionRefund
 			}
+			credits = append(credits, Credit{
+				UserID: p.Bet.UserID,
+				BetID:  p.Bet.ID,
+				Kind:   kind,
+				Amount: totalReturn,
+				Note:   string(p.Outcome),
+			})
 		}
 	}
+	m.settleRound(session, credits, m.imprisoned)
+
+	// Only rounds spun from the seeds can be verified against them
+	var fairness *messages.FairnessReveal
+	if m.provablyFair() {
+		fairness = seed.reveal()
+	}
 
 	// Send per-user result messages to ALL connected users
 	m.usersMu.RLock()
@@ -550,9 +1081,11 @@ func (m *Manager) runResultPhase() {
 		msg, err := json.Marshal(messages.ResultMessage{
 			Type:          "result",
 			WinningNumber: winningNumber,
+			WinningPocket: PocketLabel(winningNumber),
 			Payouts:       payouts,
 			TotalWon:      userTotalWon[userID],
 			Balance:       balance,
+			Fairness:      fairness,
 		})
 		if err != nil {
 			slog.Error("failed to marshal result message", "error", err, "user_id", userID)
@@ -562,6 +1095,12 @@ func (m *Manager) runResultPhase() {
 	}
 	m.usersMu.RUnlock()
 
+	var totalWon int64
+	for _, won := range userTotalWon {
+		totalWon += won
+	}
+	m.spectateResult(winningNumber, totalWon, fairness)
+
 	// Broadcast result state to all
 	m.broadcastGameState(messages.GamePhaseResult, winningNumber, 0)
 
@@ -569,11 +1108,11 @@ func (m *Manager) runResultPhase() {
 	select {
 	case <-m.stopCh:
 		return
-	case <-m.clock.After(ResultDuration):
+	case <-m.clock.After(m.phaseDurations().Result):
 	}
 
 	// Sync all player balances in the player list after payouts
-	// (delayed until after ResultDuration so the wheel animation finishes first)
+	// (delayed until after the result phase so the wheel animation finishes first)
 	m.BroadcastPlayerList()
 
 	// Refill any players who hit zero
@@ -581,8 +1120,13 @@ func (m *Manager) runResultPhase() {
 	for userID, user := range m.users {
 		user.mu.Lock()
 		if user.Balance == 0 {
-			user.Balance = StartingBalance
+			_, err := m.post(user, session.round, posting{kind: messages.TransactionRefill, amount: StartingBalance})
 			user.mu.Unlock()
+			if err != nil {
+				// Still broke, so the next round tries again.
+				continue
+			}
+			m.emit(messages.Event{Type: messages.EventBalanceRefilled, UserID: userID, Amount: StartingBalance})
 			// Notify all clients of balance refill
 			m.NotifyBalanceUpdated(userID, StartingBalance)
 		} else {
@@ -598,11 +1142,23 @@ func (m *Manager) broadcastGameState(state messages.GamePhase, winningNumber int
 		State: state,
 	}
 	if state == messages.GamePhaseResult {
+		label := PocketLabel(winningNumber)
 		msg.WinningNumber = &winningNumber
+		msg.WinningPocket = &label
 	}
 	if countdown > 0 {
 		msg.Countdown = &countdown
 	}
+	if hash, nonce := m.Commitment(); hash != "" {
+		msg.ServerSeedHash = &hash
+		msg.Nonce = &nonce
+	}
+	if state == messages.GamePhaseSpinning {
+		msg.Trajectory = m.Trajectory()
+	}
+	if reason := m.PauseReason(); state == messages.GamePhasePaused && reason != "" {
+		msg.Reason = &reason
+	}
 
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -1,69 +1,77 @@
 package game
 
-import "strconv"
+import "roulette/internal/messages"
 
-// CalculatePayouts computes payouts for all bets given the winning number.
+// CalculatePayouts computes payouts for all bets on a standard European table given the winning number.
 // Winnings represent profit only; the caller is responsible for returning the stake.
 func CalculatePayouts(winningNumber int, bets []Bet) []Payout {
-	payouts := make([]Payout, 0, len(bets))
+	return defaultRegistry.CalculatePayouts(EuropeanWheel, winningNumber, bets)
+}
 
-	for _, bet := range bets {
-		var winnings int64
+// ApplyZeroRule adjusts losing even-money payouts when the ball lands on one of w's zeros.
+// Under La Partage half the stake is refunded, rounded down, so the house keeps
+// the odd unit of an odd stake. Under En Prison the bet is marked
+// imprisoned and returned so the caller can carry it into the next spin.
+func (r *BetRegistry) ApplyZeroRule(w *Wheel, rule messages.ZeroRule, winningNumber int, payouts []Payout) []Bet {
+	if !w.IsZero(winningNumber) || rule == messages.ZeroRuleNone {
+		return nil
+	}
 
-		switch bet.Type {
-		case "straight":
-			n, _ := strconv.Atoi(bet.Value) // already validated
-			if n == winningNumber {
-				winnings = bet.Amount * 35
-			}
+	var imprisoned []Bet
+	for i := range payouts {
+		p := &payouts[i]
+		if !r.isEvenMoney(w, p.Bet.Type) {
+			continue
+		}
+		switch rule {
+		case messages.ZeroRuleLaPartage:
+			p.Outcome = messages.PayoutOutcomeHalved
+			p.Refund = p.Bet.Amount / 2
+		case messages.ZeroRuleEnPrison:
+			p.Outcome = messages.PayoutOutcomeImprisoned
+			imprisoned = append(imprisoned, p.Bet)
+		}
+	}
+	return imprisoned
+}
 
-		case "color":
-			if winningNumber == 0 {
-				// Zero is green — color bets lose
-				winnings = 0
-			} else {
-				isRed := RedNumbers[winningNumber]
-				if (bet.Value == "red" && isRed) || (bet.Value == "black" && !isRed) {
-					winnings = bet.Amount
-				}
-			}
+// SettleImprisoned settles bets imprisoned by En Prison on the previous spin.
+// A bet whose section comes up is released with its stake refunded; anything
+// else, including another zero, forfeits it.
+func (r *BetRegistry) SettleImprisoned(w *Wheel, winningNumber int, bets []Bet) []Payout {
+	payouts := r.CalculatePayouts(w, winningNumber, bets)
+	for i := range payouts {
+		p := &payouts[i]
+		if p.Winnings > 0 {
+			p.Winnings = 0
+			p.Outcome = messages.PayoutOutcomeReleased
+			p.Refund = p.Bet.Amount
+		} else {
+			p.Outcome = messages.PayoutOutcomeForfeited
+		}
+	}
+	return payouts
+}
 
-		case "even_odd":
-			if winningNumber == 0 {
-				// Zero — even/odd bets lose
-				winnings = 0
-			} else {
-				isEven := winningNumber%2 == 0
-				if (bet.Value == "even" && isEven) || (bet.Value == "odd" && !isEven) {
-					winnings = bet.Amount
-				}
-			}
+// isEvenMoney reports whether betType pays 1:1 on w, the bets zero rules apply to.
+func (r *BetRegistry) isEvenMoney(w *Wheel, betType messages.BetType) bool {
+	kind, ok := r.kinds[betType]
+	return ok && kind.Multiplier(w) == 1
+}
 
-		case "dozens":
-			if winningNumber == 0 {
-				// Zero — dozens bets lose
-				winnings = 0
-			} else {
-				var match bool
-				switch bet.Value {
-				case "first":
-					match = winningNumber >= 1 && winningNumber <= 12
-				case "second":
-					match = winningNumber >= 13 && winningNumber <= 24
-				case "third":
-					match = winningNumber >= 25 && winningNumber <= 36
-				}
-				if match {
-					winnings = bet.Amount * 2
-				}
-			}
+// payoutReturn is the amount credited back to the player for p: stake plus
+// winnings on a win, or whatever a zero rule refunds. A call bet returns
+// whatever its components return.
+func payoutReturn(p Payout) int64 {
+	if len(p.Components) > 0 {
+		var total int64
+		for _, c := range p.Components {
+			total += payoutReturn(c)
 		}
-
-		payouts = append(payouts, Payout{
-			Bet:      bet,
-			Winnings: winnings,
-		})
+		return total
 	}
-
-	return payouts
+	if p.Winnings > 0 {
+		return p.Winnings + p.Bet.Amount
+	}
+	return p.Refund
 }
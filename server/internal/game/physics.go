@@ -0,0 +1,223 @@
+package game
+
+import (
+	"math"
+	mathrand "math/rand/v2"
+	"slices"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// Ball radii in a trajectory, as a fraction of the ball track's radius.
+const (
+	trackRadius     = 1.0
+	deflectorRadius = 0.8
+	pocketRadius    = 0.6
+)
+
+// PhysicsWheel is a simple model of a spinning wheel head and ball, used to
+// give clients a server-authoritative animation.
+//
+// The rotor turns clockwise and slows at a constant rate. The ball is
+// launched anticlockwise round the track and slows until it drops off, runs
+// down onto the nearest diamond (deflector) ahead of it, bounces a few
+// pockets and settles into a pocket, where it is carried round by the rotor.
+// Speeds are in revolutions per second and times in seconds.
+type PhysicsWheel struct {
+	RotorSpeed float64 // initial rotor speed
+	RotorDecel float64 // rotor slow-down, rev/s²
+	BallSpeed  float64 // initial ball speed on the track
+	BallDecel  float64 // ball slow-down, rev/s², on the track and while dropping
+	DropSpeed  float64 // speed at which the ball leaves the track
+	Deflectors int     // diamonds on the stator, evenly spaced
+	MaxBounce  int     // most pockets a diamond can knock the ball on
+	BounceTime float64 // from hitting a diamond to settling in a pocket
+	Jitter     float64 // each spin's speeds vary by up to this fraction, e.g. 0.05
+	FrameRate  float64 // keyframes per second
+	Duration   time.Duration
+}
+
+// resultOverrun is how far into the result phase a trajectory runs.
+const resultOverrun = 2500 * time.Millisecond
+
+// DefaultPhysicsWheel returns a model tuned so the ball settles about a
+// second before the client's landing animation ends.
+func DefaultPhysicsWheel() *PhysicsWheel {
+	return &PhysicsWheel{
+		RotorSpeed: 0.5,
+		RotorDecel: 0.04,
+		BallSpeed:  2.0,
+		BallDecel:  0.6,
+		DropSpeed:  0.8,
+		Deflectors: 8,
+		MaxBounce:  6,
+		BounceTime: 0.6,
+		Jitter:     0.05,
+		FrameRate:  20,
+		// The spinning phase plus the first 2.5s of the result phase.
+		Duration: SpinningDuration + resultOverrun,
+	}
+}
+
+// PhysicsSpin is one simulated spin: where the ball settled and how it got there.
+type PhysicsSpin struct {
+	Pocket     int
+	Trajectory messages.Trajectory
+}
+
+// spinPath is a spin's motion in degrees, clockwise positive, before the
+// rotor's starting angle is chosen.
+type spinPath struct {
+	ballStart float64 // ball angle at launch
+	ballSpeed float64 // deg/s, anticlockwise
+	ballDecel float64 // deg/s²
+	drop      float64 // time the ball leaves the track
+	hit       float64 // time the ball reaches a diamond
+	diamond   float64 // angle of that diamond
+	landed    float64 // time the ball settles
+	landing   float64 // ball angle if it settled where the bounce carried it
+	rotorSpd  float64 // deg/s, clockwise
+	rotorDec  float64 // deg/s²
+}
+
+// Simulate runs a spin on w from random starting conditions and reports
+// where the ball lands.
+func (p *PhysicsWheel) Simulate(w *Wheel, rnd *mathrand.Rand) PhysicsSpin {
+	path := p.path(rnd, len(w.Order))
+	return p.spin(w, path, rnd.Float64()*360)
+}
+
+// SimulateTo runs a spin on w that lands on pocket, which must be on w. The
+// ball's flight is random; the rotor's starting angle is then chosen so that
+// the pocket is under the ball when it settles. This keeps the outcome with
+// the table's RNG while the animation stays physically consistent.
+func (p *PhysicsWheel) SimulateTo(w *Wheel, pocket int, rnd *mathrand.Rand) PhysicsSpin {
+	path := p.path(rnd, len(w.Order))
+	i := max(slices.Index(w.Order, pocket), 0)
+	width := 360 / float64(len(w.Order))
+	rotorStart := path.landing - path.rotorAngle(path.landed) - (float64(i)+0.5)*width
+	return p.spin(w, path, rotorStart)
+}
+
+// path draws the ball's flight on a head of n pockets. It does not depend on the rotor.
+func (p *PhysicsWheel) path(rnd *mathrand.Rand, n int) spinPath {
+	jitter := func(v float64) float64 { return v * (1 + p.Jitter*(2*rnd.Float64()-1)) }
+
+	s := spinPath{
+		ballStart: rnd.Float64() * 360,
+		ballSpeed: jitter(p.BallSpeed) * 360,
+		ballDecel: p.BallDecel * 360,
+		rotorSpd:  jitter(p.RotorSpeed) * 360,
+		rotorDec:  p.RotorDecel * 360,
+	}
+	dropSpeed := math.Min(p.DropSpeed*360, s.ballSpeed)
+	s.drop = (s.ballSpeed - dropSpeed) / s.ballDecel
+
+	// The ball runs down to the first diamond a little way on, still slowing
+	// at the same rate. If the diamonds are too sparse for it to reach one,
+	// it comes to rest against the bowl and bounces from there.
+	dropAngle := s.ballAt(s.drop)
+	maxRun := dropSpeed * dropSpeed / (2 * s.ballDecel)
+	spacing := 360 / float64(max(p.Deflectors, 1))
+	s.diamond = math.Floor((dropAngle-min(90, maxRun/2))/spacing) * spacing
+	if dropAngle-s.diamond > maxRun {
+		s.diamond = dropAngle - maxRun
+	}
+	disc := dropSpeed*dropSpeed - 2*s.ballDecel*(dropAngle-s.diamond)
+	s.hit = s.drop + (dropSpeed-math.Sqrt(max(disc, 0)))/s.ballDecel
+
+	// Mostly the diamond knocks the ball on, sometimes back.
+	bounce := float64(1+rnd.IntN(max(p.MaxBounce, 1))) * 360 / float64(n)
+	if rnd.Float64() < 0.3 {
+		bounce = -bounce
+	}
+	s.landing = s.diamond - bounce
+	s.landed = s.hit + p.BounceTime
+	return s
+}
+
+// ballAt is the ball's angle while it is on the track or dropping.
+func (s spinPath) ballAt(t float64) float64 {
+	return s.ballStart - (s.ballSpeed*t - s.ballDecel*t*t/2)
+}
+
+// rotorAngle is how far the rotor has turned by t, from a start of 0.
+func (s spinPath) rotorAngle(t float64) float64 {
+	if stop := s.rotorSpd / s.rotorDec; t > stop {
+		t = stop
+	}
+	return s.rotorSpd*t - s.rotorDec*t*t/2
+}
+
+// spin settles the path into a pocket with the rotor starting at rotorStart
+// and samples the keyframes.
+func (p *PhysicsWheel) spin(w *Wheel, s spinPath, rotorStart float64) PhysicsSpin {
+	n := len(w.Order)
+	width := 360 / float64(n)
+
+	// The ball falls into whichever pocket is under it and rests in its middle.
+	rotorAtLanding := rotorStart + s.rotorAngle(s.landed)
+	rel := math.Mod(s.landing-rotorAtLanding, 360)
+	if rel < 0 {
+		rel += 360
+	}
+	i := min(int(rel/width), n-1)
+	offset := (float64(i) + 0.5) * width
+	final := rotorAtLanding + offset
+	final += math.Round((s.landing-final)/360) * 360
+
+	frameAt := func(t float64) messages.TrajectoryKeyframe {
+		rotor := rotorStart + s.rotorAngle(t)
+		var ball, radius float64
+		switch {
+		case t <= s.drop:
+			ball, radius = s.ballAt(t), trackRadius
+		case t <= s.hit:
+			u := (t - s.drop) / (s.hit - s.drop)
+			ball, radius = s.ballAt(t), trackRadius-(trackRadius-deflectorRadius)*u
+		case t <= s.landed:
+			u := (t - s.hit) / (s.landed - s.hit)
+			ease := 1 - (1-u)*(1-u)
+			ball = s.diamond + (final-s.diamond)*ease
+			radius = deflectorRadius - (deflectorRadius-pocketRadius)*u + 0.05*math.Sin(math.Pi*u)
+		default:
+			// Settled: the ball turns with the rotor.
+			ball, radius = final+rotor-rotorAtLanding, pocketRadius
+		}
+		// Round so a full trajectory stays a few kilobytes on the wire.
+		return messages.TrajectoryKeyframe{
+			T:          roundTo(t, 1000),
+			RotorAngle: roundTo(rotor, 100),
+			BallAngle:  roundTo(ball, 100),
+			BallRadius: roundTo(radius, 1000),
+		}
+	}
+
+	duration := p.Duration.Seconds()
+	count := int(math.Ceil(duration*p.FrameRate - 1e-9))
+	frames := make([]messages.TrajectoryKeyframe, 0, count+1)
+	for i := range count {
+		frames = append(frames, frameAt(float64(i)/p.FrameRate))
+	}
+	frames = append(frames, frameAt(duration))
+
+	pockets := make([]string, n)
+	for j, pk := range w.Order {
+		pockets[j] = PocketLabel(pk)
+	}
+	return PhysicsSpin{
+		Pocket: w.Order[i],
+		Trajectory: messages.Trajectory{
+			Pockets:   pockets,
+			Drop:      roundTo(s.drop, 1000),
+			Landed:    roundTo(s.landed, 1000),
+			Keyframes: frames,
+		},
+	}
+}
+
+// roundTo rounds v to the nearest 1/scale.
+func roundTo(v, scale float64) float64 {
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,440 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// Limits on what the owner of a private table can choose.
+const (
+	maxPrivateSeats    = 100
+	maxPhaseSeconds    = 300
+	maxPasswordLength  = 64
+	inviteCodeLength   = 8
+	defaultPrivateName = "Private table"
+)
+
+// inviteAlphabet leaves out letters and digits easily taken for each other.
+// Its 32 symbols let a random byte pick one without bias.
+const inviteAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Default limits on how many private tables can be open at once, in all and
+// per owner.
+const (
+	DefaultMaxPrivateTables         = 100
+	DefaultMaxPrivateTablesPerOwner = 3
+)
+
+// DefaultPrivateTableIdle is how long a private table can stay empty before
+// it is torn down.
+const DefaultPrivateTableIdle = 5 * time.Minute
+
+// TableFactory builds the manager for a new table with the given ID, set up
+// like the server's other tables. The registry starts its game loop.
+type TableFactory func(id string) (*Manager, error)
+
+// privateTable is what a private table has beyond a public one.
+type privateTable struct {
+	owner      string
+	inviteCode string
+
+	mu         sync.Mutex
+	settings   messages.TableSettings
+	kicked     map[string]bool
+	emptySince time.Time // zero while anyone is seated
+}
+
+// Private reports whether the table is private.
+func (t *Table) Private() bool {
+	return t.private != nil
+}
+
+// Owner returns the ID of the user who created the private table, or "" for
+// a public table.
+func (t *Table) Owner() string {
+	if t.private == nil {
+		return ""
+	}
+	return t.private.owner
+}
+
+// InviteCode returns the code that joins the private table, or "" for a
+// public table.
+func (t *Table) InviteCode() string {
+	if t.private == nil {
+		return ""
+	}
+	return t.private.inviteCode
+}
+
+// Settings returns the private table's settings.
+func (t *Table) Settings() messages.TableSettings {
+	if t.private == nil {
+		return messages.TableSettings{}
+	}
+	t.private.mu.Lock()
+	defer t.private.mu.Unlock()
+	return t.private.settings
+}
+
+// checkEntry returns why the user can't join the table with password, or nil
+// if they can. A public table takes anyone; the owner of a private one
+// always gets in.
+func (t *Table) checkEntry(userID, password string) error {
+	p := t.private
+	if p == nil || userID == p.owner {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.kicked[userID] {
+		return ErrKicked
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(p.settings.Password)) != 1 {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// checkOwner returns ErrNotTableOwner unless userID owns the private table.
+func (t *Table) checkOwner(userID string) error {
+	if t.private == nil || userID != t.private.owner {
+		return ErrNotTableOwner
+	}
+	return nil
+}
+
+// UpdateSettings lets the owner change a private table's settings. New
+// phase lengths apply from the next phase, and a lower seat limit turns
+// no one away who is already seated.
+func (t *Table) UpdateSettings(userID string, s messages.TableSettings) error {
+	if err := t.checkOwner(userID); err != nil {
+		return err
+	}
+	if err := validateTableSettings(s); err != nil {
+		return err
+	}
+	t.private.mu.Lock()
+	t.private.settings = s
+	t.private.mu.Unlock()
+	t.applySettings(s)
+	return nil
+}
+
+// applySettings sets the manager up for s.
+func (t *Table) applySettings(s messages.TableSettings) {
+	t.Manager.SetMaxSeats(s.MaxSeats)
+	t.Manager.SetPhaseDurations(PhaseDurations{
+		Betting:  time.Duration(s.BettingSeconds) * time.Second,
+		Spinning: time.Duration(s.SpinningSeconds) * time.Second,
+		Result:   time.Duration(s.ResultSeconds) * time.Second,
+	})
+}
+
+// Kick lets the owner send a player at their private table back to the
+// lobby. The player can't rejoin it. A player with bets in play must wait
+// for them to settle: ErrBetsInPlay.
+func (t *Table) Kick(ownerID, userID string) error {
+	if err := t.checkOwner(ownerID); err != nil {
+		return err
+	}
+	if userID == ownerID {
+		return ErrKickOwner
+	}
+	// Bar them first so they can't rejoin between the release and the bar.
+	t.private.mu.Lock()
+	wasKicked := t.private.kicked[userID]
+	t.private.kicked[userID] = true
+	t.private.mu.Unlock()
+	if err := t.Manager.ReleaseUser(userID); err != nil {
+		t.private.mu.Lock()
+		t.private.kicked[userID] = wasKicked
+		t.private.mu.Unlock()
+		return err
+	}
+	slog.Info("player kicked from private table", "table_id", t.ID, "user_id", userID)
+	return nil
+}
+
+// validateTableSettings checks a private table's settings are in range.
+func validateTableSettings(s messages.TableSettings) error {
+	switch {
+	case s.MaxSeats < 0 || s.MaxSeats > maxPrivateSeats:
+		return fmt.Errorf("%w: max seats must be 0-%d", ErrInvalidSettings, maxPrivateSeats)
+	case len(s.Password) > maxPasswordLength:
+		return fmt.Errorf("%w: password must be at most %d bytes", ErrInvalidSettings, maxPasswordLength)
+	}
+	for _, secs := range []int{s.BettingSeconds, s.SpinningSeconds, s.ResultSeconds} {
+		if secs < 0 || secs > maxPhaseSeconds {
+			return fmt.Errorf("%w: phase lengths must be 0-%d seconds", ErrInvalidSettings, maxPhaseSeconds)
+		}
+	}
+	return nil
+}
+
+// generateInviteCode creates a random code from inviteAlphabet.
+func generateInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = inviteAlphabet[int(b[i])%len(inviteAlphabet)]
+	}
+	return string(b), nil
+}
+
+// privateTableIDPrefix starts the ID of every private table.
+const privateTableIDPrefix = "p-"
+
+// generatePrivateTableID creates a random ID for a private table.
+func generatePrivateTableID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return privateTableIDPrefix + hex.EncodeToString(b), nil
+}
+
+// IsPrivateTableID reports whether id is the ID of a private table.
+func IsPrivateTableID(id string) bool {
+	return strings.HasPrefix(id, privateTableIDPrefix)
+}
+
+// SetTableFactory sets how the registry builds private tables. Without one
+// it can't create them.
+func (r *TableRegistry) SetTableFactory(f TableFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factory = f
+}
+
+// SetIdleTimeout sets how long a private table can stay empty before it is
+// torn down. Call before RunGameLoops.
+func (r *TableRegistry) SetIdleTimeout(d time.Duration) {
+	r.idleTimeout = d
+}
+
+// SetPrivateTableLimits sets how many private tables can be open at once,
+// total, and how many of them one owner can have. Call before any are
+// created.
+func (r *TableRegistry) SetPrivateTableLimits(total, perOwner int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPrivate = total
+	r.maxPrivatePerOwner = perOwner
+}
+
+// checkPrivateLimits returns ErrTooManyTables if ownerID can't open another
+// private table. The caller must hold r.mu.
+func (r *TableRegistry) checkPrivateLimits(ownerID string) error {
+	var total, owned int
+	for _, t := range r.order {
+		if t.private == nil {
+			continue
+		}
+		total++
+		if t.private.owner == ownerID {
+			owned++
+		}
+	}
+	if owned >= r.maxPrivatePerOwner {
+		return fmt.Errorf("%w: you can own at most %d", ErrTooManyTables, r.maxPrivatePerOwner)
+	}
+	if total >= r.maxPrivate {
+		return fmt.Errorf("%w: the server is at its limit of %d", ErrTooManyTables, r.maxPrivate)
+	}
+	return nil
+}
+
+// CreatePrivate opens a private table owned by ownerID and starts its game
+// loop. Others join it with its invite code. The owner isn't seated at it.
+// It returns ErrTooManyTables if the owner or the server already has as many
+// private tables open as allowed.
+func (r *TableRegistry) CreatePrivate(ownerID, name string, s messages.TableSettings) (*Table, error) {
+	if err := validateTableSettings(s); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	factory := r.factory
+	err := r.checkPrivateLimits(ownerID)
+	r.mu.RUnlock()
+	if factory == nil {
+		return nil, fmt.Errorf("%w: private tables are not enabled", ErrUnknownTable)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := generatePrivateTableID()
+	if err != nil {
+		return nil, err
+	}
+	m, err := factory(id)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = defaultPrivateName
+	}
+	t := &Table{ID: id, Name: name, Manager: m, private: &privateTable{
+		owner:      ownerID,
+		settings:   s,
+		kicked:     make(map[string]bool),
+		emptySince: time.Now(),
+	}}
+	t.applySettings(s)
+
+	r.mu.Lock()
+	// Another table may have opened while this one was built.
+	if err := r.checkPrivateLimits(ownerID); err != nil {
+		r.mu.Unlock()
+		r.discard(id, m)
+		return nil, err
+	}
+	for {
+		if t.private.inviteCode, err = generateInviteCode(); err != nil {
+			r.mu.Unlock()
+			r.discard(id, m)
+			return nil, err
+		}
+		if _, taken := r.byCode[t.private.inviteCode]; !taken {
+			break
+		}
+	}
+	if err := r.add(t); err != nil {
+		r.mu.Unlock()
+		r.discard(id, m)
+		return nil, err
+	}
+	r.byCode[t.private.inviteCode] = t
+	r.mu.Unlock()
+
+	go m.RunGameLoop()
+	slog.Info("private table created", "table_id", id, "owner_id", ownerID)
+	return t, nil
+}
+
+// Enter returns the table a user asked to join: the private table with
+// inviteCode if one is given, or else the table with id, or the default if
+// id is empty. It returns why they can't join instead if the table is
+// private and they lack the code or password, or were kicked from it.
+func (r *TableRegistry) Enter(id, inviteCode, password, userID string) (*Table, error) {
+	var t *Table
+	if inviteCode != "" {
+		r.mu.RLock()
+		t = r.byCode[inviteCode]
+		r.mu.RUnlock()
+		if t == nil {
+			return nil, fmt.Errorf("%w: no table with that invite code", ErrUnknownTable)
+		}
+	} else {
+		var err error
+		if t, err = r.Lookup(id); err != nil {
+			return nil, err
+		}
+		if t.Private() && userID != t.Owner() {
+			return nil, ErrInviteRequired
+		}
+	}
+	if err := t.checkEntry(userID, password); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close lets the owner close their private table once no bets are in play.
+// It returns the IDs of the users who were at it, now back in the lobby.
+func (r *TableRegistry) Close(t *Table, userID string) ([]string, error) {
+	if err := t.checkOwner(userID); err != nil {
+		return nil, err
+	}
+	ids, err := t.Manager.Close(true)
+	if err != nil {
+		return nil, err
+	}
+	r.remove(t, "table closed by its owner")
+	slog.Info("private table closed by its owner", "table_id", t.ID, "players", len(ids))
+	return ids, nil
+}
+
+// remove takes a closed table out of the registry, stops its game loop and
+// passes it to the closed hook and the remove hook with reason.
+func (r *TableRegistry) remove(t *Table, reason string) {
+	r.mu.Lock()
+	delete(r.tables, t.ID)
+	if t.private != nil {
+		delete(r.byCode, t.private.inviteCode)
+	}
+	for i, o := range r.order {
+		if o == t {
+			r.order = append(r.order[:i:i], r.order[i+1:]...)
+			break
+		}
+	}
+	r.mu.Unlock()
+	r.discard(t.ID, t.Manager)
+	if r.onRemove != nil {
+		r.onRemove(t, reason)
+	}
+}
+
+// discard stops m, the manager of private table id, and passes id to the
+// closed hook.
+func (r *TableRegistry) discard(id string, m *Manager) {
+	m.Stop()
+	if r.onClosed != nil {
+		r.onClosed(id)
+	}
+}
+
+// runReaper periodically tears down private tables that have stayed empty
+// for the idle timeout.
+func (r *TableRegistry) runReaper() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopCh:
+			return
+		case now := <-ticker.C:
+			r.reap(now)
+		}
+	}
+}
+
+// reap tears down the private tables that have been empty since before
+// now less the idle timeout.
+func (r *TableRegistry) reap(now time.Time) {
+	for _, t := range r.Tables() {
+		p := t.private
+		if p == nil {
+			continue
+		}
+		p.mu.Lock()
+		if t.Manager.PlayerCount() > 0 {
+			p.emptySince = time.Time{}
+		} else if p.emptySince.IsZero() {
+			p.emptySince = now
+		}
+		idle := !p.emptySince.IsZero() && now.Sub(p.emptySince) >= r.idleTimeout
+		p.mu.Unlock()
+		if !idle {
+			continue
+		}
+		// Close refuses if a player sat down since the count.
+		if _, err := t.Manager.Close(false); err != nil {
+			continue
+		}
+		r.remove(t, "table closed after standing empty")
+		slog.Info("private table torn down after standing empty", "table_id", t.ID)
+	}
+}
@@ -0,0 +1,49 @@
+package game
+
+// Rebet places the bets the user made in their last settled round again,
+// at double the stake if double is set. The bets are placed as one batch:
+// either all of them are accepted or none are.
+// Returns the placed bets and the user's new balance.
+func (m *Manager) Rebet(userID string, double bool) ([]Bet, int64, error) {
+	user := m.GetUser(userID)
+	if user == nil {
+		return nil, 0, ErrUserNotFound
+	}
+	user.mu.Lock()
+	last := user.lastBets
+	user.mu.Unlock()
+	if len(last) == 0 {
+		return nil, 0, ErrNoPreviousBets
+	}
+
+	reqs := make([]BetRequest, len(last))
+	for i, bet := range last {
+		amount := bet.Amount
+		if bet.ChipAmount > 0 {
+			amount = bet.ChipAmount
+		}
+		if double {
+			amount *= 2
+		}
+		reqs[i] = BetRequest{Type: string(bet.Type), Value: bet.Value, Amount: amount}
+	}
+	return m.placeBets(userID, reqs)
+}
+
+// rememberBets records each user's bets from the round being settled so they
+// can rebet them. Users who sat the round out keep their earlier bets.
+func (m *Manager) rememberBets(bets []Bet) {
+	byUser := make(map[string][]Bet)
+	for _, bet := range bets {
+		byUser[bet.UserID] = append(byUser[bet.UserID], bet)
+	}
+	for userID, userBets := range byUser {
+		user := m.GetUser(userID)
+		if user == nil {
+			continue
+		}
+		user.mu.Lock()
+		user.lastBets = userBets
+		user.mu.Unlock()
+	}
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"roulette/internal/messages"
+)
+
+// BetRegistry is the set of bet kinds a table accepts. Validation and payout
+// both look bets up here, so a kind only needs to be defined once.
+//
+// A registry is not safe for concurrent modification; register every kind
+// before handing it to a Manager.
+type BetRegistry struct {
+	kinds     map[messages.BetType]BetKind
+	callKinds map[messages.BetType]CallBetKind
+}
+
+// NewBetRegistry creates a registry holding the given kinds.
+func NewBetRegistry(kinds ...BetKind) *BetRegistry {
+	r := &BetRegistry{
+		kinds:     make(map[messages.BetType]BetKind, len(kinds)),
+		callKinds: make(map[messages.BetType]CallBetKind),
+	}
+	for _, k := range kinds {
+		r.Register(k)
+	}
+	return r
+}
+
+// DefaultBetRegistry creates a registry holding StandardBetKinds and StandardCallBetKinds.
+func DefaultBetRegistry() *BetRegistry {
+	r := NewBetRegistry(StandardBetKinds()...)
+	for _, k := range StandardCallBetKinds() {
+		r.RegisterCall(k)
+	}
+	return r
+}
+
+// defaultRegistry backs the package-level ValidateBet and CalculatePayouts.
+var defaultRegistry = DefaultBetRegistry()
+
+// Register adds a kind, replacing any existing kind with the same type.
+func (r *BetRegistry) Register(k BetKind) {
+	delete(r.callKinds, k.Type())
+	r.kinds[k.Type()] = k
+}
+
+// RegisterCall adds a call bet kind, replacing any existing kind with the same type.
+// Its components are validated against the registry's other kinds when placed.
+func (r *BetRegistry) RegisterCall(k CallBetKind) {
+	delete(r.kinds, k.Type())
+	r.callKinds[k.Type()] = k
+}
+
+// Kind returns the kind registered for betType.
+func (r *BetRegistry) Kind(betType messages.BetType) (BetKind, bool) {
+	k, ok := r.kinds[betType]
+	return k, ok
+}
+
+// CallKind returns the call bet kind registered for betType.
+func (r *BetRegistry) CallKind(betType messages.BetType) (CallBetKind, bool) {
+	k, ok := r.callKinds[betType]
+	return k, ok
+}
+
+// Types returns the registered bet types, call bets included, in sorted order.
+func (r *BetRegistry) Types() []messages.BetType {
+	types := make([]messages.BetType, 0, len(r.kinds)+len(r.callKinds))
+	for t := range r.kinds {
+		types = append(types, t)
+	}
+	for t := range r.callKinds {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Subset returns a new registry holding only the listed types.
+// Types that are not registered in r are ignored.
+func (r *BetRegistry) Subset(types ...messages.BetType) *BetRegistry {
+	sub := NewBetRegistry()
+	for _, t := range types {
+		if k, ok := r.kinds[t]; ok {
+			sub.Register(k)
+		}
+		if k, ok := r.callKinds[t]; ok {
+			sub.RegisterCall(k)
+		}
+	}
+	return sub
+}
+
+// Resolve validates a bet on w and builds it. Call bets are expanded into
+// Components, each staked amount per chip, and the returned Bet's Amount is
+// the total stake with amount kept as ChipAmount. The caller fills in UserID.
+func (r *BetRegistry) Resolve(w *Wheel, betType, betValue string, amount int64) (Bet, error) {
+	if amount <= 0 {
+		return Bet{}, ErrBetAmountZero
+	}
+
+	bet := Bet{Type: messages.BetType(betType), Value: betValue, Amount: amount}
+
+	if kind, ok := r.kinds[bet.Type]; ok {
+		if err := kind.Validate(w, betValue); err != nil {
+			return bet, err
+		}
+		if _, ok := mulStake(amount, kind.Multiplier(w)+1); !ok {
+			return Bet{}, ErrBetAmountTooLarge
+		}
+		return bet, nil
+	}
+
+	call, ok := r.callKinds[bet.Type]
+	if !ok {
+		return Bet{}, fmt.Errorf("%w: %s", ErrUnknownBetType, betType)
+	}
+	components, err := call.Expand(w, betValue)
+	if err != nil {
+		return Bet{}, err
+	}
+	bet.Amount = 0
+	bet.ChipAmount = amount
+	var maxMultiplier int64
+	for i := range components {
+		c := &components[i]
+		kind, ok := r.kinds[c.Type]
+		if !ok {
+			return Bet{}, fmt.Errorf("%w: %s needs %s bets, which this table does not offer", ErrUnknownBetType, betType, c.Type)
+		}
+		if err := kind.Validate(w, c.Value); err != nil {
+			return Bet{}, err
+		}
+		if c.Amount, ok = mulStake(c.Amount, amount); !ok {
+			return Bet{}, ErrBetAmountTooLarge
+		}
+		if bet.Amount, ok = addStake(bet.Amount, c.Amount); !ok {
+			return Bet{}, ErrBetAmountTooLarge
+		}
+		maxMultiplier = max(maxMultiplier, kind.Multiplier(w))
+	}
+	// No pocket can return more than the whole stake at the best odds
+	// among the components, so this bounds exposure and payouts too.
+	if _, ok := mulStake(bet.Amount, maxMultiplier+1); !ok {
+		return Bet{}, ErrBetAmountTooLarge
+	}
+	bet.Components = components
+	return bet, nil
+}
+
+// mulStake returns a*b for positive a and b, and false if the product
+// overflows or isn't positive.
+func mulStake(a, b int64) (int64, bool) {
+	if a <= 0 || b <= 0 || a > math.MaxInt64/b {
+		return 0, false
+	}
+	return a * b, true
+}
+
+// addStake returns a+b for non-negative a and positive b, and false if the
+// sum overflows or b isn't positive.
+func addStake(a, b int64) (int64, bool) {
+	if a < 0 || b <= 0 || a > math.MaxInt64-b {
+		return 0, false
+	}
+	return a + b, true
+}
+
+// ValidateBet checks whether the given bet parameters are valid for this registry on w.
+func (r *BetRegistry) ValidateBet(w *Wheel, betType, betValue string, amount int64) error {
+	_, err := r.Resolve(w, betType, betValue, amount)
+	return err
+}
+
+// CalculatePayouts computes payouts for all bets given the winning pocket on w.
+// Winnings represent profit only; the caller is responsible for returning the stake.
+// Bets whose type is not registered win nothing.
+//
+// A bet with Components gets one payout per component, grouped under a parent
+// payout whose Winnings are the call bet's overall profit.
+func (r *BetRegistry) CalculatePayouts(w *Wheel, winningNumber int, bets []Bet) []Payout {
+	payouts := make([]Payout, 0, len(bets))
+
+	for _, bet := range bets {
+		if len(bet.Components) > 0 {
+			payouts = append(payouts, r.callPayout(w, winningNumber, bet))
+			continue
+		}
+
+		var winnings int64
+		if kind, ok := r.kinds[bet.Type]; ok && slices.Contains(kind.Covered(w, bet.Value), winningNumber) {
+			winnings = bet.Amount * kind.Multiplier(w)
+		}
+
+		payouts = append(payouts, Payout{
+			Bet:      bet,
+			Winnings: winnings,
+		})
+	}
+
+	return payouts
+}
+
+// callPayout settles every component of a call bet and groups them under one payout.
+func (r *BetRegistry) callPayout(w *Wheel, winningNumber int, bet Bet) Payout {
+	components := r.CalculatePayouts(w, winningNumber, bet.Components)
+
+	var returned int64
+	for _, c := range components {
+		returned += payoutReturn(c)
+	}
+
+	parent := Payout{Bet: bet, Components: components}
+	// The components already carry their bets; don't send them twice.
+	parent.Bet.Components = nil
+	if returned > bet.Amount {
+		parent.Winnings = returned - bet.Amount
+	}
+	return parent
+}
@@ -0,0 +1,422 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+
+	"roulette/internal/messages"
+)
+
+// The RNG monitor watches a table's outcomes for signs of bias. Every
+// Interval spins it runs three tests over the most recent spins in each
+// window:
+//
+//   - chi-square: each pocket comes up about 1/size of the time
+//   - runs: spins in the low and high halves of the wheel neither cluster
+//     nor alternate more than chance allows
+//   - gap: the spins between two appearances of a pocket are geometric
+//
+// An unbiased wheel fails a test with probability Alpha, so one test must
+// fail Tolerance times in a row before the monitor trips. The game loop then
+// pauses the table after the current round until an admin calls ResumeRNG.
+
+// RNGMonitorConfig tunes an RNGMonitor.
+type RNGMonitorConfig struct {
+	// Windows are the numbers of recent spins the tests are run over. Use at
+	// least ten spins per pocket so the tests' approximations hold.
+	Windows []int
+	// Interval is the number of spins between test runs.
+	Interval int
+	// Alpha is the p-value below which a test fails.
+	Alpha float64
+	// Tolerance is how many consecutive failures of one test trip the monitor.
+	Tolerance int
+}
+
+// DefaultRNGMonitorConfig tests the last 370 and 3700 spins every 37 spins.
+func DefaultRNGMonitorConfig() RNGMonitorConfig {
+	return RNGMonitorConfig{
+		Windows:   []int{370, 3700},
+		Interval:  37,
+		Alpha:     0.0001,
+		Tolerance: 3,
+	}
+}
+
+// rngKey identifies one test over one window.
+type rngKey struct {
+	test   messages.RNGTest
+	window int
+}
+
+// RNGMonitor records a wheel's outcomes and tests them for bias. It is safe
+// for concurrent use.
+type RNGMonitor struct {
+	mu       sync.Mutex
+	wheel    *Wheel
+	cfg      RNGMonitorConfig
+	keep     int   // the longest window
+	history  []int // wheel indices of recent spins, oldest first
+	spins    int64
+	results  []messages.RNGTestResult
+	failures map[rngKey]int
+	reason   string // why the monitor tripped; empty while healthy
+}
+
+// NewRNGMonitor creates a monitor for spins on w.
+func NewRNGMonitor(w *Wheel, cfg RNGMonitorConfig) *RNGMonitor {
+	cfg.Windows = slices.Sorted(slices.Values(cfg.Windows))
+	cfg.Windows = slices.DeleteFunc(cfg.Windows, func(n int) bool { return n < 2 })
+	cfg.Windows = slices.Compact(cfg.Windows)
+	cfg.Interval = max(cfg.Interval, 1)
+	cfg.Tolerance = max(cfg.Tolerance, 1)
+	keep := 0
+	if len(cfg.Windows) > 0 {
+		keep = cfg.Windows[len(cfg.Windows)-1]
+	}
+	return &RNGMonitor{
+		wheel:    w,
+		cfg:      cfg,
+		keep:     keep,
+		failures: make(map[rngKey]int),
+	}
+}
+
+// Record adds a spin's outcome and runs the tests when they are due.
+func (r *RNGMonitor) Record(pocket int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history = append(r.history, r.wheel.indexOf(pocket))
+	if len(r.history) > 2*r.keep {
+		r.history = slices.Clone(r.history[len(r.history)-r.keep:])
+	}
+	r.spins++
+	if r.spins%int64(r.cfg.Interval) == 0 {
+		r.evaluate()
+	}
+}
+
+// evaluate runs the battery over every window that has filled up. The
+// caller must hold mu.
+func (r *RNGMonitor) evaluate() {
+	var results []messages.RNGTestResult
+	for _, window := range r.cfg.Windows {
+		if len(r.history) < window {
+			continue
+		}
+		for _, res := range battery(r.wheel.Size(), r.history[len(r.history)-window:], r.cfg.Alpha) {
+			key := rngKey{test: res.Test, window: window}
+			if res.Passed {
+				delete(r.failures, key)
+			} else {
+				r.failures[key]++
+			}
+			res.Failures = r.failures[key]
+			results = append(results, res)
+
+			if res.Failures >= r.cfg.Tolerance && r.reason == "" {
+				r.reason = fmt.Sprintf("%s test over the last %d spins failed with p=%.2g", res.Test, window, res.PValue)
+				slog.Error("RNG monitor tripped", "test", res.Test, "window", window, "failures", res.Failures,
+					"statistic", res.Statistic, "p_value", res.PValue, "spins", r.spins)
+			}
+		}
+	}
+	r.results = results
+}
+
+// Healthy reports whether the monitor has not tripped.
+func (r *RNGMonitor) Healthy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reason == ""
+}
+
+// Reason says why the monitor tripped, or "" if it has not.
+func (r *RNGMonitor) Reason() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reason
+}
+
+// Report returns the latest result of each test over each window.
+func (r *RNGMonitor) Report() messages.RNGReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := slices.Clone(r.results)
+	if results == nil {
+		results = []messages.RNGTestResult{}
+	}
+	return messages.RNGReport{
+		Spins:     r.spins,
+		Alpha:     r.cfg.Alpha,
+		Tolerance: r.cfg.Tolerance,
+		Paused:    r.reason != "",
+		Reason:    r.reason,
+		Results:   results,
+	}
+}
+
+// Reset clears the monitor's history and failures, as if it had just been
+// created. Spins keeps counting.
+func (r *RNGMonitor) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.history = nil
+	r.results = nil
+	clear(r.failures)
+	r.reason = ""
+}
+
+// Battery runs the monitor's tests once over a sequence of pockets on w.
+func Battery(w *Wheel, pockets []int, alpha float64) []messages.RNGTestResult {
+	sample := make([]int, len(pockets))
+	for i, p := range pockets {
+		sample[i] = w.indexOf(p)
+	}
+	return battery(w.Size(), sample, alpha)
+}
+
+// battery runs every test over sample, a sequence of wheel indices in [0, size).
+func battery(size int, sample []int, alpha float64) []messages.RNGTestResult {
+	results := make([]messages.RNGTestResult, 0, 3)
+	add := func(test messages.RNGTest, stat, p float64) {
+		results = append(results, messages.RNGTestResult{
+			Test:      test,
+			Window:    len(sample),
+			Statistic: stat,
+			PValue:    p,
+			Passed:    p >= alpha,
+		})
+	}
+	stat, p := chiSquareTest(size, sample)
+	add(messages.RNGTestChiSquare, stat, p)
+	stat, p = runsTest(size, sample)
+	add(messages.RNGTestRuns, stat, p)
+	stat, p = gapTest(size, sample)
+	add(messages.RNGTestGap, stat, p)
+	return results
+}
+
+// chiSquareTest compares how often each index came up with the expected
+// len(sample)/size.
+func chiSquareTest(size int, sample []int) (float64, float64) {
+	if len(sample) == 0 {
+		return 0, 1
+	}
+	counts := make([]int, size)
+	for _, v := range sample {
+		counts[v]++
+	}
+	expected := float64(len(sample)) / float64(size)
+	var stat float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		stat += d * d / expected
+	}
+	return stat, chiSquareSurvival(stat, size-1)
+}
+
+// runsTest is the Wald-Wolfowitz runs test on whether each spin fell in the
+// low or high half of the indices. It returns the z-score of the number of
+// runs and a two-sided p-value: too few runs means outcomes cluster, too
+// many that they alternate.
+func runsTest(size int, sample []int) (float64, float64) {
+	var low, runs int
+	for i, v := range sample {
+		if v < size/2 {
+			low++
+		}
+		if i == 0 || (v < size/2) != (sample[i-1] < size/2) {
+			runs++
+		}
+	}
+	n1, n2 := float64(low), float64(len(sample)-low)
+	n := n1 + n2
+	if n1 == 0 || n2 == 0 {
+		// Undefined; a one-sided sample fails the chi-square test instead.
+		return 0, 1
+	}
+	mean := 2*n1*n2/n + 1
+	variance := 2 * n1 * n2 * (2*n1*n2 - n) / (n * n * (n - 1))
+	if variance <= 0 {
+		return 0, 1
+	}
+	z := (float64(runs) - mean) / math.Sqrt(variance)
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// gapBands is the number of bands gap lengths are grouped into.
+const gapBands = 9
+
+// gapTest measures the gap, in spins, between consecutive appearances of
+// each index. For an unbiased wheel a gap of r spins has probability
+// p(1-p)^r with p = 1/size. Gaps are grouped into bands size/2 spins wide,
+// the last band taking all the longer gaps, and the counts compared with
+// their expected values by a chi-square test.
+func gapTest(size int, sample []int) (float64, float64) {
+	width := max(size/2, 1)
+	observed := make([]float64, gapBands)
+	last := make([]int, size)
+	for i := range last {
+		last[i] = -1
+	}
+	for i, v := range sample {
+		if last[v] >= 0 {
+			observed[min((i-last[v]-1)/width, gapBands-1)]++
+		}
+		last[v] = i
+	}
+
+	// A gap of r spins can start at n-r-1 places in the sample, with
+	// probability p²(1-p)^r for each of the size indices.
+	n := len(sample)
+	p := 1 / float64(size)
+	expected := make([]float64, gapBands)
+	qr := 1.0
+	for r := 0; r < n-1; r++ {
+		e := float64(n-r-1) * p * qr
+		if e < 1e-12 {
+			break
+		}
+		expected[min(r/width, gapBands-1)] += e
+		qr *= 1 - p
+	}
+
+	var stat float64
+	df := -1
+	for i, e := range expected {
+		if e > 0 {
+			d := observed[i] - e
+			stat += d * d / e
+			df++
+		}
+	}
+	if df < 1 {
+		return 0, 1
+	}
+	return stat, chiSquareSurvival(stat, df)
+}
+
+// chiSquareSurvival is P(X >= stat) for X chi-square distributed with df
+// degrees of freedom.
+func chiSquareSurvival(stat float64, df int) float64 {
+	return gammaQ(float64(df)/2, stat/2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x), from
+// its series for small x and its continued fraction otherwise.
+func gammaQ(a, x float64) float64 {
+	const (
+		eps   = 1e-15
+		tiny  = 1e-300
+		steps = 1000
+	)
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lg)
+
+	if x < a+1 {
+		sum := 1 / a
+		term := sum
+		for n := 1; n < steps; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return max(0, 1-sum*prefix)
+	}
+
+	// Modified Lentz's method.
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < steps; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return prefix * h
+}
+
+// SetRNGMonitor records every spin the server makes with mon and pauses the
+// table if it trips. Live dealer results are not recorded. Call before
+// RunGameLoop.
+func (m *Manager) SetRNGMonitor(mon *RNGMonitor) {
+	m.rngMonitor = mon
+}
+
+// RNGReport returns the monitor's latest results. It is false if the table
+// has no monitor.
+func (m *Manager) RNGReport() (messages.RNGReport, bool) {
+	if m.rngMonitor == nil {
+		return messages.RNGReport{}, false
+	}
+	return m.rngMonitor.Report(), true
+}
+
+// PauseReason says why the RNG monitor paused the table, or "" if it has not.
+func (m *Manager) PauseReason() string {
+	if m.rngMonitor == nil {
+		return ""
+	}
+	return m.rngMonitor.Reason()
+}
+
+// ResumeRNG resets a tripped monitor so the table plays again. Call it once
+// the wheel or RNG has been checked; the monitor starts over with no history.
+func (m *Manager) ResumeRNG() error {
+	if m.rngMonitor == nil {
+		return ErrNoRNGMonitor
+	}
+	if m.rngMonitor.Healthy() {
+		return ErrNotPaused
+	}
+	m.rngMonitor.Reset()
+	slog.Info("RNG monitor reset, resuming the table")
+	select {
+	case m.resumeCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// runPausedPhase holds the table between rounds while the monitor is tripped.
+func (m *Manager) runPausedPhase() {
+	m.sessionMu.Lock()
+	m.session = &GameSession{State: StatePaused}
+	m.currentCountdown = 0
+	m.sessionMu.Unlock()
+
+	slog.Warn("table paused by the RNG monitor", "reason", m.PauseReason())
+	m.emit(messages.Event{Type: messages.EventPhaseChanged, Phase: messages.GamePhasePaused})
+	m.broadcastGameState(messages.GamePhasePaused, 0, 0)
+
+	for !m.rngMonitor.Healthy() {
+		select {
+		case <-m.stopCh:
+			return
+		case <-m.resumeCh:
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package game
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -18,6 +19,7 @@ const (
 	StateBetting GameState = iota
 	StateSpinning
 	StateResult
+	StatePaused
 )
 
 func (s GameState) String() string {
@@ -28,6 +30,8 @@ func (s GameState) String() string {
 		return "SPINNING"
 	case StateResult:
 		return "RESULT"
+	case StatePaused:
+		return "PAUSED"
 	default:
 		return "UNKNOWN"
 	}
@@ -41,6 +45,22 @@ const (
 	StartingBalance  = 10000           // $100.00 in cents
 )
 
+// PhaseDurations is how long each phase of a round lasts at a table.
+type PhaseDurations struct {
+	Betting  time.Duration
+	Spinning time.Duration
+	Result   time.Duration
+}
+
+// DefaultPhaseDurations returns the standard phase lengths.
+func DefaultPhaseDurations() PhaseDurations {
+	return PhaseDurations{
+		Betting:  BettingDuration,
+		Spinning: SpinningDuration,
+		Result:   ResultDuration,
+	}
+}
+
 // RedNumbers maps roulette numbers that are red
 var RedNumbers = map[int]bool{
 	1: true, 3: true, 5: true, 7: true, 9: true,
@@ -56,13 +76,52 @@ type User struct {
 	Balance        int64      `json:"balance"`
 	SessionToken   string     `json:"-"`                         // never sent directly; included in WelcomeMessage only
 	LastDisconnect *time.Time `json:"last_disconnect,omitempty"` // nil when connected, set when disconnected
+	lastBets       []Bet      // bets from the last settled round the user played, for rebet
+	clientSeed     string     // added to the provably fair spin; empty if the user set none
 	mu             sync.Mutex
 }
 
 // GameSession represents a single round of roulette
 type GameSession struct {
-	State         GameState `json:"state"`
-	Bets          []Bet     `json:"bets"`
-	WinningNumber int       `json:"winning_number"`
+	State         GameState            `json:"state"`
+	Bets          []Bet                `json:"bets"`
+	WinningNumber int                  `json:"winning_number"`
+	stakes        map[string]int64     // total staked this round, by user
+	liability     map[int]int64        // total return owed if each pocket comes up
+	seed          roundSeed            // provably fair commitment, set when betting opens
+	trajectory    *messages.Trajectory // simulated spin, if the table has a physics wheel
+	round         int64                // the round's ID in the history; fixed when created
+	startedAt     time.Time            // when betting opened; fixed when created
 	mu            sync.Mutex
 }
+
+// removeBet takes the bet at index i out of the round along with its exposure.
+// The caller must hold s.mu.
+func (s *GameSession) removeBet(i int, exposure map[int]int64) Bet {
+	bet := s.Bets[i]
+	s.Bets = slices.Delete(s.Bets, i, i+1)
+	s.release(bet, exposure)
+	return bet
+}
+
+// reserve counts bet's stake and exposure against the round's limits ahead
+// of adding it, so bets placed meanwhile can't take the same room. The
+// caller must hold s.mu.
+func (s *GameSession) reserve(bet Bet, exposure map[int]int64) {
+	if s.stakes == nil {
+		s.stakes = make(map[string]int64)
+		s.liability = make(map[int]int64)
+	}
+	s.stakes[bet.UserID] += bet.Amount
+	for p, amount := range exposure {
+		s.liability[p] += amount
+	}
+}
+
+// release gives back what reserve counted for bet. The caller must hold s.mu.
+func (s *GameSession) release(bet Bet, exposure map[int]int64) {
+	s.stakes[bet.UserID] -= bet.Amount
+	for p, amount := range exposure {
+		s.liability[p] -= amount
+	}
+}
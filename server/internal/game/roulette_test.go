@@ -1,9 +1,24 @@
 package game
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"maps"
+	"math"
+	mathrand "math/rand/v2"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"roulette/internal/messages"
 )
 
 // --- SpinWheel tests ---
@@ -20,6 +35,48 @@ func TestSpinWheelRange(t *testing.T) {
 	}
 }
 
+func TestWheelSpinRange(t *testing.T) {
+	for _, w := range []*Wheel{EuropeanWheel, AmericanWheel, TripleZeroWheel} {
+		seen := make(map[int]bool)
+		for i := 0; i < 20000; i++ {
+			n, err := w.Spin()
+			if err != nil {
+				t.Fatalf("%s: Spin returned error: %v", w.Variant, err)
+			}
+			if !w.Has(n) {
+				t.Fatalf("%s: Spin returned %d, not a pocket on this wheel", w.Variant, n)
+			}
+			seen[n] = true
+		}
+		if len(seen) != w.Size() {
+			t.Errorf("%s: expected all %d pockets to come up, saw %d", w.Variant, w.Size(), len(seen))
+		}
+	}
+}
+
+func TestWheel_ParsePocket(t *testing.T) {
+	if _, ok := EuropeanWheel.ParsePocket("00"); ok {
+		t.Error("expected 00 to be rejected on a European wheel")
+	}
+	if n, ok := AmericanWheel.ParsePocket("00"); !ok || n != DoubleZero {
+		t.Errorf("expected 00 to parse as %d on an American wheel, got %d (ok=%v)", DoubleZero, n, ok)
+	}
+	if _, ok := AmericanWheel.ParsePocket("000"); ok {
+		t.Error("expected 000 to be rejected on an American wheel")
+	}
+	if n, ok := TripleZeroWheel.ParsePocket("000"); !ok || n != TripleZero {
+		t.Errorf("expected 000 to parse as %d on a triple-zero wheel, got %d (ok=%v)", TripleZero, n, ok)
+	}
+	for _, label := range []string{"05", "+5", "37", ""} {
+		if _, ok := AmericanWheel.ParsePocket(label); ok {
+			t.Errorf("expected %q to be rejected", label)
+		}
+	}
+	if got := PocketLabel(DoubleZero); got != "00" {
+		t.Errorf("expected label 00, got %s", got)
+	}
+}
+
 // --- CalculatePayouts tests ---
 
 func TestCalculatePayouts_StraightWin(t *testing.T) {
@@ -115,6 +172,486 @@ func TestCalculatePayouts_DozensLossOnZero(t *testing.T) {
 	}
 }
 
+func TestCalculatePayouts_ColumnsWin(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "columns", Value: "second", Amount: 500}}
+	payouts := CalculatePayouts(35, bets) // 35 is in the second column
+	if payouts[0].Winnings != 1000 {
+		t.Errorf("expected winnings 1000, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_ColumnsLoss(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "columns", Value: "third", Amount: 500}}
+	payouts := CalculatePayouts(34, bets) // 34 is in the first column
+	if payouts[0].Winnings != 0 {
+		t.Errorf("expected winnings 0, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_ColumnsLossOnZero(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "columns", Value: "third", Amount: 500}}
+	payouts := CalculatePayouts(0, bets)
+	if payouts[0].Winnings != 0 {
+		t.Errorf("expected winnings 0 on zero, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_HighLowWin(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "high_low", Value: "high", Amount: 300}}
+	payouts := CalculatePayouts(19, bets)
+	if payouts[0].Winnings != 300 {
+		t.Errorf("expected winnings 300, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_HighLowLoss(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "high_low", Value: "high", Amount: 300}}
+	payouts := CalculatePayouts(18, bets)
+	if payouts[0].Winnings != 0 {
+		t.Errorf("expected winnings 0, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_HighLowLossOnZero(t *testing.T) {
+	bets := []Bet{{UserID: "u1", Type: "high_low", Value: "low", Amount: 300}}
+	payouts := CalculatePayouts(0, bets)
+	if payouts[0].Winnings != 0 {
+		t.Errorf("expected winnings 0 on zero, got %d", payouts[0].Winnings)
+	}
+}
+
+func TestCalculatePayouts_InsideBets(t *testing.T) {
+	tests := []struct {
+		betType  string
+		value    string
+		winning  int
+		expected int64
+	}{
+		{"split", "1-2", 2, 1700},
+		{"split", "0-3", 0, 1700},
+		{"split", "1-2", 3, 0},
+		{"street", "13-14-15", 14, 1100},
+		{"street", "0-2-3", 0, 1100},
+		{"street", "13-14-15", 16, 0},
+		{"corner", "17-18-20-21", 20, 800},
+		{"corner", "17-18-20-21", 19, 0},
+		{"six_line", "31-32-33-34-35-36", 36, 500},
+		{"six_line", "31-32-33-34-35-36", 30, 0},
+		{"top_line", "0-1-2-3", 0, 600},
+		{"top_line", "0-1-2-3", 4, 0},
+	}
+	for _, tt := range tests {
+		bets := []Bet{{UserID: "u1", Type: messages.BetType(tt.betType), Value: tt.value, Amount: 100}}
+		payouts := CalculatePayouts(tt.winning, bets)
+		if payouts[0].Winnings != tt.expected {
+			t.Errorf("%s %s on %d: expected winnings %d, got %d", tt.betType, tt.value, tt.winning, tt.expected, payouts[0].Winnings)
+		}
+	}
+}
+
+func TestCalculatePayouts_AmericanWheel(t *testing.T) {
+	r := DefaultBetRegistry()
+	tests := []struct {
+		betType  string
+		value    string
+		winning  int
+		expected int64
+	}{
+		{"straight", "00", DoubleZero, 3500},
+		{"straight", "0", DoubleZero, 0},
+		{"split", "0-00", DoubleZero, 1700},
+		{"street", "00-2-3", DoubleZero, 1100},
+		{"top_line", "0-00-1-2-3", DoubleZero, 600},
+		{"top_line", "0-00-1-2-3", 3, 600},
+		{"color", "red", DoubleZero, 0},
+		{"color", "black", DoubleZero, 0},
+		{"even_odd", "even", DoubleZero, 0},
+		{"high_low", "high", DoubleZero, 0},
+		{"columns", "first", DoubleZero, 0},
+	}
+	for _, tt := range tests {
+		bets := []Bet{{UserID: "u1", Type: messages.BetType(tt.betType), Value: tt.value, Amount: 100}}
+		payouts := r.CalculatePayouts(AmericanWheel, tt.winning, bets)
+		if payouts[0].Winnings != tt.expected {
+			t.Errorf("%s %s on %s: expected winnings %d, got %d", tt.betType, tt.value, PocketLabel(tt.winning), tt.expected, payouts[0].Winnings)
+		}
+	}
+}
+
+func TestCalculatePayouts_TopLineByWheel(t *testing.T) {
+	r := DefaultBetRegistry()
+	tests := []struct {
+		wheel    *Wheel
+		value    string
+		expected int64
+	}{
+		{EuropeanWheel, "0-1-2-3", 600},
+		{AmericanWheel, "0-00-1-2-3", 600},
+		{TripleZeroWheel, "0-00-000-1-2-3", 500},
+	}
+	for _, tt := range tests {
+		bet := Bet{UserID: "u1", Type: messages.BetTypeTopLine, Value: tt.value, Amount: 100}
+		for _, winning := range tt.wheel.TopLine {
+			payouts := r.CalculatePayouts(tt.wheel, winning, []Bet{bet})
+			if payouts[0].Winnings != tt.expected {
+				t.Errorf("%s top line on %s: expected winnings %d, got %d", tt.wheel.Variant, PocketLabel(winning), tt.expected, payouts[0].Winnings)
+			}
+		}
+		exposure := r.Exposure(tt.wheel, bet)
+		for _, p := range tt.wheel.TopLine {
+			if exposure[p] != tt.expected+100 {
+				t.Errorf("%s top line: expected exposure %d on %s, got %d", tt.wheel.Variant, tt.expected+100, PocketLabel(p), exposure[p])
+			}
+		}
+	}
+}
+
+func TestApplyZeroRule_LaPartage(t *testing.T) {
+	r := DefaultBetRegistry()
+	bets := []Bet{
+		{UserID: "u1", Type: "color", Value: "red", Amount: 200},
+		{UserID: "u1", Type: "dozens", Value: "first", Amount: 200},
+		{UserID: "u1", Type: "straight", Value: "0", Amount: 100},
+		{UserID: "u1", Type: "even_odd", Value: "odd", Amount: 201},
+	}
+	payouts := r.CalculatePayouts(EuropeanWheel, 0, bets)
+	if imprisoned := r.ApplyZeroRule(EuropeanWheel, messages.ZeroRuleLaPartage, 0, payouts); imprisoned != nil {
+		t.Errorf("expected no imprisoned bets, got %v", imprisoned)
+	}
+
+	if payouts[0].Outcome != messages.PayoutOutcomeHalved || payouts[0].Refund != 100 {
+		t.Errorf("expected color bet halved with refund 100, got %+v", payouts[0])
+	}
+	if payouts[1].Outcome != "" || payouts[1].Refund != 0 {
+		t.Errorf("expected dozens bet untouched, got %+v", payouts[1])
+	}
+	if payouts[2].Winnings != 3500 {
+		t.Errorf("expected straight 0 to win 3500, got %d", payouts[2].Winnings)
+	}
+	// The house keeps the odd unit.
+	if payouts[3].Outcome != messages.PayoutOutcomeHalved || payouts[3].Refund != 100 {
+		t.Errorf("expected odd stake of 201 halved with refund 100, got %+v", payouts[3])
+	}
+}
+
+func TestApplyZeroRule_OnlyOnZero(t *testing.T) {
+	r := DefaultBetRegistry()
+	bets := []Bet{{UserID: "u1", Type: "color", Value: "red", Amount: 200}}
+	payouts := r.CalculatePayouts(EuropeanWheel, 2, bets)
+	r.ApplyZeroRule(EuropeanWheel, messages.ZeroRuleLaPartage, 2, payouts)
+	if payouts[0].Refund != 0 || payouts[0].Outcome != "" {
+		t.Errorf("expected a plain loss on a non-zero number, got %+v", payouts[0])
+	}
+}
+
+func TestApplyZeroRule_EnPrison(t *testing.T) {
+	r := DefaultBetRegistry()
+	bets := []Bet{
+		{UserID: "u1", Type: "even_odd", Value: "even", Amount: 200},
+		{UserID: "u2", Type: "high_low", Value: "low", Amount: 300},
+	}
+	payouts := r.CalculatePayouts(AmericanWheel, DoubleZero, bets)
+	imprisoned := r.ApplyZeroRule(AmericanWheel, messages.ZeroRuleEnPrison, DoubleZero, payouts)
+	if len(imprisoned) != 2 {
+		t.Fatalf("expected 2 imprisoned bets, got %d", len(imprisoned))
+	}
+	for _, p := range payouts {
+		if p.Outcome != messages.PayoutOutcomeImprisoned || p.Refund != 0 {
+			t.Errorf("expected imprisoned with no refund, got %+v", p)
+		}
+	}
+
+	settled := r.SettleImprisoned(AmericanWheel, 4, imprisoned)
+	if settled[0].Outcome != messages.PayoutOutcomeReleased || settled[0].Refund != 200 || settled[0].Winnings != 0 {
+		t.Errorf("expected even bet released with stake refunded, got %+v", settled[0])
+	}
+	if settled[1].Outcome != messages.PayoutOutcomeReleased || settled[1].Refund != 300 {
+		t.Errorf("expected low bet released with stake refunded, got %+v", settled[1])
+	}
+
+	settled = r.SettleImprisoned(AmericanWheel, 0, imprisoned)
+	for _, p := range settled {
+		if p.Outcome != messages.PayoutOutcomeForfeited || payoutReturn(p) != 0 {
+			t.Errorf("expected bet forfeited on a second zero, got %+v", p)
+		}
+	}
+}
+
+// --- Call bet tests ---
+
+// coveredBy returns the set of pockets covered by a resolved call bet.
+func coveredBy(t *testing.T, r *BetRegistry, bet Bet) map[int]bool {
+	t.Helper()
+	covered := make(map[int]bool)
+	for _, c := range bet.Components {
+		kind, ok := r.Kind(c.Type)
+		if !ok {
+			t.Fatalf("component type %s not registered", c.Type)
+		}
+		for _, n := range kind.Covered(EuropeanWheel, c.Value) {
+			covered[n] = true
+		}
+	}
+	return covered
+}
+
+// arc returns the pockets on the European wheel head from first to last, clockwise.
+func arc(first, last int) map[int]bool {
+	order := EuropeanWheel.Order
+	i := slices.Index(order, first)
+	pockets := make(map[int]bool)
+	for {
+		n := order[i%len(order)]
+		pockets[n] = true
+		if n == last {
+			return pockets
+		}
+		i++
+	}
+}
+
+func TestCallBets_CoverWheelSections(t *testing.T) {
+	r := DefaultBetRegistry()
+	orphelins := arc(17, 6)
+	for n := range arc(1, 9) {
+		orphelins[n] = true
+	}
+	tests := []struct {
+		betType string
+		chips   int64
+		want    map[int]bool
+	}{
+		{"voisins", 9, arc(22, 25)},
+		{"tiers", 6, arc(27, 33)},
+		{"orphelins", 5, orphelins},
+	}
+	for _, tt := range tests {
+		bet, err := r.Resolve(EuropeanWheel, tt.betType, "", 100)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.betType, err)
+		}
+		if bet.Amount != tt.chips*100 {
+			t.Errorf("%s: expected total stake %d, got %d", tt.betType, tt.chips*100, bet.Amount)
+		}
+		if got := coveredBy(t, r, bet); !maps.Equal(got, tt.want) {
+			t.Errorf("%s: covers %v, want %v", tt.betType, got, tt.want)
+		}
+	}
+}
+
+func TestCallBets_Neighbours(t *testing.T) {
+	r := DefaultBetRegistry()
+	bet, err := r.Resolve(EuropeanWheel, "neighbours", "0:2", 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var values []string
+	for _, c := range bet.Components {
+		if c.Type != messages.BetTypeStraight || c.Amount != 50 {
+			t.Errorf("expected 50 straight-up, got %+v", c)
+		}
+		values = append(values, c.Value)
+	}
+	if want := []string{"3", "26", "0", "32", "15"}; !slices.Equal(values, want) {
+		t.Errorf("expected neighbours %v, got %v", want, values)
+	}
+	if bet.Amount != 250 {
+		t.Errorf("expected total stake 250, got %d", bet.Amount)
+	}
+
+	bet, err = r.Resolve(AmericanWheel, "neighbours", "00:1", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := []string{bet.Components[0].Value, bet.Components[1].Value, bet.Components[2].Value}; !slices.Equal(got, []string{"1", "00", "27"}) {
+		t.Errorf("expected 1, 00, 27 around 00, got %v", got)
+	}
+
+	for _, v := range []string{"17", "17:0", "17:10", "37:1", "x:1"} {
+		if _, err := r.Resolve(EuropeanWheel, "neighbours", v, 10); !errors.Is(err, ErrInvalidBetValue) {
+			t.Errorf("expected %s rejected, got: %v", v, err)
+		}
+	}
+}
+
+func TestCallBets_FrenchBetsNeedEuropeanWheel(t *testing.T) {
+	r := DefaultBetRegistry()
+	if _, err := r.Resolve(AmericanWheel, "voisins", "", 100); !errors.Is(err, ErrUnknownBetType) {
+		t.Errorf("expected voisins rejected on an American wheel, got: %v", err)
+	}
+	if _, err := r.Resolve(EuropeanWheel, "tiers", "5", 100); !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected tiers with a value rejected, got: %v", err)
+	}
+}
+
+func TestCallBets_PayoutGroupedUnderParent(t *testing.T) {
+	r := DefaultBetRegistry()
+	bet, err := r.Resolve(EuropeanWheel, "voisins", "", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	payouts := r.CalculatePayouts(EuropeanWheel, 26, []Bet{bet})
+	if len(payouts) != 1 {
+		t.Fatalf("expected 1 grouped payout, got %d", len(payouts))
+	}
+	p := payouts[0]
+	if len(p.Components) != len(bet.Components) {
+		t.Fatalf("expected %d component payouts, got %d", len(bet.Components), len(p.Components))
+	}
+	// 26 wins the 2-chip corner 25-26-28-29: 200 * 8 + 200 back, on a 900 stake.
+	if got := payoutReturn(p); got != 1800 {
+		t.Errorf("expected 1800 returned, got %d", got)
+	}
+	if p.Winnings != 900 {
+		t.Errorf("expected parent winnings 900, got %d", p.Winnings)
+	}
+
+	if got := payoutReturn(r.CalculatePayouts(EuropeanWheel, 10, []Bet{bet})[0]); got != 0 {
+		t.Errorf("expected nothing returned for a number outside voisins, got %d", got)
+	}
+}
+
+func TestPlaceBet_CallBetDebitsTotalOrNothing(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+
+	bet, balance, err := m.PlaceBet("u1", "voisins", "", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bet.Amount != 9000 || balance != StartingBalance-9000 {
+		t.Errorf("expected 9000 staked leaving %d, got stake %d balance %d", StartingBalance-9000, bet.Amount, balance)
+	}
+	for _, c := range bet.Components {
+		if c.UserID != "u1" {
+			t.Errorf("expected component owned by u1, got %q", c.UserID)
+		}
+	}
+
+	// 1000 left; tiers at 200 a chip costs 1200.
+	if _, _, err := m.PlaceBet("u1", "tiers", "", 200); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("expected ErrInsufficientBalance, got: %v", err)
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance-9000 {
+		t.Errorf("expected balance untouched by rejected bet, got %d", user.Balance)
+	}
+	user.mu.Unlock()
+	if len(m.session.Bets) != 1 {
+		t.Errorf("expected only the voisins bet recorded, got %d", len(m.session.Bets))
+	}
+}
+
+func TestPlaceBet_RejectsStakeThatOverflows(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+
+	tests := []struct {
+		betType string
+		value   string
+		amount  int64
+	}{
+		// Nine chips a unit wraps the total to -7.
+		{"voisins", "", (math.MaxUint64 - 7) / 9},
+		{"voisins", "", math.MaxInt64 / 9},
+		{"straight", "7", math.MaxInt64/36 + 1},
+	}
+	for _, tt := range tests {
+		if _, _, err := m.PlaceBet("u1", tt.betType, tt.value, tt.amount); !errors.Is(err, ErrBetAmountTooLarge) {
+			t.Errorf("%s of %d: expected ErrBetAmountTooLarge, got: %v", tt.betType, tt.amount, err)
+		}
+	}
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	if user.Balance != StartingBalance {
+		t.Errorf("expected balance untouched, got %d", user.Balance)
+	}
+}
+
+func TestFinaleEnPlein(t *testing.T) {
+	r := DefaultBetRegistry()
+	tests := map[string][]string{
+		"7": {"7", "17", "27"},
+		"0": {"0", "10", "20", "30"},
+		"6": {"6", "16", "26", "36"},
+	}
+	for value, want := range tests {
+		bet, err := r.Resolve(EuropeanWheel, "finale_en_plein", value, 100)
+		if err != nil {
+			t.Fatalf("finale %s: unexpected error: %v", value, err)
+		}
+		var got []string
+		for _, c := range bet.Components {
+			got = append(got, c.Value)
+		}
+		if !slices.Equal(got, want) || bet.Amount != int64(len(want))*100 {
+			t.Errorf("finale %s: expected %v staking %d, got %v staking %d", value, want, len(want)*100, got, bet.Amount)
+		}
+	}
+	for _, v := range []string{"10", "-1", "a", ""} {
+		if _, err := r.Resolve(EuropeanWheel, "finale_en_plein", v, 100); !errors.Is(err, ErrInvalidBetValue) {
+			t.Errorf("expected finale %q rejected, got: %v", v, err)
+		}
+	}
+}
+
+func TestFinaleACheval(t *testing.T) {
+	r := DefaultBetRegistry()
+	bet, err := r.Resolve(EuropeanWheel, "finale_a_cheval", "1-2", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, c := range bet.Components {
+		got = append(got, string(c.Type)+" "+c.Value)
+	}
+	// 21 and 22 sit at opposite ends of neighbouring rows, so they are played straight up.
+	want := []string{"split 1-2", "split 11-12", "split 31-32", "straight 21", "straight 22"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if bet.Amount != 500 {
+		t.Errorf("expected total stake 500, got %d", bet.Amount)
+	}
+
+	bet, err = r.Resolve(EuropeanWheel, "finale_a_cheval", "3-0", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bet.Components) != 4 {
+		t.Errorf("expected 0-3 to be four splits, got %+v", bet.Components)
+	}
+
+	for _, v := range []string{"3-4", "1-5", "1-1", "12", "1-22"} {
+		if _, err := r.Resolve(EuropeanWheel, "finale_a_cheval", v, 100); !errors.Is(err, ErrInvalidBetValue) {
+			t.Errorf("expected finale_a_cheval %s rejected, got: %v", v, err)
+		}
+	}
+}
+
+func TestPlaceBet_FinaleDebitsAllOrNothing(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+
+	// Four chips at a quarter of the balance plus one cent is more than the balance.
+	if _, _, err := m.PlaceBet("u1", "finale_en_plein", "0", StartingBalance/4+1); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got: %v", err)
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance {
+		t.Errorf("expected balance untouched, got %d", user.Balance)
+	}
+	user.mu.Unlock()
+
+	if _, balance, err := m.PlaceBet("u1", "finale_en_plein", "0", StartingBalance/4); err != nil || balance != 0 {
+		t.Errorf("expected the whole balance staked, got balance %d err %v", balance, err)
+	}
+}
+
 // --- ValidateBet tests ---
 
 func TestValidateBet_ValidStraight(t *testing.T) {
@@ -182,6 +719,101 @@ func TestValidateBet_InvalidDozens(t *testing.T) {
 	}
 }
 
+func TestValidateBet_ValidColumns(t *testing.T) {
+	for _, v := range []string{"first", "second", "third"} {
+		if err := ValidateBet("columns", v, 100); err != nil {
+			t.Errorf("expected valid for %s, got: %v", v, err)
+		}
+	}
+}
+
+func TestValidateBet_InvalidColumns(t *testing.T) {
+	if err := ValidateBet("columns", "fourth", 100); !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected ErrInvalidBetValue for fourth, got: %v", err)
+	}
+}
+
+func TestValidateBet_ValidHighLow(t *testing.T) {
+	for _, v := range []string{"low", "high"} {
+		if err := ValidateBet("high_low", v, 100); err != nil {
+			t.Errorf("expected valid for %s, got: %v", v, err)
+		}
+	}
+}
+
+func TestValidateBet_InvalidHighLow(t *testing.T) {
+	if err := ValidateBet("high_low", "middle", 100); !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected ErrInvalidBetValue for middle, got: %v", err)
+	}
+}
+
+func TestValidateBet_InsideBetGeometry(t *testing.T) {
+	valid := map[string][]string{
+		"split":    {"1-2", "2-1", "1-4", "0-1", "0-3", "35-36", "33-36"},
+		"street":   {"1-2-3", "34-35-36", "0-1-2", "0-2-3"},
+		"corner":   {"1-2-4-5", "2-3-5-6", "32-33-35-36"},
+		"six_line": {"1-2-3-4-5-6", "31-32-33-34-35-36"},
+		"top_line": {"0-1-2-3"},
+	}
+	invalid := map[string][]string{
+		"split":    {"1-5", "3-4", "0-4", "1-1", "36-37", "1", "a-b"},
+		"street":   {"2-3-4", "1-2", "0-1-3", "34-35-36-37"},
+		"corner":   {"3-4-6-7", "1-2-3-4", "0-1-2-3", "34-35-37-38"},
+		"six_line": {"2-3-4-5-6-7", "1-2-3-7-8-9", "34-35-36-37-38-39"},
+		"top_line": {"0-1-2", "1-2-3-4"},
+	}
+	for betType, values := range valid {
+		for _, v := range values {
+			if err := ValidateBet(betType, v, 100); err != nil {
+				t.Errorf("expected %s %s valid, got: %v", betType, v, err)
+			}
+		}
+	}
+	for betType, values := range invalid {
+		for _, v := range values {
+			if err := ValidateBet(betType, v, 100); !errors.Is(err, ErrInvalidBetValue) {
+				t.Errorf("expected %s %s rejected with ErrInvalidBetValue, got: %v", betType, v, err)
+			}
+		}
+	}
+}
+
+func TestValidateBet_WheelVariants(t *testing.T) {
+	r := DefaultBetRegistry()
+	valid := map[*Wheel][][2]string{
+		AmericanWheel: {
+			{"straight", "00"}, {"split", "0-00"}, {"split", "00-3"}, {"street", "0-00-2"},
+			{"top_line", "0-00-1-2-3"},
+		},
+		TripleZeroWheel: {
+			{"straight", "000"}, {"split", "00-000"}, {"street", "0-00-000"},
+			{"top_line", "0-00-000-1-2-3"},
+		},
+	}
+	invalid := map[*Wheel][][2]string{
+		EuropeanWheel: {{"straight", "00"}, {"top_line", "0-00-1-2-3"}},
+		AmericanWheel: {
+			{"straight", "000"}, {"split", "0-3"}, {"split", "00-1"}, {"street", "0-2-3"},
+			{"top_line", "0-1-2-3"}, {"split", "34-00"},
+		},
+		TripleZeroWheel: {{"split", "0-2"}, {"top_line", "0-00-1-2-3"}},
+	}
+	for w, bets := range valid {
+		for _, b := range bets {
+			if err := r.ValidateBet(w, b[0], b[1], 100); err != nil {
+				t.Errorf("%s: expected %s %s valid, got: %v", w.Variant, b[0], b[1], err)
+			}
+		}
+	}
+	for w, bets := range invalid {
+		for _, b := range bets {
+			if err := r.ValidateBet(w, b[0], b[1], 100); !errors.Is(err, ErrInvalidBetValue) {
+				t.Errorf("%s: expected %s %s rejected, got: %v", w.Variant, b[0], b[1], err)
+			}
+		}
+	}
+}
+
 func TestValidateBet_InvalidAmount(t *testing.T) {
 	if err := ValidateBet("straight", "5", 0); err == nil {
 		t.Error("expected error for zero amount")
@@ -192,11 +824,3281 @@ func TestValidateBet_InvalidAmount(t *testing.T) {
 }
 
 func TestValidateBet_UnknownType(t *testing.T) {
-	if err := ValidateBet("split", "1-2", 100); err == nil {
+	if err := ValidateBet("snake", "1-5-9", 100); err == nil {
 		t.Error("expected error for unknown bet type")
 	}
 }
 
+// --- BetRegistry tests ---
+
+// evensKind is a house-specific bet used to exercise custom registrations.
+type evensKind struct{}
+
+func (evensKind) Type() messages.BetType  { return "house_evens" }
+func (evensKind) Multiplier(*Wheel) int64 { return 3 }
+func (evensKind) Validate(_ *Wheel, value string) error {
+	if value != "all" {
+		return ErrInvalidBetValue
+	}
+	return nil
+}
+func (evensKind) Covered(*Wheel, string) []int { return []int{2, 4, 6} }
+
+func TestBetRegistry_CustomKind(t *testing.T) {
+	r := DefaultBetRegistry()
+	r.Register(evensKind{})
+
+	if err := r.ValidateBet(EuropeanWheel, "house_evens", "all", 100); err != nil {
+		t.Errorf("expected valid, got: %v", err)
+	}
+	if err := r.ValidateBet(EuropeanWheel, "house_evens", "some", 100); !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected ErrInvalidBetValue, got: %v", err)
+	}
+
+	bets := []Bet{{UserID: "u1", Type: "house_evens", Value: "all", Amount: 100}}
+	if got := r.CalculatePayouts(EuropeanWheel, 4, bets)[0].Winnings; got != 300 {
+		t.Errorf("expected winnings 300, got %d", got)
+	}
+	if got := r.CalculatePayouts(EuropeanWheel, 5, bets)[0].Winnings; got != 0 {
+		t.Errorf("expected winnings 0, got %d", got)
+	}
+}
+
+func TestBetRegistry_Subset(t *testing.T) {
+	r := DefaultBetRegistry().Subset(messages.BetTypeStraight, messages.BetTypeColor)
+
+	if err := r.ValidateBet(EuropeanWheel, "color", "red", 100); err != nil {
+		t.Errorf("expected valid, got: %v", err)
+	}
+	if err := r.ValidateBet(EuropeanWheel, "dozens", "first", 100); !errors.Is(err, ErrUnknownBetType) {
+		t.Errorf("expected ErrUnknownBetType, got: %v", err)
+	}
+
+	bets := []Bet{{UserID: "u1", Type: "dozens", Value: "first", Amount: 100}}
+	if got := r.CalculatePayouts(EuropeanWheel, 1, bets)[0].Winnings; got != 0 {
+		t.Errorf("expected unregistered bet to win nothing, got %d", got)
+	}
+}
+
+func TestPlaceBet_UsesManagerWheel(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBet("u1", "straight", "00", 100); !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected 00 rejected on the default European wheel, got: %v", err)
+	}
+
+	m.SetWheel(AmericanWheel)
+	if _, _, err := m.PlaceBet("u1", "straight", "00", 100); err != nil {
+		t.Errorf("expected 00 accepted on an American wheel, got: %v", err)
+	}
+}
+
+func TestPlaceBet_RejectsTypeOutsideTableRegistry(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetBetRegistry(DefaultBetRegistry().Subset(messages.BetTypeColor))
+	m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBet("u1", "straight", "5", 100); !errors.Is(err, ErrUnknownBetType) {
+		t.Errorf("expected ErrUnknownBetType, got: %v", err)
+	}
+}
+
+// --- Game loop tests ---
+
+// instantClock fires every timer and ticker immediately so phases run without delay.
+type instantClock struct{}
+
+func (instantClock) After(time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	c <- time.Now()
+	return c
+}
+
+func (instantClock) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	c := make(chan time.Time)
+	close(c)
+	return c, func() {}
+}
+
+// settleRound runs a result phase as if the wheel had landed on winningNumber.
+func settleRound(m *Manager, winningNumber int) {
+	m.sessionMu.Lock()
+	m.session.WinningNumber = winningNumber
+	m.sessionMu.Unlock()
+	m.runResultPhase()
+}
+
+func TestEnPrison_CarriesBetIntoNextRound(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetZeroRule(messages.ZeroRuleEnPrison)
+	user := m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBet("u1", "color", "red", 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 0)
+
+	if len(m.imprisoned) != 1 {
+		t.Fatalf("expected 1 imprisoned bet, got %d", len(m.imprisoned))
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance-1000 {
+		t.Errorf("expected balance %d while imprisoned, got %d", StartingBalance-1000, user.Balance)
+	}
+	user.mu.Unlock()
+
+	m.runBettingPhase()
+	settleRound(m, 1) // 1 is red
+
+	if len(m.imprisoned) != 0 {
+		t.Errorf("expected prison to be empty, got %d", len(m.imprisoned))
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance {
+		t.Errorf("expected stake returned on release, balance %d, got %d", StartingBalance, user.Balance)
+	}
+	user.mu.Unlock()
+}
+
+func TestLaPartage_RefundsHalfStake(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetZeroRule(messages.ZeroRuleLaPartage)
+	user := m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBet("u1", "even_odd", "odd", 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 0)
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	if user.Balance != StartingBalance-500 {
+		t.Errorf("expected balance %d, got %d", StartingBalance-500, user.Balance)
+	}
+}
+
+// --- Table limit tests ---
+
+func TestExposure(t *testing.T) {
+	r := DefaultBetRegistry()
+	exposure := r.Exposure(EuropeanWheel, Bet{Type: "split", Value: "1-2", Amount: 100})
+	if len(exposure) != 2 || exposure[1] != 1800 || exposure[2] != 1800 {
+		t.Errorf("expected 1800 on 1 and 2, got %v", exposure)
+	}
+
+	bet, err := r.Resolve(EuropeanWheel, "neighbours", "0:1", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exposure = r.Exposure(EuropeanWheel, bet)
+	if len(exposure) != 3 || exposure[0] != 3600 {
+		t.Errorf("expected 3600 on each of 3 pockets, got %v", exposure)
+	}
+}
+
+func TestPlaceBet_PerTypeLimits(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetLimits(TableLimits{Bets: map[messages.BetType]BetLimit{
+		messages.BetTypeStraight: {Min: 50, Max: 500},
+		messages.BetTypeVoisins:  {Max: 900},
+	}})
+	m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBet("u1", "straight", "5", 10); !errors.Is(err, ErrBetBelowMinimum) {
+		t.Errorf("expected ErrBetBelowMinimum, got: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "straight", "5", 501); !errors.Is(err, ErrBetAboveMaximum) {
+		t.Errorf("expected ErrBetAboveMaximum, got: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "straight", "5", 500); err != nil {
+		t.Errorf("expected bet at the maximum accepted, got: %v", err)
+	}
+	// Call bets are bounded by their total: 9 chips at 101 is 909.
+	if _, _, err := m.PlaceBet("u1", "voisins", "", 101); !errors.Is(err, ErrBetAboveMaximum) {
+		t.Errorf("expected ErrBetAboveMaximum for voisins, got: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "red", 5000); err != nil {
+		t.Errorf("expected unlimited bet type accepted, got: %v", err)
+	}
+}
+
+func TestPlaceBet_PlayerStakeLimit(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetLimits(TableLimits{MaxPlayerStake: 1000})
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	if _, _, err := m.PlaceBet("u1", "color", "red", 600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "black", 401); !errors.Is(err, ErrPlayerStakeLimit) {
+		t.Errorf("expected ErrPlayerStakeLimit, got: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "black", 400); err != nil {
+		t.Errorf("expected bet up to the limit accepted, got: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u2", "color", "black", 1000); err != nil {
+		t.Errorf("expected limit to be per player, got: %v", err)
+	}
+}
+
+func TestPlaceBet_TableLiabilityLimit(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetLimits(TableLimits{MaxLiability: 5000})
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	// 100 on 17 returns 3600 if 17 comes up.
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Another 1800 on 17 from a split would take it to 5400.
+	_, _, err := m.PlaceBet("u2", "split", "17-20", 100)
+	if !errors.Is(err, ErrTableLiabilityLimit) {
+		t.Fatalf("expected ErrTableLiabilityLimit, got: %v", err)
+	}
+	if RejectCode(err) != messages.BetRejectTableLiabilityLimit {
+		t.Errorf("expected table_liability_limit code, got %s", RejectCode(err))
+	}
+	if _, _, err := m.PlaceBet("u2", "split", "19-20", 100); err != nil {
+		t.Errorf("expected bet away from 17 accepted, got: %v", err)
+	}
+}
+
+// --- Bet removal tests ---
+
+func TestCancelBet_RefundsAndFreesLimits(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetLimits(TableLimits{MaxPlayerStake: 1000, MaxLiability: 3600})
+	m.RegisterUser("u1")
+
+	bet, _, err := m.PlaceBet("u1", "straight", "17", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bet.ID == "" {
+		t.Fatal("expected the server to assign a bet ID")
+	}
+
+	removed, balance, err := m.CancelBet("u1", bet.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed.ID != bet.ID || balance != StartingBalance {
+		t.Errorf("expected bet %s refunded to %d, got bet %s balance %d", bet.ID, StartingBalance, removed.ID, balance)
+	}
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets left, got %d", len(m.session.Bets))
+	}
+
+	// The stake and liability it used are free again.
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Errorf("expected the same bet to fit again, got: %v", err)
+	}
+
+	if _, _, err := m.CancelBet("u1", bet.ID); !errors.Is(err, ErrBetNotFound) {
+		t.Errorf("expected ErrBetNotFound for an already cancelled bet, got: %v", err)
+	}
+}
+
+func TestCancelBet_OnlyOwnBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	bet, _, err := m.PlaceBet("u1", "color", "red", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.CancelBet("u2", bet.ID); !errors.Is(err, ErrBetNotFound) {
+		t.Errorf("expected ErrBetNotFound cancelling another player's bet, got: %v", err)
+	}
+}
+
+func TestUndoLastBet(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	first, _, _ := m.PlaceBet("u1", "color", "red", 100)
+	last, _, _ := m.PlaceBet("u1", "dozens", "first", 200)
+	if _, _, err := m.PlaceBet("u2", "straight", "3", 300); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	removed, balance, err := m.UndoLastBet("u1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed.ID != last.ID || balance != StartingBalance-100 {
+		t.Errorf("expected %s undone leaving %d, got %s and %d", last.ID, StartingBalance-100, removed.ID, balance)
+	}
+	removed, _, _ = m.UndoLastBet("u1")
+	if removed.ID != first.ID {
+		t.Errorf("expected %s undone next, got %s", first.ID, removed.ID)
+	}
+	if _, _, err := m.UndoLastBet("u1"); !errors.Is(err, ErrBetNotFound) {
+		t.Errorf("expected ErrBetNotFound with nothing to undo, got: %v", err)
+	}
+	if len(m.session.Bets) != 1 || m.session.Bets[0].UserID != "u2" {
+		t.Errorf("expected only u2's bet left, got %+v", m.session.Bets)
+	}
+}
+
+func TestClearBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	m.PlaceBet("u1", "color", "red", 100)
+	m.PlaceBet("u2", "straight", "3", 300)
+	m.PlaceBet("u1", "voisins", "", 10)
+
+	removed, balance, err := m.ClearBets("u1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(removed) != 2 || removed[0].Type != "color" || removed[1].Type != "voisins" {
+		t.Errorf("expected color then voisins removed, got %+v", removed)
+	}
+	if balance != StartingBalance {
+		t.Errorf("expected full refund to %d, got %d", StartingBalance, balance)
+	}
+	if len(m.session.Bets) != 1 {
+		t.Errorf("expected u2's bet to stay, got %d bets", len(m.session.Bets))
+	}
+}
+
+func TestCancelBet_RejectsWhenNotBetting(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	bet, _, _ := m.PlaceBet("u1", "color", "red", 100)
+
+	m.sessionMu.Lock()
+	m.session.State = StateSpinning
+	m.sessionMu.Unlock()
+
+	if _, _, err := m.CancelBet("u1", bet.ID); !errors.Is(err, ErrBettingClosed) {
+		t.Errorf("expected ErrBettingClosed, got: %v", err)
+	}
+	if _, _, err := m.ClearBets("u1"); !errors.Is(err, ErrBettingClosed) {
+		t.Errorf("expected ErrBettingClosed, got: %v", err)
+	}
+}
+
+// --- PlaceBets tests ---
+
+func TestPlaceBets_AcceptsAll(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	bets, balance, err := m.PlaceBets("u1", []BetRequest{
+		{Type: "straight", Value: "17", Amount: 100},
+		{Type: "color", Value: "red", Amount: 200},
+		{Type: "voisins", Amount: 10},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bets) != 3 || bets[0].Type != "straight" || bets[1].Type != "color" || bets[2].Type != "voisins" {
+		t.Fatalf("expected bets in request order, got %+v", bets)
+	}
+	for _, b := range bets {
+		if b.ID == "" || b.UserID != "u1" {
+			t.Errorf("expected an ID and owner on every bet, got %+v", b)
+		}
+	}
+	if want := int64(StartingBalance - 100 - 200 - 90); balance != want {
+		t.Errorf("expected balance %d, got %d", want, balance)
+	}
+	if len(m.session.Bets) != 3 {
+		t.Errorf("expected 3 bets on the table, got %d", len(m.session.Bets))
+	}
+}
+
+func TestPlaceBets_RejectsAllOnInvalidBet(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	_, _, err := m.PlaceBets("u1", []BetRequest{
+		{Type: "straight", Value: "17", Amount: 100},
+		{Type: "split", Value: "1-5", Amount: 100},
+	})
+	var betErr *BetError
+	if !errors.As(err, &betErr) || betErr.Index != 1 {
+		t.Fatalf("expected a BetError for bet 1, got: %v", err)
+	}
+	if !errors.Is(err, ErrInvalidBetValue) {
+		t.Errorf("expected ErrInvalidBetValue, got: %v", err)
+	}
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets placed, got %d", len(m.session.Bets))
+	}
+}
+
+func TestPlaceBets_ChecksSummedBalance(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+
+	_, _, err := m.PlaceBets("u1", []BetRequest{
+		{Type: "color", Value: "red", Amount: StartingBalance / 2},
+		{Type: "color", Value: "black", Amount: StartingBalance/2 + 1},
+	})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got: %v", err)
+	}
+	var betErr *BetError
+	if errors.As(err, &betErr) {
+		t.Errorf("expected the balance error to cover the whole batch, got bet %d", betErr.Index)
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance {
+		t.Errorf("expected balance untouched, got %d", user.Balance)
+	}
+	user.mu.Unlock()
+}
+
+func TestPlaceBets_RejectsStakesThatOverflow(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+
+	// Each bet is fine alone, but the sum wraps to a small total.
+	_, _, err := m.PlaceBets("u1", []BetRequest{
+		{Type: "color", Value: "red", Amount: math.MaxInt64 / 2},
+		{Type: "color", Value: "black", Amount: math.MaxInt64 / 2},
+		{Type: "even_odd", Value: "even", Amount: 2},
+	})
+	if !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("expected ErrInvalidAmount, got: %v", err)
+	}
+	user.mu.Lock()
+	if user.Balance != StartingBalance {
+		t.Errorf("expected balance untouched, got %d", user.Balance)
+	}
+	user.mu.Unlock()
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets recorded, got %d", len(m.session.Bets))
+	}
+}
+
+func TestPlaceBets_LimitsCountEarlierBetsInBatch(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetLimits(TableLimits{MaxLiability: 5000})
+	m.RegisterUser("u1")
+
+	// 100 on 17 returns 3600; a second 100 on 17 would take the pocket to 7200.
+	_, _, err := m.PlaceBets("u1", []BetRequest{
+		{Type: "straight", Value: "17", Amount: 100},
+		{Type: "straight", Value: "17", Amount: 100},
+	})
+	var betErr *BetError
+	if !errors.As(err, &betErr) || betErr.Index != 1 || !errors.Is(err, ErrTableLiabilityLimit) {
+		t.Fatalf("expected bet 1 to break the liability limit, got: %v", err)
+	}
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets placed, got %d", len(m.session.Bets))
+	}
+}
+
+func TestPlaceBets_RejectsEmptyBatch(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	if _, _, err := m.PlaceBets("u1", nil); !errors.Is(err, ErrNoBets) {
+		t.Errorf("expected ErrNoBets, got: %v", err)
+	}
+}
+
+func TestPlaceBets_RejectsOversizedBatch(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	reqs := make([]BetRequest, MaxBatchBets+1)
+	for i := range reqs {
+		reqs[i] = BetRequest{Type: "straight", Value: fmt.Sprint(i % 37), Amount: 1}
+	}
+	_, balance, err := m.PlaceBets("u1", reqs)
+	if !errors.Is(err, ErrTooManyBets) {
+		t.Fatalf("expected ErrTooManyBets, got: %v", err)
+	}
+	if code := RejectCode(err); code != messages.BetRejectTooManyBets {
+		t.Errorf("expected code %s, got %s", messages.BetRejectTooManyBets, code)
+	}
+	if balance != 0 || m.GetUser("u1").Balance != StartingBalance {
+		t.Errorf("expected no bets placed, got balance %d", m.GetUser("u1").Balance)
+	}
+
+	if _, _, err := m.PlaceBets("u1", reqs[:MaxBatchBets]); err != nil {
+		t.Errorf("expected a full batch to be accepted, got: %v", err)
+	}
+}
+
+// --- Rebet tests ---
+
+// playLosingRound places bets for u1, settles them on 13 (which none of them
+// cover) and opens the next betting round.
+func playLosingRound(t *testing.T, m *Manager) {
+	t.Helper()
+	m.SetClock(instantClock{})
+	if _, _, err := m.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "voisins", "", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 13)
+
+	m.sessionMu.Lock()
+	m.session = &GameSession{State: StateBetting}
+	m.sessionMu.Unlock()
+}
+
+func TestRebet_ReplaysLastRound(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	playLosingRound(t, m)
+
+	bets, balance, err := m.Rebet("u1", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bets) != 2 || bets[0].Type != "color" || bets[1].Type != "voisins" {
+		t.Fatalf("expected color then voisins, got %+v", bets)
+	}
+	if bets[0].Amount != 100 || bets[1].Amount != 90 || bets[1].ChipAmount != 10 {
+		t.Errorf("expected the same stakes as last round, got %+v", bets)
+	}
+	if want := int64(StartingBalance - 2*190); balance != want {
+		t.Errorf("expected balance %d, got %d", want, balance)
+	}
+	if len(m.session.Bets) != 2 {
+		t.Errorf("expected 2 bets on the table, got %d", len(m.session.Bets))
+	}
+}
+
+func TestRebet_Double(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	playLosingRound(t, m)
+
+	bets, balance, err := m.Rebet("u1", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bets[0].Amount != 200 || bets[1].Amount != 180 || bets[1].ChipAmount != 20 {
+		t.Errorf("expected doubled stakes, got %+v", bets)
+	}
+	if want := int64(StartingBalance - 190 - 380); balance != want {
+		t.Errorf("expected balance %d, got %d", want, balance)
+	}
+}
+
+func TestRebet_AllOrNothing(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+	playLosingRound(t, m)
+
+	// Enough for the red bet alone but not for both.
+	user.mu.Lock()
+	user.Balance = 150
+	user.mu.Unlock()
+
+	if _, _, err := m.Rebet("u1", false); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("expected ErrInsufficientBalance, got: %v", err)
+	}
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets placed, got %d", len(m.session.Bets))
+	}
+	user.mu.Lock()
+	if user.Balance != 150 {
+		t.Errorf("expected balance untouched at 150, got %d", user.Balance)
+	}
+	user.mu.Unlock()
+}
+
+func TestRebet_AllOrNothingAcrossLimits(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	playLosingRound(t, m)
+
+	// Each bet fits on its own, but not together.
+	m.SetLimits(TableLimits{MaxPlayerStake: 150})
+
+	if _, _, err := m.Rebet("u1", false); !errors.Is(err, ErrPlayerStakeLimit) {
+		t.Fatalf("expected ErrPlayerStakeLimit, got: %v", err)
+	}
+	if len(m.session.Bets) != 0 {
+		t.Errorf("expected no bets placed, got %d", len(m.session.Bets))
+	}
+}
+
+func TestRebet_NoPreviousBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	if _, _, err := m.Rebet("u1", false); !errors.Is(err, ErrNoPreviousBets) {
+		t.Errorf("expected ErrNoPreviousBets, got: %v", err)
+	}
+}
+
+// --- Fairness tests ---
+
+func TestFairSpin_DeterministicAndOnWheel(t *testing.T) {
+	seed := []byte("server-seed")
+	for _, w := range []*Wheel{EuropeanWheel, AmericanWheel, TripleZeroWheel} {
+		seen := make(map[int]bool)
+		for nonce := int64(1); nonce <= 2000; nonce++ {
+			n := FairSpin(w, seed, []string{"alice"}, nonce)
+			if !w.Has(n) {
+				t.Fatalf("%s: nonce %d gave %d, not a pocket", w.Variant, nonce, n)
+			}
+			if again := FairSpin(w, seed, []string{"alice"}, nonce); again != n {
+				t.Fatalf("%s: nonce %d gave %d then %d", w.Variant, nonce, n, again)
+			}
+			seen[n] = true
+		}
+		if len(seen) != w.Size() {
+			t.Errorf("%s: expected every pocket in 2000 spins, saw %d of %d", w.Variant, len(seen), w.Size())
+		}
+	}
+}
+
+func TestFairSpin_ClientSeedOrderDoesNotMatter(t *testing.T) {
+	seed := []byte("server-seed")
+	for nonce := int64(1); nonce <= 50; nonce++ {
+		a := FairSpin(EuropeanWheel, seed, []string{"alice", "bob"}, nonce)
+		b := FairSpin(EuropeanWheel, seed, []string{"bob", "alice"}, nonce)
+		if a != b {
+			t.Fatalf("nonce %d: seed order changed the pocket (%d vs %d)", nonce, a, b)
+		}
+	}
+}
+
+func TestVerifySpin(t *testing.T) {
+	rs := newRoundSeed(7)
+	reveal := rs.reveal()
+	want := FairSpin(EuropeanWheel, rs.serverSeed, nil, 7)
+
+	got, err := VerifySpin(EuropeanWheel, reveal.ServerSeed, reveal.ServerSeedHash, nil, 7)
+	if err != nil || got != want {
+		t.Errorf("expected %d, got %d (err %v)", want, got, err)
+	}
+
+	other := newRoundSeed(7).reveal()
+	if _, err := VerifySpin(EuropeanWheel, other.ServerSeed, reveal.ServerSeedHash, nil, 7); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("expected ErrSeedMismatch for a different seed, got: %v", err)
+	}
+	if _, err := VerifySpin(EuropeanWheel, "not-hex", reveal.ServerSeedHash, nil, 7); !errors.Is(err, ErrSeedMismatch) {
+		t.Errorf("expected ErrSeedMismatch for a malformed seed, got: %v", err)
+	}
+}
+
+func TestSetClientSeed(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	if err := m.SetClientSeed("u1", "lucky_7"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, bad := range []string{"", "has space", "a,b", "a:b", string(make([]byte, maxClientSeedLength+1))} {
+		if err := m.SetClientSeed("u1", bad); !errors.Is(err, ErrInvalidClientSeed) {
+			t.Errorf("seed %q: expected ErrInvalidClientSeed, got: %v", bad, err)
+		}
+	}
+	if got := m.ClientSeed("u1"); got != "lucky_7" {
+		t.Errorf("expected rejected seeds to leave lucky_7, got %q", got)
+	}
+}
+
+func TestManager_RoundIsVerifiable(t *testing.T) {
+	var mu sync.Mutex
+	var hash string
+	var result messages.ResultMessage
+	m := NewManager(func(data []byte) {
+		var msg messages.GameStateMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "game_state" && msg.State == messages.GamePhaseBetting {
+			mu.Lock()
+			hash = *msg.ServerSeedHash
+			mu.Unlock()
+		}
+	}, func(_ string, data []byte) {
+		mu.Lock()
+		json.Unmarshal(data, &result)
+		mu.Unlock()
+	})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.SetClientSeed("u1", "alice")
+	m.SetClientSeed("u2", "bob")
+
+	m.runBettingPhase()
+	m.runSpinningPhase()
+	m.runResultPhase()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if hash == "" || result.Fairness == nil {
+		t.Fatalf("expected a commitment and a reveal, got %q and %+v", hash, result.Fairness)
+	}
+	if result.Fairness.ServerSeedHash != hash {
+		t.Errorf("revealed hash %s differs from the one published at betting %s", result.Fairness.ServerSeedHash, hash)
+	}
+	if !slices.Equal(result.Fairness.ClientSeeds, []string{"alice", "bob"}) {
+		t.Errorf("expected both client seeds revealed, got %v", result.Fairness.ClientSeeds)
+	}
+	got, err := VerifySpin(EuropeanWheel, result.Fairness.ServerSeed, hash, result.Fairness.ClientSeeds, result.Fairness.Nonce)
+	if err != nil || got != result.WinningNumber {
+		t.Errorf("expected verification to give %d, got %d (err %v)", result.WinningNumber, got, err)
+	}
+}
+
+// --- WheelRNG tests ---
+
+func TestSeededRNG_Repeatable(t *testing.T) {
+	a, b := NewSeededRNG(42), NewSeededRNG(42)
+	for i := 0; i < 100; i++ {
+		x, _ := a.Spin(AmericanWheel)
+		y, _ := b.Spin(AmericanWheel)
+		if x != y {
+			t.Fatalf("spin %d: same seed gave %d and %d", i, x, y)
+		}
+		if !AmericanWheel.Has(x) {
+			t.Fatalf("spin %d: %d is not a pocket", i, x)
+		}
+	}
+}
+
+func TestScriptedRNG(t *testing.T) {
+	rng := NewScriptedRNG(17, 0, 5)
+	var got []int
+	for i := 0; i < 4; i++ {
+		n, err := rng.Spin(EuropeanWheel)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got = append(got, n)
+	}
+	if !slices.Equal(got, []int{17, 0, 5, 17}) {
+		t.Errorf("expected the script to repeat, got %v", got)
+	}
+
+	if _, err := NewScriptedRNG(DoubleZero).Spin(EuropeanWheel); err == nil {
+		t.Error("expected an error for 00 on a european wheel")
+	}
+	if _, err := NewScriptedRNG().Spin(EuropeanWheel); err == nil {
+		t.Error("expected an error for an empty script")
+	}
+}
+
+func TestManager_ScriptedRNGSettlesEndToEnd(t *testing.T) {
+	var mu sync.Mutex
+	var results []messages.ResultMessage
+	m := NewManager(func([]byte) {}, func(_ string, data []byte) {
+		var msg messages.ResultMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "result" {
+			mu.Lock()
+			results = append(results, msg)
+			mu.Unlock()
+		}
+	})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17, 4))
+	user := m.RegisterUser("u1")
+
+	for _, want := range []int{17, 4} {
+		m.runBettingPhase()
+		if hash, _ := m.Commitment(); hash != "" {
+			t.Errorf("expected no commitment with an injected RNG, got %s", hash)
+		}
+		if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		m.runSpinningPhase()
+		if m.session.WinningNumber != want {
+			t.Fatalf("expected the scripted %d, got %d", want, m.session.WinningNumber)
+		}
+		m.runResultPhase()
+	}
+
+	// Won 3500 on the first round, lost 100 on the second.
+	user.mu.Lock()
+	if want := int64(StartingBalance + 3500 - 100); user.Balance != want {
+		t.Errorf("expected balance %d, got %d", want, user.Balance)
+	}
+	user.mu.Unlock()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(results) != 2 || results[0].TotalWon != 3600 || results[1].TotalWon != 0 {
+		t.Errorf("expected a 3600 return then nothing, got %+v", results)
+	}
+	if results[0].Fairness != nil {
+		t.Errorf("expected no fairness reveal with an injected RNG, got %+v", results[0].Fairness)
+	}
+}
+
+func TestManager_FailingRNGFallsBackToFairSpin(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG())
+
+	m.runBettingPhase()
+	seed := m.session.seed
+	m.runSpinningPhase()
+
+	if want := FairSpin(EuropeanWheel, seed.serverSeed, seed.clientSeeds, seed.nonce); m.session.WinningNumber != want {
+		t.Errorf("expected the fair spin %d, got %d", want, m.session.WinningNumber)
+	}
+}
+
+// --- Physics wheel tests ---
+
+// restingPocket returns the pocket under the ball in a trajectory's last keyframe.
+func restingPocket(w *Wheel, tr messages.Trajectory) int {
+	last := tr.Keyframes[len(tr.Keyframes)-1]
+	rel := math.Mod(last.BallAngle-last.RotorAngle, 360)
+	if rel < 0 {
+		rel += 360
+	}
+	return w.Order[int(rel/(360/float64(len(w.Order))))]
+}
+
+func TestPhysicsWheel_SimulateToLandsOnTarget(t *testing.T) {
+	p := DefaultPhysicsWheel()
+	rnd := mathrand.New(mathrand.NewPCG(1, 2))
+	for _, w := range []*Wheel{EuropeanWheel, AmericanWheel, TripleZeroWheel} {
+		for _, target := range w.Pockets() {
+			spin := p.SimulateTo(w, target, rnd)
+			if spin.Pocket != target {
+				t.Fatalf("%s: aimed for %s, landed on %s", w.Variant, PocketLabel(target), PocketLabel(spin.Pocket))
+			}
+			if got := restingPocket(w, spin.Trajectory); got != target {
+				t.Fatalf("%s: trajectory for %s ends in %s", w.Variant, PocketLabel(target), PocketLabel(got))
+			}
+		}
+	}
+}
+
+func TestPhysicsWheel_TrajectoryIsContinuous(t *testing.T) {
+	p := DefaultPhysicsWheel()
+	rnd := mathrand.New(mathrand.NewPCG(3, 4))
+	for i := 0; i < 50; i++ {
+		spin := p.Simulate(EuropeanWheel, rnd)
+		tr := spin.Trajectory
+		if restingPocket(EuropeanWheel, tr) != spin.Pocket {
+			t.Fatalf("spin %d: trajectory does not end in the reported pocket %d", i, spin.Pocket)
+		}
+		if !(tr.Drop > 0 && tr.Drop < tr.Landed && tr.Landed < p.Duration.Seconds()) {
+			t.Fatalf("spin %d: expected drop < landed < duration, got %v and %v", i, tr.Drop, tr.Landed)
+		}
+		for j := 1; j < len(tr.Keyframes); j++ {
+			prev, cur := tr.Keyframes[j-1], tr.Keyframes[j]
+			if cur.T <= prev.T {
+				t.Fatalf("spin %d: keyframe %d goes back in time", i, j)
+			}
+			// The ball never covers more than a fifth of the wheel between frames.
+			if math.Abs(cur.BallAngle-prev.BallAngle) > 72 {
+				t.Fatalf("spin %d: ball jumps from %.1f to %.1f at %.2fs", i, prev.BallAngle, cur.BallAngle, cur.T)
+			}
+		}
+	}
+}
+
+func TestManager_PhysicsTrajectorySentWhenSpinning(t *testing.T) {
+	var mu sync.Mutex
+	var spinning *messages.GameStateMessage
+	m := NewManager(func(data []byte) {
+		var msg messages.GameStateMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "game_state" && msg.State == messages.GamePhaseSpinning {
+			mu.Lock()
+			spinning = &msg
+			mu.Unlock()
+		}
+	}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(26))
+	m.SetPhysics(DefaultPhysicsWheel())
+
+	m.runBettingPhase()
+	m.runSpinningPhase()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if spinning == nil || spinning.Trajectory == nil {
+		t.Fatal("expected a trajectory with the spinning state")
+	}
+	if got := restingPocket(EuropeanWheel, *spinning.Trajectory); got != 26 {
+		t.Errorf("expected the ball to settle in 26, got %d", got)
+	}
+}
+
+func TestManager_PhysicsTrajectoryFollowsSpinningPhase(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetPhysics(DefaultPhysicsWheel())
+
+	for _, spinning := range []time.Duration{8 * time.Second, 5 * time.Second} {
+		// As a private table's owner would change it between rounds.
+		m.SetPhaseDurations(PhaseDurations{Spinning: spinning})
+		m.runBettingPhase()
+		m.runSpinningPhase()
+
+		m.sessionMu.RLock()
+		frames := m.session.trajectory.Keyframes
+		m.sessionMu.RUnlock()
+		want := (spinning + resultOverrun).Seconds()
+		if last := frames[len(frames)-1].T; last != want {
+			t.Errorf("spinning %s: expected the trajectory to run %.1fs, got %.1fs", spinning, want, last)
+		}
+		m.runResultPhase()
+	}
+}
+
+// --- Live dealer tests ---
+
+// heldClock ticks instantly but only fires After when the test sends on after.
+type heldClock struct{ after chan time.Time }
+
+func (c heldClock) After(time.Duration) <-chan time.Time { return c.after }
+
+func (heldClock) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	return instantClock{}.NewTicker(0)
+}
+
+// startLiveSpin runs a live spinning phase in the background and waits until
+// it is ready for the croupier. The returned channel yields its result.
+func startLiveSpin(t *testing.T, m *Manager) <-chan bool {
+	t.Helper()
+	done := make(chan bool, 1)
+	go func() { done <- m.runSpinningPhase() }()
+	deadline := time.Now().Add(time.Second)
+	for !m.AwaitingResult() {
+		if time.Now().After(deadline) {
+			t.Fatal("spinning phase never started waiting for the croupier")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func newLiveManager(t *testing.T, clock Clock, sendToUser SendToUserFunc) *Manager {
+	t.Helper()
+	m := NewManager(func([]byte) {}, sendToUser)
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(clock)
+	m.SetLiveDealer(time.Minute)
+	return m
+}
+
+func TestLiveDealer_ConfirmedResultSettles(t *testing.T) {
+	m := newLiveManager(t, heldClock{after: make(chan time.Time)}, func(string, []byte) {})
+	m.RegisterUser("u1")
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := startLiveSpin(t, m)
+	if hash, _ := m.Commitment(); hash != "" {
+		t.Errorf("expected no seed commitment at a live table, got %s", hash)
+	}
+	if _, err := m.EnterResult("17"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.ConfirmResult("17"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !<-done {
+		t.Fatal("expected the round to go on to settlement")
+	}
+	if m.session.WinningNumber != 17 {
+		t.Errorf("expected 17, got %d", m.session.WinningNumber)
+	}
+}
+
+func TestLiveDealer_MismatchedEntriesStartOver(t *testing.T) {
+	m := newLiveManager(t, heldClock{after: make(chan time.Time)}, func(string, []byte) {})
+	done := startLiveSpin(t, m)
+
+	m.EnterResult("17")
+	if _, err := m.ConfirmResult("18"); !errors.Is(err, ErrResultMismatch) {
+		t.Fatalf("expected ErrResultMismatch, got: %v", err)
+	}
+	if _, err := m.ConfirmResult("17"); !errors.Is(err, ErrNoPendingResult) {
+		t.Fatalf("expected the first entry discarded, got: %v", err)
+	}
+	if _, err := m.EnterResult("37"); !errors.Is(err, ErrInvalidPocket) {
+		t.Errorf("expected ErrInvalidPocket, got: %v", err)
+	}
+
+	m.EnterResult("5")
+	if _, err := m.ConfirmResult("5"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-done
+	if m.session.WinningNumber != 5 {
+		t.Errorf("expected 5, got %d", m.session.WinningNumber)
+	}
+	if _, err := m.EnterResult("5"); !errors.Is(err, ErrNotAwaitingResult) {
+		t.Errorf("expected ErrNotAwaitingResult once settled, got: %v", err)
+	}
+}
+
+func TestLiveDealer_VoidRefundsEveryBet(t *testing.T) {
+	var mu sync.Mutex
+	voided := make(map[string]messages.RoundVoidedMessage)
+	m := newLiveManager(t, heldClock{after: make(chan time.Time)}, func(userID string, data []byte) {
+		var msg messages.RoundVoidedMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "round_voided" {
+			mu.Lock()
+			voided[userID] = msg
+			mu.Unlock()
+		}
+	})
+	u1 := m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.PlaceBet("u1", "straight", "17", 100)
+	m.PlaceBet("u1", "voisins", "", 10)
+
+	done := startLiveSpin(t, m)
+	if err := m.VoidRound("ball left the wheel"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if <-done {
+		t.Fatal("expected a voided round not to be settled")
+	}
+
+	u1.mu.Lock()
+	if u1.Balance != StartingBalance {
+		t.Errorf("expected a full refund to %d, got %d", StartingBalance, u1.Balance)
+	}
+	u1.mu.Unlock()
+	mu.Lock()
+	defer mu.Unlock()
+	if got := voided["u1"]; got.Refunded != 190 || got.Reason != "ball left the wheel" {
+		t.Errorf("expected u1 told of a 190 refund, got %+v", got)
+	}
+	if got, ok := voided["u2"]; !ok || got.Refunded != 0 {
+		t.Errorf("expected u2 told of the void with nothing refunded, got %+v", got)
+	}
+}
+
+func TestLiveDealer_TimeoutVoids(t *testing.T) {
+	m := newLiveManager(t, instantClock{}, func(string, []byte) {})
+	m.RegisterUser("u1")
+	m.PlaceBet("u1", "color", "red", 500)
+
+	if m.runSpinningPhase() {
+		t.Fatal("expected the round to be voided when no result arrives")
+	}
+	if u := m.GetUser("u1"); u.Balance != StartingBalance {
+		t.Errorf("expected a refund to %d, got %d", StartingBalance, u.Balance)
+	}
+}
+
+func TestLiveDealer_RequiresLiveMode(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	if _, err := m.EnterResult("17"); !errors.Is(err, ErrNotLiveDealer) {
+		t.Errorf("expected ErrNotLiveDealer, got: %v", err)
+	}
+	if err := m.VoidRound(""); !errors.Is(err, ErrNotLiveDealer) {
+		t.Errorf("expected ErrNotLiveDealer, got: %v", err)
+	}
+	if m.CheckAdminToken("") {
+		t.Error("expected an unset admin token to authorize nothing")
+	}
+	m.SetAdminToken("s3cret")
+	if !m.CheckAdminToken("s3cret") || m.CheckAdminToken("guess") {
+		t.Error("expected only the admin token to be accepted")
+	}
+}
+
+// --- RNG monitor tests ---
+
+// rngDraws sizes the offline battery, e.g. go test ./internal/game -run TestRNGBattery -rng.draws=10000000.
+// Unset, the battery only runs over the deterministic sources, so a plain go test can't fail by chance.
+var rngDraws = flag.Int("rng.draws", 0, "spins drawn from each source by TestRNGBattery; also tests the crypto source")
+
+func TestChiSquareSurvival(t *testing.T) {
+	// 95th percentiles of the chi-square distribution.
+	tests := []struct {
+		stat float64
+		df   int
+	}{
+		{3.841, 1},
+		{18.307, 10},
+		{50.998, 36},
+		{15.507, 8},
+	}
+	for _, tt := range tests {
+		if p := chiSquareSurvival(tt.stat, tt.df); math.Abs(p-0.05) > 1e-3 {
+			t.Errorf("chiSquareSurvival(%v, %d) = %v, want 0.05", tt.stat, tt.df, p)
+		}
+	}
+	if p := chiSquareSurvival(0, 36); p != 1 {
+		t.Errorf("expected p 1 for a zero statistic, got %v", p)
+	}
+}
+
+// TestRNGBattery draws from each of the table's sources and checks that the
+// battery fails windows about as often as alpha says it should, and passes
+// over the whole sample. By default it draws a fixed sample from the seeded
+// and fair sources; set -rng.draws to add the crypto source and run it over
+// millions of spins.
+func TestRNGBattery(t *testing.T) {
+	const (
+		window = 3700
+		alpha  = 0.01
+	)
+	seed := make([]byte, 32)
+	var nonce int64
+	sources := map[string]func(w *Wheel) int{
+		"seeded": func() func(w *Wheel) int {
+			rng := NewSeededRNG(42)
+			return func(w *Wheel) int { n, _ := rng.Spin(w); return n }
+		}(),
+		"fair": func(w *Wheel) int {
+			nonce++
+			return FairSpin(w, seed, []string{"alice"}, nonce)
+		},
+	}
+	size := 100_000
+	if *rngDraws > 0 {
+		size = *rngDraws
+		sources["crypto"] = func(w *Wheel) int {
+			n, err := CryptoRNG{}.Spin(w)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return n
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		for _, w := range []*Wheel{EuropeanWheel, AmericanWheel} {
+			draws := make([]int, size)
+			for i := range draws {
+				draws[i] = sources[name](w)
+			}
+
+			chunks := len(draws) / window
+			failures := map[messages.RNGTest]int{}
+			for c := range chunks {
+				for _, res := range Battery(w, draws[c*window:(c+1)*window], alpha) {
+					if !res.Passed {
+						failures[res.Test]++
+					}
+				}
+			}
+			expected := float64(chunks) * alpha
+			bound := int(expected + 4*math.Sqrt(expected) + 3)
+			for test, n := range failures {
+				if n > bound {
+					t.Errorf("%s on %s: %s failed %d of %d windows, expected about %.1f", name, w.Variant, test, n, chunks, expected)
+				}
+			}
+			for _, res := range Battery(w, draws, 1e-6) {
+				if !res.Passed {
+					t.Errorf("%s on %s: %s failed over %d spins, p=%g", name, w.Variant, res.Test, len(draws), res.PValue)
+				}
+			}
+			t.Logf("%s on %s: %d windows of %d, failures at alpha %g: %v", name, w.Variant, chunks, window, alpha, failures)
+		}
+	}
+}
+
+func TestBattery_FlagsBiasedSequences(t *testing.T) {
+	// Pockets in wheel order: perfectly even, but regular.
+	var cycle []int
+	for range 20 {
+		cycle = append(cycle, EuropeanWheel.Order...)
+	}
+	// A seeded wheel with an extra share of 17s.
+	rng := NewSeededRNG(1)
+	var heavy []int
+	for i := range 740 {
+		n, _ := rng.Spin(EuropeanWheel)
+		if i%6 == 0 {
+			n = 17
+		}
+		heavy = append(heavy, n)
+	}
+
+	tests := []struct {
+		name    string
+		pockets []int
+		fails   messages.RNGTest
+	}{
+		{"cycle", cycle, messages.RNGTestGap},
+		{"heavy", heavy, messages.RNGTestChiSquare},
+		{"alternating", slices.Repeat([]int{1, 36}, 370), messages.RNGTestRuns},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, res := range Battery(EuropeanWheel, tt.pockets, 0.0001) {
+				if res.Test == tt.fails && res.Passed {
+					t.Errorf("expected %s to fail, got p=%g", res.Test, res.PValue)
+				}
+			}
+		})
+	}
+}
+
+func TestRNGMonitor_TripsAfterConsecutiveFailures(t *testing.T) {
+	mon := NewRNGMonitor(EuropeanWheel, RNGMonitorConfig{
+		Windows:   []int{370},
+		Interval:  37,
+		Alpha:     0.001,
+		Tolerance: 2,
+	})
+	for i := range 407 {
+		if i == 370 {
+			if !mon.Healthy() {
+				t.Fatal("expected one failure not to trip the monitor")
+			}
+			if r := mon.Report(); len(r.Results) != 3 || r.Results[2].Test != messages.RNGTestGap || r.Results[2].Failures != 1 {
+				t.Fatalf("expected 3 results after the first run, got %+v", r.Results)
+			}
+		}
+		mon.Record(EuropeanWheel.Order[i%37])
+	}
+	if mon.Healthy() || mon.Reason() == "" {
+		t.Fatal("expected the monitor to trip on the second failure")
+	}
+	if r := mon.Report(); !r.Paused || r.Spins != 407 {
+		t.Errorf("expected a paused report after 407 spins, got %+v", r)
+	}
+
+	mon.Reset()
+	if !mon.Healthy() {
+		t.Error("expected the monitor to be healthy after a reset")
+	}
+	if r := mon.Report(); len(r.Results) != 0 || r.Spins != 407 {
+		t.Errorf("expected no results and the spin count kept, got %+v", r)
+	}
+}
+
+func TestRNGMonitor_WaitsForWindowToFill(t *testing.T) {
+	mon := NewRNGMonitor(EuropeanWheel, DefaultRNGMonitorConfig())
+	for range 369 {
+		mon.Record(17)
+	}
+	if r := mon.Report(); len(r.Results) != 0 || !mon.Healthy() {
+		t.Errorf("expected no tests before the window fills, got %+v", r)
+	}
+}
+
+func TestManager_PausesWhenRNGMonitorTrips(t *testing.T) {
+	var mu sync.Mutex
+	var paused messages.GameStateMessage
+	m := NewManager(func(data []byte) {
+		var msg messages.GameStateMessage
+		if json.Unmarshal(data, &msg) == nil && msg.State == messages.GamePhasePaused {
+			mu.Lock()
+			paused = msg
+			mu.Unlock()
+		}
+	}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17))
+	m.SetRNGMonitor(NewRNGMonitor(EuropeanWheel, RNGMonitorConfig{Windows: []int{74}, Interval: 37, Alpha: 0.001, Tolerance: 1}))
+	m.RegisterUser("u1")
+
+	if err := m.ResumeRNG(); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("expected ErrNotPaused, got %v", err)
+	}
+	for range 74 {
+		m.runBettingPhase()
+		m.runSpinningPhase()
+		m.runResultPhase()
+	}
+	if m.PauseReason() == "" {
+		t.Fatal("expected the monitor to trip on a wheel that only lands on 17")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.runPausedPhase()
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for state, _, _ := m.GetCurrentGameState(); state != messages.GamePhasePaused; state, _, _ = m.GetCurrentGameState() {
+		if time.Now().After(deadline) {
+			t.Fatal("table never paused")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); !errors.Is(err, ErrBettingClosed) {
+		t.Errorf("expected ErrBettingClosed while paused, got %v", err)
+	}
+
+	if err := m.ResumeRNG(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("table did not resume")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if paused.Reason == nil || *paused.Reason == "" {
+		t.Errorf("expected the PAUSED state to carry a reason, got %+v", paused)
+	}
+}
+
+func TestManager_ResumeRNGWithoutMonitor(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	if err := m.ResumeRNG(); !errors.Is(err, ErrNoRNGMonitor) {
+		t.Errorf("expected ErrNoRNGMonitor, got %v", err)
+	}
+	if _, ok := m.RNGReport(); ok {
+		t.Error("expected no report without a monitor")
+	}
+}
+
+// --- User store tests ---
+
+func TestBoltStore_RestoresBalanceAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roulette.db")
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	m.SetUserStore(store)
+	user := m.RegisterUser("u1")
+	m.SetUserName("u1", "Alice")
+	if _, _, err := m.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	token := user.SessionToken
+	m.Stop()
+	if err := store.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	m = NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetUserStore(store)
+
+	if m.ValidateSessionToken("u1", "wrong") || m.GetUser("u1") != nil {
+		t.Fatal("expected a wrong token not to restore the user")
+	}
+	if !m.ValidateSessionToken("u1", token) {
+		t.Fatal("expected the session token to survive a restart")
+	}
+	restored := m.GetUser("u1")
+	if restored == nil || restored.Balance != StartingBalance-100 || restored.Name != "Alice#u1" {
+		t.Errorf("expected Alice#u1 with %d, got %+v", StartingBalance-100, restored)
+	}
+}
+
+func TestManager_EvictedUserRestoredOnReconnect(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	user := m.RegisterUser("u1")
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 17)
+
+	// As runCleanup does after the grace period.
+	m.usersMu.Lock()
+	delete(m.users, "u1")
+	m.usersMu.Unlock()
+
+	if !m.ValidateSessionToken("u1", user.SessionToken) {
+		t.Fatal("expected an evicted user to be restored")
+	}
+	if got, want := m.GetUser("u1").Balance, int64(StartingBalance+3500); got != want {
+		t.Errorf("expected balance %d, got %d", want, got)
+	}
+}
+
+func TestManager_EvictionKeepsUsersWithBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetZeroRule(messages.ZeroRuleEnPrison)
+	for _, id := range []string{"held", "betting", "gone", "here"} {
+		m.RegisterUser(id)
+	}
+	if _, _, err := m.PlaceBet("held", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 0)
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("betting", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	long := time.Now().Add(-2 * disconnectGracePeriod)
+	for _, id := range []string{"held", "betting", "gone"} {
+		m.GetUser(id).LastDisconnect = &long
+	}
+
+	m.evictDisconnected(time.Now())
+	for id, want := range map[string]bool{"held": true, "betting": true, "gone": false, "here": true} {
+		if got := m.GetUser(id) != nil; got != want {
+			t.Errorf("%s: expected seated %v, got %v", id, want, got)
+		}
+	}
+}
+
+func TestUnregisterUser_DeletesFromStore(t *testing.T) {
+	store := NewMemoryUserStore()
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetUserStore(store)
+	m.RegisterUser("u1")
+
+	m.UnregisterUser("u1")
+	if _, ok, _ := store.GetUser("u1"); ok {
+		t.Error("expected the user to be deleted from the store")
+	}
+}
+
+// --- Round history tests ---
+
+func TestManager_RecordsRounds(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17, 4))
+	m.RegisterUser("u1")
+
+	for range 2 {
+		m.runBettingPhase()
+		if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		m.runSpinningPhase()
+		m.runResultPhase()
+	}
+
+	round, ok, err := m.Round(1)
+	if err != nil || !ok {
+		t.Fatalf("expected round 1, got ok=%v err=%v", ok, err)
+	}
+	if *round.WinningNumber != 17 || *round.WinningPocket != "17" || round.Voided {
+		t.Errorf("expected round 1 to land on 17, got %+v", round)
+	}
+	if len(round.Bets) != 1 || len(round.Payouts) != 1 || round.Payouts[0].Winnings != 3500 {
+		t.Errorf("expected the bet and its 3500 win, got %+v and %+v", round.Bets, round.Payouts)
+	}
+	if round.StartedAt.IsZero() || round.EndedAt.Before(round.StartedAt) {
+		t.Errorf("expected start and end times in order, got %v and %v", round.StartedAt, round.EndedAt)
+	}
+
+	rounds, _ := m.Rounds(0, 10)
+	if len(rounds) != 2 || rounds[0].ID != 2 || rounds[1].ID != 1 {
+		t.Fatalf("expected rounds 2 and 1, got %+v", rounds)
+	}
+	if rounds, _ := m.Rounds(2, 10); len(rounds) != 1 || rounds[0].ID != 1 {
+		t.Errorf("expected only round 1 before 2, got %+v", rounds)
+	}
+	results, _ := m.RecentResults(HistoryLength)
+	want := []messages.RoundResult{
+		{RoundID: 2, WinningNumber: 4, WinningPocket: "4"},
+		{RoundID: 1, WinningNumber: 17, WinningPocket: "17"},
+	}
+	if !slices.Equal(results, want) {
+		t.Errorf("expected %+v, got %+v", want, results)
+	}
+}
+
+func TestManager_RecordsVoidedRound(t *testing.T) {
+	m := newLiveManager(t, instantClock{}, func(string, []byte) {})
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.voidRound("ball left the wheel")
+
+	rounds, _ := m.Rounds(0, 1)
+	if len(rounds) != 1 || !rounds[0].Voided || rounds[0].VoidReason != "ball left the wheel" || rounds[0].WinningNumber != nil {
+		t.Fatalf("expected a voided round, got %+v", rounds)
+	}
+	if len(rounds[0].Bets) != 1 {
+		t.Errorf("expected the refunded bet in the round, got %+v", rounds[0].Bets)
+	}
+	if results, _ := m.RecentResults(HistoryLength); len(results) != 0 {
+		t.Errorf("expected voided rounds to be left out of the results, got %+v", results)
+	}
+}
+
+func TestMemoryRoundStore_KeepsLatest(t *testing.T) {
+	s := NewMemoryRoundStore(3)
+	for id := range int64(5) {
+		s.PutRound(messages.Round{ID: id + 1})
+	}
+	rounds, _ := s.Rounds(0, 10)
+	if len(rounds) != 3 || rounds[0].ID != 5 || rounds[2].ID != 3 {
+		t.Errorf("expected rounds 5 to 3, got %+v", rounds)
+	}
+	if _, ok, _ := s.GetRound(1); ok {
+		t.Error("expected round 1 to have been dropped")
+	}
+}
+
+func TestBoltStore_Rounds(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "roulette.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for id := range int64(5) {
+		n := int(id)
+		if err := store.PutRound(messages.Round{ID: id + 1, WinningNumber: &n}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	ids := func(rounds []messages.Round) []int64 {
+		var ids []int64
+		for _, r := range rounds {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		before int64
+		limit  int
+		want   []int64
+	}{
+		{0, 2, []int64{5, 4}},
+		{4, 10, []int64{3, 2, 1}},
+		{100, 1, []int64{5}},
+		{1, 10, nil},
+	}
+	for _, tt := range tests {
+		rounds, err := store.Rounds(tt.before, tt.limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ids(rounds); !slices.Equal(got, tt.want) {
+			t.Errorf("Rounds(%d, %d) = %v, want %v", tt.before, tt.limit, got, tt.want)
+		}
+	}
+	if r, ok, _ := store.GetRound(3); !ok || *r.WinningNumber != 2 {
+		t.Errorf("expected round 3 landing on 2, got %+v", r)
+	}
+
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRoundStore(store)
+	m.runBettingPhase()
+	if m.session.round != 6 {
+		t.Errorf("expected round IDs to carry on at 6, got %d", m.session.round)
+	}
+}
+
+// --- Ledger tests ---
+
+// checkLedger fails the test unless the user's balance is the sum of their
+// transactions and each one picks up where the last left off.
+func checkLedger(t *testing.T, m *Manager, userID string) []messages.Transaction {
+	t.Helper()
+	txs, err := m.Transactions(userID, 0, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slices.Reverse(txs)
+	var sum int64
+	for i, tx := range txs {
+		if i > 0 && tx.BalanceBefore != txs[i-1].BalanceAfter {
+			t.Errorf("transaction %d starts at %d, the last ended at %d", tx.ID, tx.BalanceBefore, txs[i-1].BalanceAfter)
+		}
+		sum += entryAmount(tx, userID)
+	}
+	user := m.GetUser(userID)
+	user.mu.Lock()
+	balance := user.Balance
+	user.mu.Unlock()
+	if sum != balance {
+		t.Errorf("expected the ledger to sum to the balance %d, got %d", balance, sum)
+	}
+	return txs
+}
+
+func TestLedger_RecordsEveryBalanceChange(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17, 4))
+	m.RegisterUser("u1")
+
+	// Round 1: a straight win, a losing bet and a cancelled bet.
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "straight", "5", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "black", 50); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.UndoLastBet("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.runSpinningPhase()
+	m.runResultPhase()
+
+	// Round 2: lose everything and get refilled.
+	m.runBettingPhase()
+	balance := m.GetUser("u1").Balance
+	if _, _, err := m.PlaceBet("u1", "straight", "17", balance); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.runSpinningPhase()
+	m.runResultPhase()
+
+	if _, err := m.AdjustBalance("u1", -500, "goodwill reversal"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	txs := checkLedger(t, m, "u1")
+	want := []messages.TransactionKind{
+		messages.TransactionOpening,
+		messages.TransactionBet, messages.TransactionBet, messages.TransactionBet,
+		messages.TransactionRefund,
+		messages.TransactionWin,
+		messages.TransactionBet,
+		messages.TransactionRefill,
+		messages.TransactionAdjustment,
+	}
+	var kinds []messages.TransactionKind
+	for _, tx := range txs {
+		kinds = append(kinds, tx.Kind)
+	}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("expected %v, got %v", want, kinds)
+	}
+	if win := txs[5]; win.RoundID != 1 || win.Amount != 3600 || win.Credit != "u1" || win.Debit != HouseAccount {
+		t.Errorf("expected a 3600 credit from the house in round 1, got %+v", win)
+	}
+	if adj := txs[8]; adj.Amount != 500 || adj.Debit != "u1" || adj.Credit != HouseAccount || adj.Note != "goodwill reversal" {
+		t.Errorf("expected a 500 debit to the house, got %+v", adj)
+	}
+
+	if res, err := m.Reconcile(); err != nil || res.Checked != 1 || len(res.Mismatches) != 0 {
+		t.Errorf("expected one clean user, got %+v, %v", res, err)
+	}
+}
+
+func TestLedger_RefundsVoidedRound(t *testing.T) {
+	m := newLiveManager(t, instantClock{}, func(string, []byte) {})
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.voidRound("ball left the wheel")
+
+	txs := checkLedger(t, m, "u1")
+	last := txs[len(txs)-1]
+	if last.Kind != messages.TransactionRefund || last.Amount != 100 || last.Note != "round voided" {
+		t.Errorf("expected the bet refunded, got %+v", last)
+	}
+}
+
+func TestLedger_AdjustBalanceErrors(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+
+	tests := []struct {
+		userID string
+		amount int64
+		want   error
+	}{
+		{"u1", 0, ErrInvalidAmount},
+		{"nobody", 100, ErrUserNotFound},
+		{"u1", -StartingBalance - 1, ErrInsufficientBalance},
+	}
+	for _, tt := range tests {
+		if _, err := m.AdjustBalance(tt.userID, tt.amount, ""); !errors.Is(err, tt.want) {
+			t.Errorf("AdjustBalance(%q, %d) = %v, want %v", tt.userID, tt.amount, err, tt.want)
+		}
+	}
+	tx, err := m.AdjustBalance("u1", 250, "bonus")
+	if err != nil || tx.BalanceAfter != StartingBalance+250 || tx.Kind != messages.TransactionAdjustment {
+		t.Errorf("expected an adjustment to %d, got %+v, %v", StartingBalance+250, tx, err)
+	}
+	if txs := checkLedger(t, m, "u1"); tx.ID == 0 || txs[len(txs)-1] != tx {
+		t.Errorf("expected the recorded transaction back, got %+v", tx)
+	}
+}
+
+func TestLedger_RegisterTwiceOpensOnce(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	user := m.RegisterUser("u1")
+	if _, err := m.AdjustBalance("u1", 250, "bonus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if again := m.RegisterUser("u1"); again != user {
+		t.Error("expected the seated user back")
+	}
+	// As if evicted while disconnected: only the user store has them.
+	m.usersMu.Lock()
+	delete(m.users, "u1")
+	m.usersMu.Unlock()
+	if again := m.RegisterUser("u1"); again == nil || again.SessionToken != user.SessionToken {
+		t.Error("expected the stored user back")
+	}
+
+	txs := checkLedger(t, m, "u1")
+	if len(txs) != 2 || txs[0].Kind != messages.TransactionOpening {
+		t.Errorf("expected one opening and the adjustment, got %+v", txs)
+	}
+	if balance := m.GetUser("u1").Balance; balance != StartingBalance+250 {
+		t.Errorf("expected balance %d kept, got %d", StartingBalance+250, balance)
+	}
+	if res, err := m.Reconcile(); err != nil || len(res.Mismatches) != 0 {
+		t.Errorf("expected a clean ledger, got %+v, %v", res, err)
+	}
+}
+
+func TestLedger_ReconcileFlagsMismatch(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.GetUser("u2").Balance += 1 // a change that bypassed the ledger
+
+	res, err := m.Reconcile()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []messages.LedgerMismatch{{UserID: "u2", Balance: StartingBalance + 1, LedgerBalance: StartingBalance}}
+	if res.Checked != 2 || !slices.Equal(res.Mismatches, want) {
+		t.Errorf("expected %+v out of 2, got %+v", want, res)
+	}
+}
+
+// failingLedger is a memory ledger whose writes fail while fail is set.
+type failingLedger struct {
+	*MemoryLedger
+	fail atomic.Bool
+}
+
+func (l *failingLedger) AppendTransactions(txs []messages.Transaction) error {
+	if l.fail.Load() {
+		return errors.New("disk full")
+	}
+	return l.MemoryLedger.AppendTransactions(txs)
+}
+
+func TestLedger_FailedWriteChangesNothing(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	ledger := &failingLedger{MemoryLedger: NewMemoryLedger()}
+	m.SetLedger(ledger)
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	bet, _, err := m.PlaceBet("u1", "color", "red", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ledger.fail.Store(true)
+	if _, _, err := m.PlaceBet("u1", "color", "black", 100); !errors.Is(err, ErrLedgerWrite) {
+		t.Errorf("expected the bet refused with ErrLedgerWrite, got %v", err)
+	}
+	if _, _, err := m.CancelBet("u1", bet.ID); !errors.Is(err, ErrLedgerWrite) {
+		t.Errorf("expected the cancel refused with ErrLedgerWrite, got %v", err)
+	}
+	if _, err := m.AdjustBalance("u1", 500, "bonus"); !errors.Is(err, ErrLedgerWrite) {
+		t.Errorf("expected the adjustment refused with ErrLedgerWrite, got %v", err)
+	}
+	if _, err := m.JoinUser("u2"); !errors.Is(err, ErrLedgerWrite) || m.GetUser("u2") != nil {
+		t.Errorf("expected u2 not registered, got %v", err)
+	}
+
+	if bets := m.session.Bets; len(bets) != 1 || bets[0].ID != bet.ID {
+		t.Errorf("expected only the first bet on the table, got %+v", bets)
+	}
+	if staked := m.session.stakes["u1"]; staked != 100 {
+		t.Errorf("expected the refused bet's stake given back, got %d staked", staked)
+	}
+	if rec, _, _ := m.store.GetUser("u1"); rec.Balance != StartingBalance-100 {
+		t.Errorf("expected the stored balance %d, got %d", StartingBalance-100, rec.Balance)
+	}
+	ledger.fail.Store(false)
+	checkLedger(t, m, "u1")
+}
+
+// gatedLedger is a memory ledger that holds up userID's transactions of
+// kind until gate is closed.
+type gatedLedger struct {
+	*MemoryLedger
+	userID string
+	kind   messages.TransactionKind
+	gate   chan struct{}
+}
+
+func (l *gatedLedger) AppendTransactions(txs []messages.Transaction) error {
+	if txs[0].UserID == l.userID && txs[0].Kind == l.kind {
+		<-l.gate
+	}
+	return l.MemoryLedger.AppendTransactions(txs)
+}
+
+func TestLedger_SlowBetWriteDoesNotHoldUpTable(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	ledger := &gatedLedger{MemoryLedger: NewMemoryLedger(), userID: "u1", kind: messages.TransactionBet, gate: make(chan struct{})}
+	m.SetLedger(ledger)
+	m.SetLimits(TableLimits{MaxPlayerStake: 150})
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.runBettingPhase()
+
+	done := make(chan error)
+	go func() {
+		_, _, err := m.PlaceBet("u1", "color", "red", 100)
+		done <- err
+	}()
+	// Wait for u1's bet to reach the ledger with its stake reserved.
+	for {
+		m.session.mu.Lock()
+		staked := m.session.stakes["u1"]
+		m.session.mu.Unlock()
+		if staked == 100 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, _, err := m.PlaceBet("u2", "color", "red", 100); err != nil {
+		t.Fatalf("expected u2's bet placed while u1's is written, got %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "black", 100); !errors.Is(err, ErrPlayerStakeLimit) {
+		t.Errorf("expected u1's reserved stake counted, got %v", err)
+	}
+	close(ledger.gate)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.session.Bets) != 2 {
+		t.Errorf("expected both bets on the table, got %+v", m.session.Bets)
+	}
+	checkLedger(t, m, "u1")
+}
+
+func TestLedger_SlowRefundWriteDoesNotHoldUpTable(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	ledger := &gatedLedger{MemoryLedger: NewMemoryLedger(), userID: "u1", kind: messages.TransactionRefund, gate: make(chan struct{})}
+	m.SetLedger(ledger)
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.runBettingPhase()
+	bet, _, err := m.PlaceBet("u1", "color", "red", 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, _, err := m.CancelBet("u1", bet.ID)
+		done <- err
+	}()
+	// Wait for u1's bet to come off the table while its refund is written.
+	for {
+		m.session.mu.Lock()
+		n := len(m.session.Bets)
+		m.session.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, _, err := m.PlaceBet("u2", "color", "red", 100); err != nil {
+		t.Fatalf("expected u2's bet placed while u1's refund is written, got %v", err)
+	}
+	if _, _, err := m.CancelBet("u1", bet.ID); !errors.Is(err, ErrBetNotFound) {
+		t.Errorf("expected the bet being cancelled not found again, got %v", err)
+	}
+	close(ledger.gate)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(m.session.Bets) != 1 || m.session.Bets[0].UserID != "u2" {
+		t.Errorf("expected only u2's bet on the table, got %+v", m.session.Bets)
+	}
+	checkLedger(t, m, "u1")
+}
+
+func TestLedger_RetriesFailedSettlement(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	clock := heldClock{after: make(chan time.Time)}
+	m.SetClock(clock)
+	ledger := &failingLedger{MemoryLedger: NewMemoryLedger()}
+	m.SetLedger(ledger)
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ledger.fail.Store(true)
+	done := make(chan struct{})
+	go func() {
+		settleRound(m, 1) // 1 is red
+		close(done)
+	}()
+	// Let a retry fail too.
+	clock.after <- time.Now()
+	if s, _, _ := m.settlements.GetSettlement(); s.State != SettlementSettling {
+		t.Errorf("expected the round still settling, got %s", s.State)
+	}
+	ledger.fail.Store(false)
+	clock.after <- time.Now() // the retry, then the result phase
+	clock.after <- time.Now()
+	<-done
+
+	if s, _, _ := m.settlements.GetSettlement(); s.State != SettlementSettled {
+		t.Errorf("expected the round settled, got %s", s.State)
+	}
+	if balance := m.GetUser("u1").Balance; balance != StartingBalance+100 {
+		t.Errorf("expected the win credited once, got balance %d", balance)
+	}
+	checkLedger(t, m, "u1")
+}
+
+func TestBoltStore_PostSavesUsersWithTransactions(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "roulette.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	txs := []messages.Transaction{{UserID: "u1", RoundID: 3, Debit: HouseAccount, Credit: "u1", Amount: 250}}
+	if err := store.Post(txs, []UserRecord{{ID: "u1", Balance: 250}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rec, ok, err := store.GetUser("u1")
+	if err != nil || !ok || rec.Balance != 250 {
+		t.Errorf("expected u1 saved with 250, got %+v, %v, %v", rec, ok, err)
+	}
+	if sum, err := store.LedgerBalance("u1"); err != nil || sum != 250 {
+		t.Errorf("expected the ledger to sum to 250, got %d, %v", sum, err)
+	}
+	if got, err := store.RoundTransactions(3); err != nil || len(got) != 1 || got[0].ID == 0 {
+		t.Errorf("expected the transaction indexed by round with an ID, got %+v, %v", got, err)
+	}
+}
+
+func TestBoltStore_Ledger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roulette.db")
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetUserStore(store)
+	m.SetLedger(store)
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	for i := range int64(3) {
+		if _, err := m.AdjustBalance("u1", i+1, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	checkLedger(t, m, "u1")
+	store.Close()
+
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if sum, err := store.LedgerBalance("u1"); err != nil || sum != StartingBalance+6 {
+		t.Errorf("expected %d after reopening, got %d, %v", StartingBalance+6, sum, err)
+	}
+
+	// A database from before the balances were kept sums them on opening.
+	store.db.Update(func(tx *bolt.Tx) error { return tx.DeleteBucket(ledgerBalancesBucket) })
+	store.Close()
+	store, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if sum, err := store.LedgerBalance("u1"); err != nil || sum != StartingBalance+6 {
+		t.Errorf("expected %d summed on opening, got %d, %v", StartingBalance+6, sum, err)
+	}
+
+	// IDs are global: u1 has 1, 3, 4 and 5; u2 has 2.
+	ids := func(txs []messages.Transaction) []int64 {
+		var ids []int64
+		for _, tx := range txs {
+			ids = append(ids, tx.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		userID string
+		before int64
+		limit  int
+		want   []int64
+	}{
+		{"u1", 0, 2, []int64{5, 4}},
+		{"u1", 4, 10, []int64{3, 1}},
+		{"u2", 0, 10, []int64{2}},
+		{"nobody", 0, 10, nil},
+	}
+	for _, tt := range tests {
+		txs, err := store.Transactions(tt.userID, tt.before, tt.limit)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := ids(txs); !slices.Equal(got, tt.want) {
+			t.Errorf("Transactions(%q, %d, %d) = %v, want %v", tt.userID, tt.before, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryLedger_KeepsRecentRounds(t *testing.T) {
+	l := NewMemoryLedger()
+	for round := int64(1); round <= memoryRounds+1; round++ {
+		err := l.AppendTransactions([]messages.Transaction{
+			{UserID: "u1", RoundID: round, Debit: "u1", Credit: HouseAccount, Amount: 10},
+			{UserID: "u2", RoundID: round, Debit: HouseAccount, Credit: "u2", Amount: 5},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if txs, _ := l.RoundTransactions(1); len(txs) != 0 {
+		t.Errorf("expected round 1 dropped from the index, got %d transactions", len(txs))
+	}
+	txs, _ := l.RoundTransactions(memoryRounds + 1)
+	if len(txs) != 2 || txs[0].UserID != "u1" || txs[1].UserID != "u2" || txs[0].ID >= txs[1].ID {
+		t.Errorf("expected the last round's two transactions in order, got %+v", txs)
+	}
+	if txs, _ := l.Transactions("u1", 0, 2*memoryTransactions); len(txs) != memoryTransactions {
+		t.Errorf("expected %d of u1's transactions kept, got %d", memoryTransactions, len(txs))
+	}
+	if sum, _ := l.LedgerBalance("u1"); sum != -10*(memoryRounds+1) {
+		t.Errorf("expected the balance to count dropped transactions, got %d", sum)
+	}
+}
+
+// --- Settlement tests ---
+
+// openBoltManager opens the database at path and gives a manager every store in it.
+func openBoltManager(t *testing.T, path string) (*Manager, *BoltStore) {
+	t.Helper()
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	m.SetClock(instantClock{})
+	m.SetUserStore(store)
+	m.SetRoundStore(store)
+	m.SetLedger(store)
+	m.SetSettlementStore(store)
+	return m, store
+}
+
+// postCounter keeps users and the ledger in memory, like a Poster store,
+// and counts the writes.
+type postCounter struct {
+	*MemoryLedger
+	*MemoryUserStore
+	posts atomic.Int64
+}
+
+func (c *postCounter) Post(txs []messages.Transaction, users []UserRecord) error {
+	c.posts.Add(1)
+	if err := c.AppendTransactions(txs); err != nil {
+		return err
+	}
+	for _, rec := range users {
+		if err := c.PutUser(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSettlement_CreditsRoundInOneWrite(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	store := &postCounter{MemoryLedger: NewMemoryLedger(), MemoryUserStore: NewMemoryUserStore()}
+	m.SetUserStore(store)
+	m.SetLedger(store)
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.runBettingPhase()
+	for _, userID := range []string{"u1", "u2"} {
+		if _, _, err := m.PlaceBet(userID, "color", "red", 100); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if n := store.posts.Load(); n != 4 {
+		t.Fatalf("expected a write per registration and bet, got %d", n)
+	}
+
+	settleRound(m, 1) // 1 is red
+	if n := store.posts.Load(); n != 5 {
+		t.Errorf("expected both wins in one write, got %d writes", n-4)
+	}
+	for _, userID := range []string{"u1", "u2"} {
+		checkLedger(t, m, userID)
+		if rec, _, _ := store.GetUser(userID); rec.Balance != StartingBalance+100 {
+			t.Errorf("expected %s saved with the win, got %d", userID, rec.Balance)
+		}
+	}
+}
+
+func TestRecoverSettlement_RefundsOpenRound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roulette.db")
+	m, store := openBoltManager(t, path)
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u1", "color", "red", 50); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.UndoLastBet("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The server goes down mid-round.
+	m.Stop()
+	store.Close()
+
+	m, store = openBoltManager(t, path)
+	t.Cleanup(func() { m.Stop(); store.Close() })
+	if err := m.RecoverSettlement(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec, _, _ := store.GetUser("u1"); rec.Balance != StartingBalance {
+		t.Errorf("expected the stake refunded once, got balance %d", rec.Balance)
+	}
+	txs, _ := store.Transactions("u1", 0, 1)
+	if txs[0].Kind != messages.TransactionRefund || txs[0].Amount != 100 || txs[0].Note != restartReason {
+		t.Errorf("expected a refund of the open bet, got %+v", txs[0])
+	}
+	if s, _, _ := store.GetSettlement(); s.State != SettlementSettled {
+		t.Errorf("expected the round settled, got %q", s.State)
+	}
+	if r, ok, _ := store.GetRound(1); !ok || !r.Voided || r.VoidReason != restartReason {
+		t.Errorf("expected round 1 recorded as voided, got %+v", r)
+	}
+
+	m.runBettingPhase()
+	if m.session.round != 2 {
+		t.Errorf("expected the next round to be 2, got %d", m.session.round)
+	}
+}
+
+func TestRecoverSettlement_FinishesSettlingRound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "roulette.db")
+	m, store := openBoltManager(t, path)
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+	m.runBettingPhase()
+	b1, _, _ := m.PlaceBet("u1", "straight", "17", 100)
+	b2, _, _ := m.PlaceBet("u2", "color", "red", 100)
+	session := m.session
+	s := Settlement{
+		Round:     session.round,
+		State:     SettlementSettling,
+		StartedAt: session.startedAt,
+		Credits: []Credit{
+			{UserID: "u1", BetID: b1.ID, Kind: messages.TransactionWin, Amount: 3600},
+			{UserID: "u2", BetID: b2.ID, Kind: messages.TransactionWin, Amount: 200},
+		},
+	}
+	if err := store.PutSettlement(s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// u1's credit reaches the ledger, then the server goes down before
+	// saving u1 or crediting u2.
+	stale, _, _ := store.GetUser("u1")
+	m.credit(session.round, []string{"u1"}, map[string][]posting{
+		"u1": {{kind: messages.TransactionWin, amount: 3600, betID: b1.ID}},
+	})
+	store.PutUser(stale)
+	m.Stop()
+	store.Close()
+
+	m, store = openBoltManager(t, path)
+	t.Cleanup(func() { m.Stop(); store.Close() })
+	if err := m.RecoverSettlement(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]int64{"u1": StartingBalance + 3500, "u2": StartingBalance + 100}
+	for userID, balance := range want {
+		if rec, _, _ := store.GetUser(userID); rec.Balance != balance {
+			t.Errorf("expected %s to have %d, got %d", userID, balance, rec.Balance)
+		}
+		if sum, _ := store.LedgerBalance(userID); sum != balance {
+			t.Errorf("expected %s's ledger to sum to %d, got %d", userID, balance, sum)
+		}
+	}
+	if s, _, _ := store.GetSettlement(); s.State != SettlementSettled {
+		t.Errorf("expected the round settled, got %q", s.State)
+	}
+}
+
+func TestSettle_CreditsEachBetOnce(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17))
+	m.RegisterUser("u1")
+	m.runBettingPhase()
+	if _, _, err := m.PlaceBet("u1", "straight", "17", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.runSpinningPhase()
+	m.runResultPhase()
+
+	s, ok, _ := m.settlements.GetSettlement()
+	if !ok || s.State != SettlementSettled || len(s.Credits) != 1 {
+		t.Fatalf("expected a settled round with one credit, got %+v", s)
+	}
+	if err := m.settle(s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := m.GetUser("u1").Balance, int64(StartingBalance+3500); got != want {
+		t.Errorf("expected balance %d after settling twice, got %d", want, got)
+	}
+	checkLedger(t, m, "u1")
+}
+
+func TestRecoverSettlement_RestoresCarriedBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	carried := []Bet{{ID: "b1", UserID: "u1", Type: "color", Value: "red", Amount: 100}}
+	m.settlements.PutSettlement(Settlement{Round: 7, State: SettlementSettled, Carried: carried})
+
+	if err := m.RecoverSettlement(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.round != 7 || len(m.imprisoned) != 1 || m.imprisoned[0].ID != "b1" {
+		t.Errorf("expected round 7 with b1 held En Prison, got %d and %+v", m.round, m.imprisoned)
+	}
+}
+
+// --- Event log tests ---
+
+func TestEvents_ReplayRebuildsState(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17, 4))
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	m.runBettingPhase()
+	m.PlaceBet("u1", "straight", "17", 100)
+	m.PlaceBet("u1", "color", "black", 50)
+	m.UndoLastBet("u1")
+	m.PlaceBet("u2", "straight", "17", StartingBalance)
+	m.runSpinningPhase()
+	m.runResultPhase()
+	m.runBettingPhase()
+	m.PlaceBet("u1", "color", "red", 200)
+	m.runSpinningPhase()
+	m.runResultPhase()
+	m.AdjustBalance("u1", -300, "")
+
+	events, _ := m.Events(0, 1000)
+	for i, e := range events {
+		if e.Seq != int64(i+1) {
+			t.Fatalf("expected event %d to have Seq %d, got %+v", i, i+1, e)
+		}
+	}
+	got := Replay(messages.TableState{}, events)
+	if want := m.TableState(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected replay to give %+v, got %+v", want, got)
+	}
+	for _, userID := range []string{"u1", "u2"} {
+		if got, want := got.Balances[userID], m.GetUser(userID).Balance; got != want {
+			t.Errorf("expected %s's replayed balance %d, got %d", userID, want, got)
+		}
+	}
+	if got.Round != 2 || *got.WinningNumber != 4 || len(got.Bets) != 1 {
+		t.Errorf("expected round 2 landing on 4 with one bet, got %+v", got)
+	}
+}
+
+func TestEvents_ReplayRound(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetRNG(NewScriptedRNG(17, 4))
+	m.RegisterUser("u1")
+	for _, value := range []string{"17", "4"} {
+		m.runBettingPhase()
+		m.PlaceBet("u1", "straight", value, 100)
+		m.runSpinningPhase()
+		m.runResultPhase()
+	}
+
+	replay, ok, err := m.ReplayRound(2)
+	if err != nil || !ok {
+		t.Fatalf("expected round 2, got ok=%v err=%v", ok, err)
+	}
+	if replay.Start.Round != 1 || replay.Start.Balances["u1"] != StartingBalance+3500 {
+		t.Errorf("expected to start after round 1's win, got %+v", replay.Start)
+	}
+	var types []messages.EventType
+	for _, e := range replay.Events {
+		types = append(types, e.Type)
+	}
+	want := []messages.EventType{
+		messages.EventPhaseChanged, messages.EventBetPlaced,
+		messages.EventPhaseChanged, messages.EventWheelSpun,
+		messages.EventPhaseChanged, messages.EventPayoutCredited,
+	}
+	if !slices.Equal(types, want) {
+		t.Errorf("expected %v, got %v", want, types)
+	}
+	if replay.End.Round != 2 || *replay.End.WinningNumber != 4 || replay.End.Balances["u1"] != StartingBalance+7000 {
+		t.Errorf("expected round 2 to end on 4 with the second win, got %+v", replay.End)
+	}
+	if _, ok, _ := m.ReplayRound(3); ok {
+		t.Error("expected no replay of a round not played")
+	}
+}
+
+func TestEvents_RebuildFromSnapshot(t *testing.T) {
+	log := NewMemoryEventLog()
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetEventLog(log)
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	// A snapshot that disagrees with the events shows the rebuild started
+	// from it rather than from the first event.
+	snap := m.TableState()
+	snap.Balances["u1"] = 1
+	log.PutSnapshot(snap)
+	m.RegisterUser("u3")
+
+	rebuilt := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { rebuilt.Stop() })
+	rebuilt.SetEventLog(log)
+	got, err := rebuilt.stateAt(6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]int64{"u1": 1, "u2": StartingBalance, "u3": StartingBalance}
+	if got.Seq != 6 || !maps.Equal(got.Balances, want) {
+		t.Errorf("expected %v after event 6, got %+v", want, got)
+	}
+
+	// No one is seated after the restart.
+	if got := rebuilt.TableState(); got.Seq != 9 || len(got.Balances) != 0 {
+		t.Errorf("expected the three users to have left by event 9, got %+v", got)
+	}
+
+	// New events carry on from the rebuilt sequence.
+	rebuilt.RegisterUser("u4")
+	if events, _ := log.Events(9, 10); len(events) != 2 || events[0].Seq != 10 {
+		t.Errorf("expected events 10 and 11 next, got %+v", events)
+	}
+}
+
+func TestEvents_BalancesFollowUsersAtTable(t *testing.T) {
+	from := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { from.Stop() })
+	to := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { to.Stop() })
+	to.SetUserStore(from.store)
+	to.SetLedger(from.ledger)
+
+	from.RegisterUser("u1")
+	if _, err := from.AdjustBalance("u1", 250, "bonus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := from.ReleaseUser("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A payout after they left goes to the store, not the old table's state.
+	err := from.credit(0, []string{"u1"}, map[string][]posting{
+		"u1": {{kind: messages.TransactionWin, amount: 50, betID: "b1"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if txs, _ := from.Transactions("u1", 0, 1); len(txs) != 1 || txs[0].BalanceBefore != StartingBalance+250 || txs[0].BalanceAfter != StartingBalance+300 {
+		t.Errorf("expected the payout added to the stored balance, got %+v", txs)
+	}
+	if _, err := to.AdmitUser("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := from.TableState().Balances; len(got) != 0 {
+		t.Errorf("expected no one at the old table, got %v", got)
+	}
+	want := map[string]int64{"u1": StartingBalance + 300}
+	if got := to.TableState().Balances; !maps.Equal(got, want) {
+		t.Errorf("expected %v at the new table, got %v", want, got)
+	}
+	events, _ := to.Events(0, 100)
+	if got := Replay(messages.TableState{}, events).Balances; !maps.Equal(got, want) {
+		t.Errorf("expected the replay to give %v, got %v", want, got)
+	}
+}
+
+func TestMemoryEventLog_KeepsRecentRounds(t *testing.T) {
+	l := NewMemoryEventLog()
+	var seq int64
+	for round := int64(1); round <= memoryRounds+20; round++ {
+		events := make([]messages.Event, 3)
+		for i := range events {
+			seq++
+			events[i] = messages.Event{Seq: seq, Type: messages.EventPhaseChanged, Round: round}
+		}
+		if err := l.AppendEvents(events); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if round%10 == 0 {
+			l.PutSnapshot(messages.TableState{Seq: seq, Round: round})
+		}
+	}
+
+	if events, _ := l.RoundEvents(20); len(events) != 0 {
+		t.Errorf("expected round 20 dropped, got %d events", len(events))
+	}
+	events, _ := l.RoundEvents(21)
+	if len(events) != 3 || events[0].Seq != 61 {
+		t.Errorf("expected round 21's 3 events from Seq 61, got %+v", events)
+	}
+	// Round 21 rebuilds from the snapshot after round 20.
+	if s, ok, _ := l.Snapshot(61); !ok || s.Seq != 60 {
+		t.Errorf("expected the snapshot at Seq 60 kept, got %+v, %v", s, ok)
+	}
+	if s, ok, _ := l.Snapshot(60); ok {
+		t.Errorf("expected older snapshots dropped, got %+v", s)
+	}
+	if events, _ := l.Events(0, 1); len(events) != 1 || events[0].Seq != 61 {
+		t.Errorf("expected the log to start after the snapshot, got %+v", events)
+	}
+}
+
+func TestBoltStore_Events(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "roulette.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	var events []messages.Event
+	for seq := range int64(6) {
+		events = append(events, messages.Event{Seq: seq + 1, Type: messages.EventPhaseChanged, Round: seq/2 + 1})
+	}
+	if err := store.AppendEvents(events); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, seq := range []int64{2, 4} {
+		if err := store.PutSnapshot(messages.TableState{Seq: seq}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	seqs := func(events []messages.Event) []int64 {
+		var seqs []int64
+		for _, e := range events {
+			seqs = append(seqs, e.Seq)
+		}
+		return seqs
+	}
+	if got, _ := store.Events(0, 2); !slices.Equal(seqs(got), []int64{1, 2}) {
+		t.Errorf("expected events 1 and 2, got %v", seqs(got))
+	}
+	if got, _ := store.Events(4, 10); !slices.Equal(seqs(got), []int64{5, 6}) {
+		t.Errorf("expected events 5 and 6, got %v", seqs(got))
+	}
+	if got, _ := store.RoundEvents(2); !slices.Equal(seqs(got), []int64{3, 4}) {
+		t.Errorf("expected round 2 to have events 3 and 4, got %v", seqs(got))
+	}
+
+	tests := []struct {
+		before int64
+		want   int64
+		ok     bool
+	}{
+		{0, 4, true},
+		{4, 2, true},
+		{5, 4, true},
+		{2, 0, false},
+	}
+	for _, tt := range tests {
+		s, ok, err := store.Snapshot(tt.before)
+		if err != nil || ok != tt.ok || s.Seq != tt.want {
+			t.Errorf("Snapshot(%d) = %d, %v, %v; want %d, %v", tt.before, s.Seq, ok, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// --- Table registry tests ---
+
+// newTables registers tables with the given IDs, sharing one user store
+// and ledger as the server's tables do.
+func newTables(t *testing.T, ids ...string) *TableRegistry {
+	t.Helper()
+	users, ledger := NewMemoryUserStore(), NewMemoryLedger()
+	r := NewTableRegistry()
+	for _, id := range ids {
+		m := NewManager(func([]byte) {}, func(string, []byte) {})
+		t.Cleanup(func() { m.Stop() })
+		m.SetClock(instantClock{})
+		m.SetUserStore(users)
+		m.SetLedger(ledger)
+		if _, err := r.Add(id, id, m); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return r
+}
+
+func TestTableRegistry_Lookup(t *testing.T) {
+	r := newTables(t, "main", "high")
+	if _, err := r.Add("high", "High", NewManager(nil, nil)); !errors.Is(err, ErrDuplicateTable) {
+		t.Errorf("expected ErrDuplicateTable, got %v", err)
+	}
+	if tbl, err := r.Lookup(""); err != nil || tbl.ID != "main" {
+		t.Errorf("expected the first table by default, got %v, %v", tbl, err)
+	}
+	if tbl, err := r.Lookup("high"); err != nil || tbl.Manager.TableID() != "high" {
+		t.Errorf("expected table high, got %v, %v", tbl, err)
+	}
+	if _, err := r.Lookup("nope"); !errors.Is(err, ErrUnknownTable) {
+		t.Errorf("expected ErrUnknownTable, got %v", err)
+	}
+
+	high, _ := r.Get("high")
+	high.Manager.RegisterUser("u1")
+	if tbl := r.Locate("u1"); tbl != high {
+		t.Errorf("expected u1 at table high, got %v", tbl)
+	}
+	infos := r.Infos()
+	if len(infos) != 2 || infos[0].ID != "main" || infos[0].Players != 0 || infos[1].Players != 1 {
+		t.Errorf("expected main empty and high with one player, got %+v", infos)
+	}
+}
+
+func TestTableRegistry_MoveKeepsBalance(t *testing.T) {
+	r := newTables(t, "main", "high")
+	main, _ := r.Get("main")
+	high, _ := r.Get("high")
+	main.Manager.RegisterUser("u1")
+	main.Manager.runBettingPhase()
+	if _, _, err := main.Manager.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := r.Move("u1", main, high); !errors.Is(err, ErrBetsInPlay) {
+		t.Fatalf("expected ErrBetsInPlay with a bet on the table, got %v", err)
+	}
+	if r.Locate("u1") != main {
+		t.Fatal("expected u1 to stay at main")
+	}
+
+	main.Manager.ClearBets("u1")
+	main.Manager.AdjustBalance("u1", -100, "")
+	if err := r.Move("u1", main, high); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if main.Manager.GetUser("u1") != nil {
+		t.Error("expected u1 gone from main")
+	}
+	user := high.Manager.GetUser("u1")
+	if user == nil || user.Balance != StartingBalance-100 {
+		t.Fatalf("expected u1 at high with %d, got %+v", StartingBalance-100, user)
+	}
+
+	high.Manager.runBettingPhase()
+	high.Manager.PlaceBet("u1", "straight", "17", 100)
+	txs := checkLedger(t, high.Manager, "u1")
+	if last := txs[len(txs)-2:]; last[0].TableID != "main" || last[1].TableID != "high" {
+		t.Errorf("expected the adjustment at main, then the bet at high, got %+v", last)
+	}
+
+	if err := r.Move("nobody", nil, main); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestTableRegistry_MoveWhileSourceFills(t *testing.T) {
+	r := newTables(t, "main", "high")
+	main, _ := r.Get("main")
+	high, _ := r.Get("high")
+	main.Manager.SetMaxSeats(1)
+	high.Manager.SetMaxSeats(1)
+	main.Manager.RegisterUser("u1")
+	high.Manager.RegisterUser("u2")
+	high.Manager.ReleaseUser("u2")
+
+	// Move's steps, with u2 taking u1's seat at main between them.
+	if err := high.Manager.ReserveSeat("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := high.Manager.AdmitUser("u2"); !errors.Is(err, ErrTableFull) {
+		t.Fatalf("expected the held seat to be taken, got %v", err)
+	}
+	if err := main.Manager.ReleaseUser("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := main.Manager.AdmitUser("u2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.finishMove("u1", main, high); err != nil {
+		t.Fatalf("expected u1 seated in the held seat, got %v", err)
+	}
+	if r.Locate("u1") != high || r.Locate("u2") != main {
+		t.Fatalf("expected u1 at high and u2 at main, got %v and %v", r.Locate("u1"), r.Locate("u2"))
+	}
+
+	// A full target refuses before u2 gives up their seat.
+	if err := r.Move("u2", main, high); !errors.Is(err, ErrTableFull) {
+		t.Fatalf("expected ErrTableFull, got %v", err)
+	}
+	if r.Locate("u2") != main {
+		t.Fatal("expected u2 to stay at main")
+	}
+
+	// The target closing while main fills leaves u1 at no table, which
+	// the error says.
+	high.Manager.ReleaseUser("u1")
+	main.Manager.ReleaseUser("u2")
+	main.Manager.AdmitUser("u1")
+	high.Manager.ReserveSeat("u1")
+	main.Manager.ReleaseUser("u1")
+	main.Manager.AdmitUser("u2")
+	high.Manager.Close(true)
+	err := r.finishMove("u1", main, high)
+	if !errors.Is(err, ErrNotSeated) || !errors.Is(err, ErrTableClosed) || !errors.Is(err, ErrTableFull) {
+		t.Fatalf("expected ErrNotSeated wrapping why, got %v", err)
+	}
+	if r.Locate("u1") != nil {
+		t.Errorf("expected u1 at no table, at %v", r.Locate("u1"))
+	}
+	if rec, ok, _ := main.Manager.store.GetUser("u1"); !ok || rec.Balance != StartingBalance {
+		t.Errorf("expected u1 kept in the store with their balance, got %+v", rec)
+	}
+}
+
+func TestRecoverSettlement_RefundsOwnTableOnly(t *testing.T) {
+	r := newTables(t, "main", "high")
+	main, _ := r.Get("main")
+	high, _ := r.Get("high")
+	main.Manager.RegisterUser("u1")
+	main.Manager.runBettingPhase()
+	main.Manager.PlaceBet("u1", "color", "red", 100)
+	main.Manager.ClearBets("u1")
+	r.Move("u1", main, high)
+	high.Manager.runBettingPhase()
+	high.Manager.PlaceBet("u1", "color", "red", 100)
+
+	// Both tables opened round 1; only main goes down with it open.
+	if err := main.Manager.RecoverSettlement(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec, _, _ := main.Manager.store.GetUser("u1"); rec.Balance != StartingBalance-100 {
+		t.Errorf("expected only the bet at high still staked, got balance %d", rec.Balance)
+	}
+}
+
+func TestBoltStore_TableViews(t *testing.T) {
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "roulette.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	high, err := store.Table("high")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if main, _ := store.Table(DefaultTableID); main != store {
+		t.Error("expected the default table to use the store itself")
+	}
+
+	store.PutRound(messages.Round{ID: 1, Variant: messages.WheelVariantEuropean})
+	high.PutRound(messages.Round{ID: 1, Variant: messages.WheelVariantAmerican})
+	high.PutSettlement(Settlement{Round: 1, State: SettlementOpen})
+	high.AppendEvents([]messages.Event{{Seq: 1, Type: messages.EventPhaseChanged, Round: 1}})
+	store.PutUser(UserRecord{ID: "u1", Balance: 5})
+
+	if r, _, _ := store.GetRound(1); r.Variant != messages.WheelVariantEuropean {
+		t.Errorf("expected the default table's round, got %+v", r)
+	}
+	if r, _, _ := high.GetRound(1); r.Variant != messages.WheelVariantAmerican {
+		t.Errorf("expected high's round, got %+v", r)
+	}
+	if _, ok, _ := store.GetSettlement(); ok {
+		t.Error("expected no settlement at the default table")
+	}
+	if events, _ := store.Events(0, 10); len(events) != 0 {
+		t.Errorf("expected no events at the default table, got %+v", events)
+	}
+	if rec, ok, _ := high.GetUser("u1"); !ok || rec.Balance != 5 {
+		t.Errorf("expected tables to share users, got %+v", rec)
+	}
+}
+
+// --- Private table tests ---
+
+// newPrivateTables gives a registry with one public table, main, and a
+// factory for private tables that share its users and ledger.
+func newPrivateTables(t *testing.T) *TableRegistry {
+	t.Helper()
+	r := newTables(t, "main")
+	main, _ := r.Get("main")
+	r.SetTableFactory(func(id string) (*Manager, error) {
+		m := NewManager(func([]byte) {}, func(string, []byte) {})
+		m.SetUserStore(main.Manager.store)
+		m.SetLedger(main.Manager.ledger)
+		t.Cleanup(func() {
+			// Closing a table stops it.
+			if _, ok := r.Get(id); ok {
+				m.Stop()
+			}
+		})
+		return m, nil
+	})
+	return r
+}
+
+func TestPrivateTable_Enter(t *testing.T) {
+	r := newPrivateTables(t)
+	table, err := r.CreatePrivate("owner", "", messages.TableSettings{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(table.InviteCode()) != inviteCodeLength || table.Name != defaultPrivateName {
+		t.Errorf("expected an invite code and the default name, got %q and %q", table.InviteCode(), table.Name)
+	}
+	if infos := r.Infos(); len(infos) != 1 || infos[0].ID != "main" {
+		t.Errorf("expected the lobby to list only main, got %+v", infos)
+	}
+
+	tests := []struct {
+		name               string
+		id, code, password string
+		userID             string
+		want               error
+	}{
+		{"by ID", table.ID, "", "hunter2", "u1", ErrInviteRequired},
+		{"wrong code", "", "NOTACODE", "hunter2", "u1", ErrUnknownTable},
+		{"wrong password", "", table.InviteCode(), "hunter3", "u1", ErrWrongPassword},
+		{"code and password", "", table.InviteCode(), "hunter2", "u1", nil},
+		{"owner by ID", table.ID, "", "", "owner", nil},
+	}
+	for _, tt := range tests {
+		got, err := r.Enter(tt.id, tt.code, tt.password, tt.userID)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if tt.want == nil && got != table {
+			t.Errorf("%s: expected the private table, got %v", tt.name, got)
+		}
+	}
+
+	if _, err := r.CreatePrivate("owner", "", messages.TableSettings{MaxSeats: -1}); !errors.Is(err, ErrInvalidSettings) {
+		t.Errorf("expected ErrInvalidSettings, got %v", err)
+	}
+}
+
+func TestPrivateTable_MaxSeats(t *testing.T) {
+	r := newPrivateTables(t)
+	main, _ := r.Get("main")
+	for _, id := range []string{"owner", "u1", "u2"} {
+		main.Manager.RegisterUser(id)
+	}
+	table, _ := r.CreatePrivate("owner", "Friends", messages.TableSettings{MaxSeats: 2})
+	for _, id := range []string{"owner", "u1"} {
+		if err := r.Move(id, main, table); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := r.Move("u2", main, table); !errors.Is(err, ErrTableFull) {
+		t.Fatalf("expected ErrTableFull, got %v", err)
+	}
+	if r.Locate("u2") != main {
+		t.Error("expected u2 to stay at main")
+	}
+	if _, err := table.Manager.JoinUser("u3"); !errors.Is(err, ErrTableFull) {
+		t.Errorf("expected a new player turned away too, got %v", err)
+	}
+
+	if err := table.UpdateSettings("u1", messages.TableSettings{MaxSeats: 3}); !errors.Is(err, ErrNotTableOwner) {
+		t.Errorf("expected ErrNotTableOwner, got %v", err)
+	}
+	if err := table.UpdateSettings("owner", messages.TableSettings{MaxSeats: 3, BettingSeconds: 5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Move("u2", main, table); err != nil {
+		t.Errorf("expected a seat after raising the limit, got %v", err)
+	}
+	if got := table.Manager.phaseDurations(); got.Betting != 5*time.Second || got.Result != ResultDuration {
+		t.Errorf("expected 5s betting and the default result phase, got %+v", got)
+	}
+}
+
+func TestPrivateTable_Kick(t *testing.T) {
+	r := newPrivateTables(t)
+	main, _ := r.Get("main")
+	main.Manager.RegisterUser("owner")
+	main.Manager.RegisterUser("u1")
+	table, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+	r.Move("owner", main, table)
+	r.Move("u1", main, table)
+
+	if err := table.Kick("u1", "owner"); !errors.Is(err, ErrNotTableOwner) {
+		t.Errorf("expected ErrNotTableOwner, got %v", err)
+	}
+	if err := table.Kick("owner", "owner"); !errors.Is(err, ErrKickOwner) {
+		t.Errorf("expected ErrKickOwner, got %v", err)
+	}
+	if err := table.Kick("owner", "u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table.Manager.GetUser("u1") != nil {
+		t.Error("expected u1 gone from the table")
+	}
+	if _, err := r.Enter("", table.InviteCode(), "", "u1"); !errors.Is(err, ErrKicked) {
+		t.Errorf("expected u1 kept out, got %v", err)
+	}
+	if err := r.Move("u1", nil, main); err != nil {
+		t.Errorf("expected u1 free to sit at main, got %v", err)
+	}
+}
+
+func TestPrivateTable_Close(t *testing.T) {
+	r := newPrivateTables(t)
+	main, _ := r.Get("main")
+	main.Manager.RegisterUser("owner")
+	main.Manager.RegisterUser("u1")
+	table, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+	r.Move("owner", main, table)
+	r.Move("u1", main, table)
+	// Let the game loop open the first round.
+	for table.Manager.TableState().Round == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, _, err := table.Manager.PlaceBet("u1", "color", "red", 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := r.Close(table, "u1"); !errors.Is(err, ErrNotTableOwner) {
+		t.Errorf("expected ErrNotTableOwner, got %v", err)
+	}
+	if _, err := r.Close(table, "owner"); !errors.Is(err, ErrBetsInPlay) {
+		t.Fatalf("expected ErrBetsInPlay, got %v", err)
+	}
+	table.Manager.ClearBets("u1")
+	ids, err := r.Close(table, "owner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(ids, []string{"owner", "u1"}) {
+		t.Errorf("expected owner and u1 released, got %v", ids)
+	}
+	if _, ok := r.Get(table.ID); ok {
+		t.Error("expected the table gone from the registry")
+	}
+	if _, err := r.Enter("", table.InviteCode(), "", "u1"); !errors.Is(err, ErrUnknownTable) {
+		t.Errorf("expected the invite code dead, got %v", err)
+	}
+	if _, err := table.Manager.AdmitUser("u1"); !errors.Is(err, ErrTableClosed) {
+		t.Errorf("expected ErrTableClosed, got %v", err)
+	}
+	if rec, _, _ := main.Manager.store.GetUser("u1"); rec.Balance != StartingBalance {
+		t.Errorf("expected u1's balance kept, got %d", rec.Balance)
+	}
+}
+
+func TestPrivateTable_Limits(t *testing.T) {
+	r := newPrivateTables(t)
+	r.SetPrivateTableLimits(3, 2)
+
+	first, err := r.CreatePrivate("owner", "", messages.TableSettings{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.CreatePrivate("owner", "", messages.TableSettings{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.CreatePrivate("owner", "", messages.TableSettings{}); !errors.Is(err, ErrTooManyTables) {
+		t.Errorf("expected the owner's third table refused with ErrTooManyTables, got %v", err)
+	}
+	if _, err := r.CreatePrivate("u1", "", messages.TableSettings{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.CreatePrivate("u2", "", messages.TableSettings{}); !errors.Is(err, ErrTooManyTables) {
+		t.Errorf("expected a fourth table refused with ErrTooManyTables, got %v", err)
+	}
+	if n := len(r.Tables()); n != 4 {
+		t.Errorf("expected main and 3 private tables, got %d tables", n)
+	}
+
+	// Closing a table frees its place.
+	if _, err := r.Close(first, "owner"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.CreatePrivate("owner", "", messages.TableSettings{}); err != nil {
+		t.Errorf("expected a table after closing one, got %v", err)
+	}
+}
+
+func TestPrivateTable_ReapsIdleTables(t *testing.T) {
+	r := newPrivateTables(t)
+	r.SetIdleTimeout(time.Minute)
+	main, _ := r.Get("main")
+	main.Manager.RegisterUser("owner")
+	busy, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+	r.Move("owner", main, busy)
+	idle, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+
+	now := time.Now()
+	r.reap(now)
+	r.reap(now.Add(59 * time.Second))
+	if _, ok := r.Get(idle.ID); !ok {
+		t.Fatal("expected the empty table kept until the timeout")
+	}
+	r.reap(now.Add(2 * time.Minute))
+	if _, ok := r.Get(idle.ID); ok {
+		t.Error("expected the empty table torn down")
+	}
+	if _, ok := r.Get(busy.ID); !ok {
+		t.Error("expected the table with a player kept")
+	}
+	if _, ok := r.Get("main"); !ok {
+		t.Error("expected public tables never torn down")
+	}
+}
+
+func TestRecoverClosedTable_RefundsCarriedBets(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.store.PutUser(UserRecord{ID: "u1", Balance: 900})
+	carried := []Bet{{ID: "b1", UserID: "u1", Type: "color", Value: "red", Amount: 100}}
+	m.settlements.PutSettlement(Settlement{Round: 7, State: SettlementSettled, Carried: carried})
+
+	for range 2 {
+		if err := m.RecoverClosedTable(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if rec, _, _ := m.store.GetUser("u1"); rec.Balance != 1000 {
+		t.Errorf("expected the held bet refunded once, got balance %d", rec.Balance)
+	}
+	if s, _, _ := m.settlements.GetSettlement(); s.State != SettlementSettled || len(s.Carried) != 0 {
+		t.Errorf("expected the table to hold nothing, got %+v", s)
+	}
+}
+
+// --- Spectator tests ---
+
+func TestSpectators_CountedNotSeated(t *testing.T) {
+	var mu sync.Mutex
+	var counts []int
+	m := NewManager(func(data []byte) {
+		var msg messages.SpectatorsMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "spectators" {
+			mu.Lock()
+			counts = append(counts, msg.Count)
+			mu.Unlock()
+		}
+	}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+
+	for range 3 {
+		m.AddSpectator()
+	}
+	m.RemoveSpectator()
+	if m.SpectatorCount() != 2 || m.PlayerCount() != 0 {
+		t.Fatalf("expected 2 spectators and no players, got %d and %d", m.SpectatorCount(), m.PlayerCount())
+	}
+	table := &Table{ID: "main", Manager: m}
+	if info := table.Info(); info.Spectators != 2 || info.Players != 0 {
+		t.Errorf("expected the lobby to show 2 spectators apart from players, got %+v", info)
+	}
+
+	m.runBettingPhase()
+	m.runBettingPhase()
+	m.AddSpectator()
+	m.runBettingPhase()
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(counts, []int{2, 3}) {
+		t.Errorf("expected the count sent only when it changed, got %v", counts)
+	}
+}
+
+func TestSpectators_SeeRoundResult(t *testing.T) {
+	var mu sync.Mutex
+	var seen []string
+	var result messages.RoundResultMessage
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetClock(instantClock{})
+	m.SetSpectatorBroadcast(func(data []byte) {
+		var msg messages.RoundResultMessage
+		json.Unmarshal(data, &msg)
+		mu.Lock()
+		seen = append(seen, msg.Type)
+		if msg.Type == "round_result" {
+			result = msg
+		}
+		mu.Unlock()
+	})
+	m.RegisterUser("u1")
+	m.RegisterUser("u2")
+
+	if _, _, err := m.PlaceBet("u1", "color", "red", 1000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := m.PlaceBet("u2", "color", "black", 500); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	settleRound(m, 1) // 1 is red
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(seen, []string{"round_result"}) {
+		t.Fatalf("expected spectators sent only the round result, got %v", seen)
+	}
+	if result.WinningNumber != 1 || result.WinningPocket != "1" || result.TotalWon != 2000 {
+		t.Errorf("expected 1 to win 2000 across the table, got %+v", result)
+	}
+}
+
+func TestTableRegistry_RemoveHook(t *testing.T) {
+	r := newPrivateTables(t)
+	var removed, closedIDs []string
+	r.SetRemoveHook(func(t *Table, reason string) {
+		removed = append(removed, t.ID+": "+reason)
+	})
+	r.SetClosedHook(func(id string) { closedIDs = append(closedIDs, id) })
+	main, _ := r.Get("main")
+	main.Manager.RegisterUser("owner")
+	closed, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+	idle, _ := r.CreatePrivate("owner", "", messages.TableSettings{})
+
+	if _, err := r.Close(closed, "owner"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	r.reap(now)
+	r.reap(now.Add(DefaultPrivateTableIdle))
+
+	want := []string{
+		closed.ID + ": table closed by its owner",
+		idle.ID + ": table closed after standing empty",
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("expected %v, got %v", want, removed)
+	}
+	if !slices.Equal(closedIDs, []string{closed.ID, idle.ID}) {
+		t.Errorf("expected the closed hook to get %v and %v, got %v", closed.ID, idle.ID, closedIDs)
+	}
+}
+
+// --- Seat queue tests ---
+
+func TestSeatQueue_SeatsInOrderAtRoundEnd(t *testing.T) {
+	var mu sync.Mutex
+	positions := make(map[string]int)
+	m := NewManager(func([]byte) {}, func(userID string, data []byte) {
+		var msg messages.QueuePositionMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "queue_position" {
+			mu.Lock()
+			positions[userID] = msg.Position
+			mu.Unlock()
+		}
+	})
+	t.Cleanup(func() { m.Stop() })
+	var seated []string
+	m.SetSeatedHook(func(userID string) { seated = append(seated, userID) })
+	m.SetMaxSeats(1)
+	m.RegisterUser("u1")
+
+	if _, err := m.JoinUser("u2"); !errors.Is(err, ErrTableFull) {
+		t.Fatalf("expected ErrTableFull, got %v", err)
+	}
+	for _, tt := range []struct {
+		userID string
+		want   int
+	}{{"u2", 1}, {"u3", 2}, {"u2", 1}} {
+		if got, err := m.Enqueue(tt.userID, "bob"); err != nil || got != tt.want {
+			t.Errorf("%s: expected place %d, got %d (%v)", tt.userID, tt.want, got, err)
+		}
+	}
+	if rec, ok, _ := m.store.GetUser("u2"); !ok || rec.Name != "bob#u2" || rec.Balance != StartingBalance {
+		t.Errorf("expected a new user created while waiting, got %+v", rec)
+	}
+	if m.GetUser("u2") != nil {
+		t.Error("expected u2 not seated while waiting")
+	}
+
+	if err := m.ReleaseUser("u1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.AdmitUser("u1"); !errors.Is(err, ErrTableFull) {
+		t.Errorf("expected u1 to wait behind the line, got %v", err)
+	}
+	m.fillSeats()
+
+	if !slices.Equal(seated, []string{"u2"}) || m.GetUser("u2") == nil {
+		t.Errorf("expected u2 seated from the front of the line, got %v", seated)
+	}
+	if m.QueuePosition("u3") != 1 || m.QueueLength() != 1 {
+		t.Errorf("expected u3 first in line, got place %d of %d", m.QueuePosition("u3"), m.QueueLength())
+	}
+	mu.Lock()
+	if positions["u3"] != 1 {
+		t.Errorf("expected u3 told they are first, got %d", positions["u3"])
+	}
+	mu.Unlock()
+
+	if !m.Dequeue("u3") || m.Dequeue("u3") {
+		t.Error("expected u3 taken out of line once")
+	}
+	if info := (&Table{ID: "main", Manager: m}).Info(); info.MaxSeats != 1 || info.Queued != 0 || info.Players != 1 {
+		t.Errorf("expected one seat, taken, and no one waiting, got %+v", info)
+	}
+}
+
+func TestSeatQueue_FreesSeatsOfDisconnectedPlayers(t *testing.T) {
+	m := NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	m.SetMaxSeats(3)
+	m.SetSeatGrace(time.Minute)
+	for _, id := range []string{"gone", "held", "blip"} {
+		m.RegisterUser(id)
+	}
+	long := time.Now().Add(-2 * time.Minute)
+	short := time.Now().Add(-time.Second)
+	m.GetUser("gone").LastDisconnect = &long
+	m.GetUser("held").LastDisconnect = &long
+	m.GetUser("blip").LastDisconnect = &short
+	m.imprisoned = []Bet{{ID: "b1", UserID: "held", Type: "color", Value: "red", Amount: 100}}
+	m.Enqueue("u4", "")
+
+	m.fillSeats()
+	for id, want := range map[string]bool{"gone": false, "held": true, "blip": true, "u4": true} {
+		if got := m.GetUser(id) != nil; got != want {
+			t.Errorf("%s: expected seated %v, got %v", id, want, got)
+		}
+	}
+	if rec, _, _ := m.store.GetUser("gone"); rec.Balance != StartingBalance {
+		t.Errorf("expected the freed player's balance kept, got %d", rec.Balance)
+	}
+
+	m.SetSeatGrace(0)
+	m.GetUser("blip").LastDisconnect = &long
+	m.fillSeats()
+	if m.GetUser("blip") == nil {
+		t.Error("expected no seat given up without a seat grace")
+	}
+}
+
+func TestSeatQueue_CloseTurnsAwayQueue(t *testing.T) {
+	var mu sync.Mutex
+	var left []string
+	m := NewManager(func([]byte) {}, func(userID string, data []byte) {
+		var msg messages.TableLeftMessage
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "table_left" {
+			mu.Lock()
+			left = append(left, userID)
+			mu.Unlock()
+		}
+	})
+	t.Cleanup(func() { m.Stop() })
+	m.SetMaxSeats(1)
+	m.RegisterUser("u1")
+	m.Enqueue("u2", "")
+
+	if _, err := m.Close(true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.QueueLength() != 0 {
+		t.Errorf("expected the line cleared, got %d waiting", m.QueueLength())
+	}
+	mu.Lock()
+	if !slices.Equal(left, []string{"u2"}) {
+		t.Errorf("expected u2 told the table closed, got %v", left)
+	}
+	mu.Unlock()
+	if _, err := m.Enqueue("u3", ""); !errors.Is(err, ErrTableClosed) {
+		t.Errorf("expected ErrTableClosed, got %v", err)
+	}
+}
+
 // --- PlaceBet tests ---
 
 func TestPlaceBet_RejectsWhenNotBetting(t *testing.T) {
@@ -209,7 +4111,7 @@ func TestPlaceBet_RejectsWhenNotBetting(t *testing.T) {
 	m.session.State = StateSpinning
 	m.sessionMu.Unlock()
 
-	_, err := m.PlaceBet("u1", "straight", "5", 100)
+	_, _, err := m.PlaceBet("u1", "straight", "5", 100)
 	if err == nil {
 		t.Error("expected error when not in betting state")
 	}
@@ -225,7 +4127,7 @@ func TestPlaceBet_RejectsInsufficientBalance(t *testing.T) {
 	m.session.State = StateBetting
 	m.sessionMu.Unlock()
 
-	_, err := m.PlaceBet("u1", "straight", "5", StartingBalance+1)
+	_, _, err := m.PlaceBet("u1", "straight", "5", StartingBalance+1)
 	if err == nil {
 		t.Error("expected error for insufficient balance")
 	}
@@ -240,7 +4142,7 @@ func TestPlaceBet_Success(t *testing.T) {
 	m.session.State = StateBetting
 	m.sessionMu.Unlock()
 
-	newBalance, err := m.PlaceBet("u1", "straight", "5", 500)
+	_, newBalance, err := m.PlaceBet("u1", "straight", "5", 500)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -286,7 +4188,7 @@ func TestPlaceBet_ConcurrentBets(t *testing.T) {
 	for i := range numBettors {
 		go func(id string) {
 			defer wg.Done()
-			_, err := m.PlaceBet(id, "straight", "7", betAmount)
+			_, _, err := m.PlaceBet(id, "straight", "7", betAmount)
 			if err != nil {
 				t.Errorf("unexpected error for %s: %v", id, err)
 			}
@@ -0,0 +1,216 @@
+package game
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// DefaultSeatGrace is how long a player at a table with a seat limit can be
+// disconnected before their seat is given up at the end of a round.
+const DefaultSeatGrace = 30 * time.Second
+
+// A table with a seat limit turns players away once it is full, and they
+// can wait in line for a seat instead. Seats are given out at the end of a
+// round, first in line first, once players leave or the seats of those who
+// disconnected are given up.
+
+// SetSeatGrace sets how long a player at a table with a seat limit can be
+// disconnected before their seat is given up. 0 keeps their seat until
+// they are evicted. Call before RunGameLoop.
+func (m *Manager) SetSeatGrace(d time.Duration) {
+	m.seatGrace = d
+}
+
+// SetSeatedHook sets f to be called with each player seated from the
+// queue, so their connection can be moved to the table. Call before
+// RunGameLoop.
+func (m *Manager) SetSeatedHook(f func(userID string)) {
+	m.onSeated = f
+}
+
+// MaxSeats returns how many players the table seats, or 0 for no limit.
+func (m *Manager) MaxSeats() int {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	return m.maxSeats
+}
+
+// QueueLength returns how many users are waiting for a seat.
+func (m *Manager) QueueLength() int {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	return len(m.queue)
+}
+
+// QueuePosition returns the user's place in line for a seat, from 1, or 0
+// if they aren't waiting.
+func (m *Manager) QueuePosition(userID string) int {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	return slices.Index(m.queue, userID) + 1
+}
+
+// ReserveSeat holds a seat for a user about to move here from another
+// table, so it can't be taken while they leave that one. AdmitUser seats
+// them in it; CancelReservation gives it up if they stay where they are.
+// Returns why the table can't take them, as AdmitUser would.
+func (m *Manager) ReserveSeat(userID string) error {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	if m.reserved[userID] {
+		return nil
+	}
+	if err := m.admitting(); err != nil {
+		return err
+	}
+	m.reserved[userID] = true
+	return nil
+}
+
+// CancelReservation gives up the seat held for a user, if there is one.
+func (m *Manager) CancelReservation(userID string) {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	delete(m.reserved, userID)
+}
+
+// Enqueue puts a user in line for a seat at the table and returns their
+// place in it, from 1. A user already in line keeps their place. One the
+// store doesn't know yet is created with the starting balance and name, as
+// JoinUser and SetUserName would, but isn't seated.
+func (m *Manager) Enqueue(userID, name string) (int, error) {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	if m.closed {
+		return 0, ErrTableClosed
+	}
+	if i := slices.Index(m.queue, userID); i >= 0 {
+		return i + 1, nil
+	}
+
+	_, ok, err := m.store.GetUser(userID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		user, err := m.newUser(userID)
+		if err != nil {
+			return 0, err
+		}
+		if name = displayName(userID, name); name != "" {
+			user.mu.Lock()
+			user.Name = name
+			m.saveUser(user)
+			user.mu.Unlock()
+		}
+	}
+	m.queue = append(m.queue, userID)
+	slog.Info("user waiting for a seat", "table_id", m.tableID, "user_id", userID, "position", len(m.queue))
+	return len(m.queue), nil
+}
+
+// Dequeue takes a user out of line for a seat and tells those behind them
+// their new place. It reports whether they were in line: false if they
+// have been seated from it.
+func (m *Manager) Dequeue(userID string) bool {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	i := slices.Index(m.queue, userID)
+	if i < 0 {
+		return false
+	}
+	m.queue = slices.Delete(m.queue, i, i+1)
+	m.notifyQueue(i)
+	return true
+}
+
+// notifyQueue tells the users in line from position i on where they stand.
+// The caller must hold usersMu.
+func (m *Manager) notifyQueue(i int) {
+	for ; i < len(m.queue); i++ {
+		msg, err := json.Marshal(messages.QueuePositionMessage{
+			Type:     "queue_position",
+			TableID:  m.tableID,
+			Position: i + 1,
+		})
+		if err != nil {
+			slog.Error("failed to marshal queue position", "error", err, "user_id", m.queue[i])
+			continue
+		}
+		m.sendToUser(m.queue[i], msg)
+	}
+}
+
+// turnAwayQueue turns away everyone waiting for a seat at the closed
+// table. The caller must hold usersMu.
+func (m *Manager) turnAwayQueue() {
+	for _, userID := range m.queue {
+		msg, err := json.Marshal(messages.TableLeftMessage{
+			Type:    "table_left",
+			TableID: m.tableID,
+			Reason:  "table closed",
+		})
+		if err != nil {
+			slog.Error("failed to marshal table left", "error", err, "user_id", userID)
+			continue
+		}
+		m.sendToUser(userID, msg)
+	}
+	m.queue = nil
+}
+
+// fillSeats runs at the end of each round. On a table with a seat limit it
+// gives up the seats of players disconnected for longer than the seat
+// grace, unless the table holds bets of theirs, and then seats those
+// waiting, first in line first. Game loop only.
+func (m *Manager) fillSeats() {
+	now := time.Now()
+	var freed, seated []string
+	m.usersMu.Lock()
+	if m.maxSeats > 0 && m.seatGrace > 0 {
+		for userID, user := range m.users {
+			user.mu.Lock()
+			lastDisconnect := user.LastDisconnect
+			user.mu.Unlock()
+			if lastDisconnect == nil || now.Sub(*lastDisconnect) <= m.seatGrace {
+				continue
+			}
+			// The round's bets are settled, but En Prison ones are held.
+			if slices.ContainsFunc(m.imprisoned, func(b Bet) bool { return b.UserID == userID }) {
+				continue
+			}
+			delete(m.users, userID)
+			freed = append(freed, userID)
+		}
+		m.leftTable(freed...)
+	}
+	for len(m.queue) > 0 && m.seatFree() {
+		userID := m.queue[0]
+		m.queue = m.queue[1:]
+		user, err := m.loadUserLocked(userID, func(UserRecord) bool { return true })
+		if err != nil || user == nil {
+			slog.Error("failed to seat user from the queue", "error", err, "user_id", userID)
+			continue
+		}
+		seated = append(seated, userID)
+	}
+	if len(seated) > 0 {
+		m.notifyQueue(0)
+	}
+	m.usersMu.Unlock()
+
+	for _, userID := range freed {
+		slog.Info("seat given up by disconnected player", "table_id", m.tableID, "user_id", userID)
+		m.NotifyPlayerLeft(userID)
+	}
+	for _, userID := range seated {
+		slog.Info("user seated from the queue", "table_id", m.tableID, "user_id", userID)
+		if m.onSeated != nil {
+			m.onSeated(userID)
+		}
+	}
+}
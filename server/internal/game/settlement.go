@@ -0,0 +1,381 @@
+package game
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// A round's settlement is persisted as it goes so a crash can't leave it
+// half done. Betting opens the round; before any credit is applied the round
+// is marked settling along with every credit it owes; once they are all in
+// the ledger it is marked settled. A credit is applied at most once per
+// (round, bet ID), so settling a round again only applies what is missing.
+// On startup RecoverSettlement finishes a settling round and refunds a round
+// that was still open.
+
+// SettlementState is how far a round's settlement has got.
+type SettlementState string
+
+const (
+	SettlementOpen     SettlementState = "open"     // taking bets or spinning
+	SettlementSettling SettlementState = "settling" // credits being applied
+	SettlementSettled  SettlementState = "settled"  // every credit applied
+)
+
+// restartReason voids a round the server went down in before it settled.
+const restartReason = "server restarted"
+
+// settleRetry is how long the game loop waits to try again when it can't
+// settle a round.
+const settleRetry = 5 * time.Second
+
+// closedReason refunds the bets a table was holding when it closed for good.
+const closedReason = "table closed"
+
+// Credit is a payment a settling round owes on one bet.
+type Credit struct {
+	UserID string                   `json:"user_id"`
+	BetID  string                   `json:"bet_id"`
+	Kind   messages.TransactionKind `json:"kind"`
+	Amount int64                    `json:"amount"`
+	Note   string                   `json:"note,omitempty"`
+}
+
+// Settlement is the persisted state of the latest round.
+type Settlement struct {
+	Round     int64           `json:"round"`
+	State     SettlementState `json:"state"`
+	StartedAt time.Time       `json:"started_at"`
+	Credits   []Credit        `json:"credits,omitempty"`
+	// Carried holds the En Prison bets the table is holding: those riding
+	// on the round while it is open, and those carried into the next round
+	// once it settles.
+	Carried []Bet `json:"carried,omitempty"`
+}
+
+// SettlementStore keeps the settlement of the latest round.
+type SettlementStore interface {
+	// PutSettlement replaces the stored settlement.
+	PutSettlement(s Settlement) error
+	// GetSettlement returns the stored settlement. ok is false if no round
+	// has been played.
+	GetSettlement() (s Settlement, ok bool, err error)
+}
+
+// MemorySettlementStore keeps the settlement in memory. It is safe for
+// concurrent use.
+type MemorySettlementStore struct {
+	mu         sync.Mutex
+	settlement *Settlement
+}
+
+// NewMemorySettlementStore creates an empty in-memory store.
+func NewMemorySettlementStore() *MemorySettlementStore {
+	return &MemorySettlementStore{}
+}
+
+// PutSettlement replaces the stored settlement.
+func (s *MemorySettlementStore) PutSettlement(st Settlement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settlement = &st
+	return nil
+}
+
+// GetSettlement returns the stored settlement.
+func (s *MemorySettlementStore) GetSettlement() (Settlement, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settlement == nil {
+		return Settlement{}, false, nil
+	}
+	return *s.settlement, true, nil
+}
+
+// SetSettlementStore sets where round settlements are persisted. Call
+// before RecoverSettlement and RunGameLoop.
+func (m *Manager) SetSettlementStore(s SettlementStore) {
+	m.settlements = s
+}
+
+// RecoverSettlement finishes the last round if the server went down before
+// it settled: a settling round gets the credits it is missing, and a round
+// still open has every bet in it refunded and is recorded as voided. It
+// needs the user store, ledger and round store in place. Call before
+// RunGameLoop.
+func (m *Manager) RecoverSettlement() error {
+	s, ok, err := m.settlements.GetSettlement()
+	if err != nil {
+		return fmt.Errorf("read settlement: %w", err)
+	}
+	if !ok {
+		return nil
+	}
+	m.round = max(m.round, s.Round)
+
+	switch s.State {
+	case SettlementOpen:
+		if s, err = m.refundOpenRound(s); err != nil {
+			return err
+		}
+		slog.Warn("refunding round interrupted by a restart", "round", s.Round, "bets", len(s.Credits))
+		m.recordRound(&GameSession{round: s.Round, startedAt: s.StartedAt}, nil, nil, nil, restartReason)
+	case SettlementSettling:
+		slog.Warn("finishing round interrupted by a restart", "round", s.Round)
+	case SettlementSettled:
+		m.imprisoned = s.Carried
+		return nil
+	}
+
+	// Unless the store posts both in one write, the ledger is written before
+	// the user, so it has the last word on balances the crash may have left
+	// behind.
+	for _, c := range s.Credits {
+		if err := m.resyncBalance(c.UserID); err != nil {
+			return err
+		}
+	}
+	if err := m.settle(s); err != nil {
+		return err
+	}
+	m.imprisoned = s.Carried
+	return nil
+}
+
+// RecoverClosedTable finishes the last round of a table that won't play
+// again, as RecoverSettlement does, and refunds the En Prison bets it was
+// holding.
+func (m *Manager) RecoverClosedTable() error {
+	if err := m.RecoverSettlement(); err != nil {
+		return err
+	}
+	if len(m.imprisoned) == 0 {
+		return nil
+	}
+	s := Settlement{Round: m.round, State: SettlementSettling}
+	for _, bet := range m.imprisoned {
+		s.Credits = append(s.Credits, Credit{
+			UserID: bet.UserID,
+			BetID:  bet.ID,
+			Kind:   messages.TransactionRefund,
+			Amount: bet.Amount,
+			Note:   closedReason,
+		})
+	}
+	m.imprisoned = nil
+	slog.Warn("refunding bets held by a closed table", "table_id", m.tableID, "bets", len(s.Credits))
+	if err := m.settlements.PutSettlement(s); err != nil {
+		return fmt.Errorf("mark round %d settling: %w", s.Round, err)
+	}
+	return m.settle(s)
+}
+
+// refundOpenRound turns an open round into a settling one that refunds
+// every bet placed in it and every bet it carried.
+func (m *Manager) refundOpenRound(s Settlement) (Settlement, error) {
+	txs, err := m.ledger.RoundTransactions(s.Round)
+	if err != nil {
+		return s, fmt.Errorf("read round %d transactions: %w", s.Round, err)
+	}
+	refund := func(userID, betID string, amount int64) {
+		s.Credits = append(s.Credits, Credit{
+			UserID: userID,
+			BetID:  betID,
+			Kind:   messages.TransactionRefund,
+			Amount: amount,
+			Note:   restartReason,
+		})
+	}
+	for _, bet := range s.Carried {
+		refund(bet.UserID, bet.ID, bet.Amount)
+	}
+	for _, tx := range txs {
+		// Other tables number their rounds the same way.
+		if tx.Kind == messages.TransactionBet && tx.TableID == m.tableID {
+			refund(tx.UserID, tx.BetID, tx.Amount)
+		}
+	}
+	s.State = SettlementSettling
+	s.Carried = nil
+	if err := m.settlements.PutSettlement(s); err != nil {
+		return s, fmt.Errorf("mark round %d settling: %w", s.Round, err)
+	}
+	return s, nil
+}
+
+// resyncBalance sets a stored user's balance to the sum of their ledger
+// entries. Users in memory are left alone.
+func (m *Manager) resyncBalance(userID string) error {
+	if m.GetUser(userID) != nil {
+		return nil
+	}
+	rec, ok, err := m.store.GetUser(userID)
+	if err != nil || !ok {
+		return err
+	}
+	balance, err := m.ledger.LedgerBalance(userID)
+	if err != nil {
+		return err
+	}
+	if balance == rec.Balance {
+		return nil
+	}
+	slog.Warn("restoring balance from the ledger", "user_id", userID, "balance", rec.Balance, "ledger_balance", balance)
+	rec.Balance = balance
+	return m.store.PutUser(rec)
+}
+
+// openRound records that the session's round has opened.
+func (m *Manager) openRound(session *GameSession) {
+	s := Settlement{
+		Round:     session.round,
+		State:     SettlementOpen,
+		StartedAt: session.startedAt,
+		Carried:   m.imprisoned,
+	}
+	if err := m.settlements.PutSettlement(s); err != nil {
+		slog.Error("failed to record open round", "error", err, "round", s.Round)
+	}
+}
+
+// settleRound marks the session's round settling with its credits, applies
+// them and marks it settled, leaving carried held by the table. It keeps
+// trying until the round is settled or the table stops.
+func (m *Manager) settleRound(session *GameSession, credits []Credit, carried []Bet) {
+	s := Settlement{
+		Round:     session.round,
+		State:     SettlementSettling,
+		StartedAt: session.startedAt,
+		Credits:   credits,
+		Carried:   carried,
+	}
+	// Hold the table until the round is settled rather than open another on
+	// top of it. Settling again skips the credits already applied.
+	for {
+		err := m.settlements.PutSettlement(s)
+		if err == nil {
+			err = m.settle(s)
+		}
+		if err == nil {
+			return
+		}
+		slog.Error("failed to settle round, retrying", "error", err, "round", s.Round)
+		select {
+		case <-m.stopCh:
+			// Left settling, so RecoverSettlement finishes it on the next start.
+			return
+		case <-m.clock.After(settleRetry):
+		}
+	}
+}
+
+// settle applies the credits the ledger doesn't already have for the round
+// and marks it settled.
+func (m *Manager) settle(s Settlement) error {
+	txs, err := m.ledger.RoundTransactions(s.Round)
+	if err != nil {
+		return fmt.Errorf("read round %d transactions: %w", s.Round, err)
+	}
+	credited := make(map[string]bool)
+	for _, tx := range txs {
+		if tx.BetID != "" && tx.Credit == tx.UserID {
+			credited[tx.BetID] = true
+		}
+	}
+
+	var userIDs []string
+	postings := make(map[string][]posting)
+	for _, c := range s.Credits {
+		if credited[c.BetID] {
+			continue
+		}
+		credited[c.BetID] = true
+		if _, ok := postings[c.UserID]; !ok {
+			userIDs = append(userIDs, c.UserID)
+		}
+		postings[c.UserID] = append(postings[c.UserID], posting{kind: c.Kind, amount: c.Amount, betID: c.BetID, note: c.Note})
+	}
+	if err := m.credit(s.Round, userIDs, postings); err != nil {
+		return err
+	}
+
+	s.State = SettlementSettled
+	if err := m.settlements.PutSettlement(s); err != nil {
+		return fmt.Errorf("mark round %d settled: %w", s.Round, err)
+	}
+	return nil
+}
+
+// credit posts to each of userIDs their postings and records them all in
+// one write. Users not in memory are credited through the ledger: their
+// transactions are added to the stored balance, which is never replaced
+// with one read before the write.
+func (m *Manager) credit(round int64, userIDs []string, postings map[string][]posting) error {
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	var users []*User
+	var stored, credited []string
+	for _, userID := range userIDs {
+		if user, ok := m.users[userID]; ok {
+			users = append(users, user)
+			credited = append(credited, userID)
+			continue
+		}
+		_, found, err := m.store.GetUser(userID)
+		if err != nil {
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+		if !found {
+			slog.Warn("dropping credit for unknown user", "user_id", userID, "round", round)
+			continue
+		}
+		stored = append(stored, userID)
+		credited = append(credited, userID)
+	}
+
+	// Hold every user in memory until the write lands, so no other change
+	// to their balances comes in between.
+	var txs []messages.Transaction
+	recs := make([]UserRecord, len(users))
+	for i, user := range users {
+		user.mu.Lock()
+		defer user.mu.Unlock()
+		userTxs, balance := m.transactions(user, round, postings[user.ID])
+		txs = append(txs, userTxs...)
+		recs[i] = user.record()
+		recs[i].Balance = balance
+	}
+	for _, userID := range stored {
+		// writePostings sets the balances from the store.
+		userTxs, _ := m.transactions(&User{ID: userID}, round, postings[userID])
+		txs = append(txs, userTxs...)
+	}
+	if len(txs) == 0 {
+		return nil
+	}
+	if err := m.writePostings(txs, recs); err != nil {
+		return fmt.Errorf("credit round %d: %w", round, err)
+	}
+
+	var events []messages.Event
+	for i, user := range users {
+		user.Balance = recs[i].Balance
+	}
+	for _, userID := range credited {
+		for _, p := range postings[userID] {
+			events = append(events, messages.Event{
+				Type:   messages.EventPayoutCredited,
+				Round:  round,
+				UserID: userID,
+				BetID:  p.betID,
+				Amount: p.amount,
+			})
+		}
+	}
+	m.emit(events...)
+	return nil
+}
@@ -0,0 +1,82 @@
+package game
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"roulette/internal/messages"
+)
+
+// Spectators watch a table without sitting at it. They have no balance and
+// can't bet, so the Manager only counts them; the connections themselves
+// are the hub's. They get the table's broadcasts, and a round_result in
+// place of each player's result.
+
+// SetSpectatorBroadcast sets how the Manager sends to the table's
+// spectators alone. Without one they get only the table's broadcasts. Call
+// before RunGameLoop.
+func (m *Manager) SetSpectatorBroadcast(f BroadcastFunc) {
+	m.sendSpectators = f
+}
+
+// AddSpectator counts one more spectator at the table.
+func (m *Manager) AddSpectator() {
+	m.spectators.Add(1)
+}
+
+// RemoveSpectator counts one fewer spectator at the table.
+func (m *Manager) RemoveSpectator() {
+	m.spectators.Add(-1)
+}
+
+// SpectatorCount returns how many spectators are watching the table.
+func (m *Manager) SpectatorCount() int {
+	return int(m.spectators.Load())
+}
+
+// spectate sends msg to the table's spectators.
+func (m *Manager) spectate(msg []byte) {
+	if m.sendSpectators != nil {
+		m.sendSpectators(msg)
+	}
+}
+
+// broadcastSpectatorCount tells the table how many are watching, if that
+// has changed since it was last told. It runs once a round rather than as
+// each spectator comes and goes, so a crowd arriving doesn't flood the
+// table. Game loop only.
+func (m *Manager) broadcastSpectatorCount() {
+	count := m.SpectatorCount()
+	if count == m.lastSpectators {
+		return
+	}
+	m.lastSpectators = count
+	msg, err := json.Marshal(messages.SpectatorsMessage{
+		Type:  "spectators",
+		Count: count,
+	})
+	if err != nil {
+		slog.Error("failed to marshal spectator count", "error", err)
+		return
+	}
+	m.broadcast(msg)
+}
+
+// spectateResult shows the table's spectators the round's result.
+func (m *Manager) spectateResult(winningNumber int, totalWon int64, fairness *messages.FairnessReveal) {
+	if m.sendSpectators == nil {
+		return
+	}
+	msg, err := json.Marshal(messages.RoundResultMessage{
+		Type:          "round_result",
+		WinningNumber: winningNumber,
+		WinningPocket: PocketLabel(winningNumber),
+		TotalWon:      totalWon,
+		Fairness:      fairness,
+	})
+	if err != nil {
+		slog.Error("failed to marshal round result", "error", err)
+		return
+	}
+	m.spectate(msg)
+}
@@ -2,15 +2,104 @@ package game
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
+	mathrand "math/rand/v2"
+	"slices"
+	"sync"
 )
 
-// SpinWheel generates a cryptographically random roulette number between 0 and 36.
-func SpinWheel() (int, error) {
-	n, err := rand.Int(rand.Reader, big.NewInt(37))
+// WheelRNG picks the pocket a spin lands on. Tables use the provably fair
+// derivation unless one is injected with Manager.SetRNG.
+type WheelRNG interface {
+	// Spin returns a pocket on w.
+	Spin(w *Wheel) (int, error)
+}
+
+// CryptoRNG spins with crypto/rand.
+type CryptoRNG struct{}
+
+// Spin picks a cryptographically random pocket on w.
+func (CryptoRNG) Spin(w *Wheel) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(w.Size())))
 	if err != nil {
 		return 0, fmt.Errorf("failed to generate random number: %w", err)
 	}
-	return int(n.Int64()), nil
+	return w.pocketAt(int(n.Int64())), nil
+}
+
+// SeededRNG is a deterministic source: two SeededRNGs created with the same
+// seed produce the same spins, which makes tests and replays repeatable.
+// It is safe for concurrent use.
+type SeededRNG struct {
+	mu  sync.Mutex
+	rng *mathrand.Rand
+}
+
+// NewSeededRNG creates a deterministic source from seed.
+func NewSeededRNG(seed uint64) *SeededRNG {
+	return &SeededRNG{rng: mathrand.New(mathrand.NewPCG(seed, seed))}
+}
+
+// Spin picks the next pocket in the seeded sequence.
+func (s *SeededRNG) Spin(w *Wheel) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return w.pocketAt(s.rng.IntN(w.Size())), nil
+}
+
+// ScriptedRNG replays a fixed list of outcomes in order, starting over after
+// the last one. It is safe for concurrent use.
+type ScriptedRNG struct {
+	mu      sync.Mutex
+	pockets []int
+	next    int
+}
+
+// NewScriptedRNG creates a source that lands on each of pockets in turn.
+func NewScriptedRNG(pockets ...int) *ScriptedRNG {
+	return &ScriptedRNG{pockets: pockets}
+}
+
+// Spin returns the next scripted pocket. It fails if the script is empty or
+// the pocket is not on w.
+func (s *ScriptedRNG) Spin(w *Wheel) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pockets) == 0 {
+		return 0, errors.New("scripted spin: no outcomes")
+	}
+	n := s.pockets[s.next]
+	s.next = (s.next + 1) % len(s.pockets)
+	if !w.Has(n) {
+		return 0, fmt.Errorf("scripted spin: %s is not on a %s wheel", PocketLabel(n), w.Variant)
+	}
+	return n, nil
+}
+
+// SpinWheel generates a cryptographically random roulette number between 0 and 36.
+func SpinWheel() (int, error) {
+	return EuropeanWheel.Spin()
+}
+
+// Spin picks a cryptographically random pocket on the wheel.
+func (w *Wheel) Spin() (int, error) {
+	return CryptoRNG{}.Spin(w)
+}
+
+// pocketAt maps an index in [0, Size) onto a pocket: zeros first, then 1-36.
+func (w *Wheel) pocketAt(i int) int {
+	if i < len(w.Zeros) {
+		return w.Zeros[i]
+	}
+	return i - len(w.Zeros) + 1
+}
+
+// indexOf is the inverse of pocketAt. pocket must be on the wheel.
+func (w *Wheel) indexOf(pocket int) int {
+	if i := slices.Index(w.Zeros, pocket); i >= 0 {
+		return i
+	}
+	return pocket - 1 + len(w.Zeros)
 }
@@ -0,0 +1,228 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"roulette/internal/messages"
+)
+
+// DefaultTableID is the ID of the table a server runs when it is given no
+// table list.
+const DefaultTableID = "main"
+
+// Table is one roulette table in a TableRegistry.
+type Table struct {
+	ID      string
+	Name    string
+	Manager *Manager
+	private *privateTable // nil for a public table
+}
+
+// Info describes the table for the lobby.
+func (t *Table) Info() messages.TableInfo {
+	state, _, _ := t.Manager.GetCurrentGameState()
+	return messages.TableInfo{
+		ID:         t.ID,
+		Name:       t.Name,
+		Variant:    t.Manager.Wheel().Variant,
+		ZeroRule:   t.Manager.ZeroRule(),
+		LiveDealer: t.Manager.LiveDealer(),
+		State:      state,
+		Players:    t.Manager.PlayerCount(),
+		Spectators: t.Manager.SpectatorCount(),
+		MaxSeats:   t.Manager.MaxSeats(),
+		Queued:     t.Manager.QueueLength(),
+	}
+}
+
+// TableRegistry runs a set of tables, each with its own Manager. Players
+// move between them, but sit at one at a time: only that table's Manager
+// holds them in memory. It is safe for concurrent use.
+type TableRegistry struct {
+	mu                 sync.RWMutex
+	tables             map[string]*Table
+	order              []*Table          // in the order added; the first is the default
+	byCode             map[string]*Table // private tables by invite code
+	factory            TableFactory
+	idleTimeout        time.Duration
+	maxPrivate         int // private tables open at once
+	maxPrivatePerOwner int
+	onRemove           func(t *Table, reason string)
+	onClosed           func(id string)
+	stopCh             chan struct{}
+}
+
+// NewTableRegistry creates an empty registry.
+func NewTableRegistry() *TableRegistry {
+	return &TableRegistry{
+		tables:             make(map[string]*Table),
+		byCode:             make(map[string]*Table),
+		idleTimeout:        DefaultPrivateTableIdle,
+		maxPrivate:         DefaultMaxPrivateTables,
+		maxPrivatePerOwner: DefaultMaxPrivateTablesPerOwner,
+		stopCh:             make(chan struct{}),
+	}
+}
+
+// Add registers a table and sets its manager's table ID. Tables share the
+// user store and ledger, so players keep their balance from table to table.
+func (r *TableRegistry) Add(id, name string, m *Manager) (*Table, error) {
+	t := &Table{ID: id, Name: name, Manager: m}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.add(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// add registers t. The caller must hold mu.
+func (r *TableRegistry) add(t *Table) error {
+	if _, ok := r.tables[t.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateTable, t.ID)
+	}
+	t.Manager.SetTableID(t.ID)
+	r.tables[t.ID] = t
+	r.order = append(r.order, t)
+	return nil
+}
+
+// SetRemoveHook sets f to be called with each private table the registry
+// tears down, and why, once it is out of the registry. Whoever is watching
+// it can be sent back to the lobby. Call before RunGameLoops.
+func (r *TableRegistry) SetRemoveHook(f func(t *Table, reason string)) {
+	r.onRemove = f
+}
+
+// SetClosedHook sets f to be called with the ID of each private table the
+// registry tears down or gives up on while creating it, once its game loop
+// is told to stop, so whatever the table kept can be let go. Call before
+// RunGameLoops.
+func (r *TableRegistry) SetClosedHook(f func(id string)) {
+	r.onClosed = f
+}
+
+// Get returns the table with id.
+func (r *TableRegistry) Get(id string) (*Table, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tables[id]
+	return t, ok
+}
+
+// Lookup returns the table with id, or the default table if id is empty.
+func (r *TableRegistry) Lookup(id string) (*Table, error) {
+	if id == "" {
+		if t := r.Default(); t != nil {
+			return t, nil
+		}
+	} else if t, ok := r.Get(id); ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownTable, id)
+}
+
+// Default returns the first table added, or nil if there is none. Add the
+// public tables before creating any private one.
+func (r *TableRegistry) Default() *Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.order) == 0 {
+		return nil
+	}
+	return r.order[0]
+}
+
+// Tables returns every table in the order they were added.
+func (r *TableRegistry) Tables() []*Table {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]*Table(nil), r.order...)
+}
+
+// Infos describes the public tables for the lobby.
+func (r *TableRegistry) Infos() []messages.TableInfo {
+	infos := []messages.TableInfo{}
+	for _, t := range r.Tables() {
+		if !t.Private() {
+			infos = append(infos, t.Info())
+		}
+	}
+	return infos
+}
+
+// Locate returns the table the user is sitting at, or nil if they are not
+// at any.
+func (r *TableRegistry) Locate(userID string) *Table {
+	for _, t := range r.Tables() {
+		if t.Manager.GetUser(userID) != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// Move takes a user from table from, which may be nil for the lobby, to
+// table to. Their seat at to is held before they leave from, so if to can't
+// take them they stay where they were. Should to close while they move,
+// they go back to from; if that is no longer possible either they are left
+// at no table and the error wraps ErrNotSeated.
+func (r *TableRegistry) Move(userID string, from, to *Table) error {
+	if from == to {
+		return nil
+	}
+	if err := to.Manager.ReserveSeat(userID); err != nil {
+		return err
+	}
+	if from != nil {
+		if err := from.Manager.ReleaseUser(userID); err != nil {
+			to.Manager.CancelReservation(userID)
+			return err
+		}
+	}
+	return r.finishMove(userID, from, to)
+}
+
+// finishMove seats the user, released from table from, in the seat Move
+// held for them at table to.
+func (r *TableRegistry) finishMove(userID string, from, to *Table) error {
+	_, err := to.Manager.AdmitUser(userID)
+	if err == nil || from == nil {
+		return err
+	}
+	if _, back := from.Manager.AdmitUser(userID); back != nil {
+		return fmt.Errorf("%w: %w; and back at %s: %w", ErrNotSeated, err, from.ID, back)
+	}
+	return err
+}
+
+// CheckSessionToken reports whether token is the user's session token,
+// without seating them anywhere.
+func (r *TableRegistry) CheckSessionToken(userID, token string) bool {
+	if t := r.Locate(userID); t != nil {
+		return t.Manager.ValidateSessionToken(userID, token)
+	}
+	if t := r.Default(); t != nil {
+		return t.Manager.checkStoredToken(userID, token)
+	}
+	return false
+}
+
+// RunGameLoops starts every table's game loop, and the tearing down of
+// private tables left empty.
+func (r *TableRegistry) RunGameLoops() {
+	for _, t := range r.Tables() {
+		go t.Manager.RunGameLoop()
+	}
+	go r.runReaper()
+}
+
+// Stop shuts down every table's game loop.
+func (r *TableRegistry) Stop() {
+	close(r.stopCh)
+	for _, t := range r.Tables() {
+		t.Manager.Stop()
+	}
+}
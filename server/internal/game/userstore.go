@@ -0,0 +1,256 @@
+package game
+
+import (
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+
+	"roulette/internal/messages"
+)
+
+// UserRecord is the part of a user that outlives their connection and a
+// restart of the server.
+type UserRecord struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Balance      int64  `json:"balance"`
+	SessionToken string `json:"session_token"`
+}
+
+// UserStore persists users. The Manager keeps connected users in memory and
+// writes every change to their name or balance through to the store, so a
+// user who reconnects with their session token gets the same balance back,
+// even after a restart.
+type UserStore interface {
+	// GetUser returns the user with id. ok is false if there is none.
+	GetUser(id string) (rec UserRecord, ok bool, err error)
+	// PutUser creates or replaces a user.
+	PutUser(rec UserRecord) error
+	// DeleteUser removes a user. Removing an unknown user is not an error.
+	DeleteUser(id string) error
+}
+
+// MemoryUserStore keeps users in memory, so they last as long as the
+// process. It is safe for concurrent use.
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]UserRecord
+}
+
+// NewMemoryUserStore creates an empty in-memory store.
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[string]UserRecord)}
+}
+
+// GetUser returns the user with id.
+func (s *MemoryUserStore) GetUser(id string) (UserRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rec, ok := s.users[id]
+	return rec, ok, nil
+}
+
+// PutUser creates or replaces a user.
+func (s *MemoryUserStore) PutUser(rec UserRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[rec.ID] = rec
+	return nil
+}
+
+// DeleteUser removes a user.
+func (s *MemoryUserStore) DeleteUser(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+	return nil
+}
+
+// record is the persistent part of the user. The caller must hold user.mu.
+func (u *User) record() UserRecord {
+	return UserRecord{
+		ID:           u.ID,
+		Name:         u.Name,
+		Balance:      u.Balance,
+		SessionToken: u.SessionToken,
+	}
+}
+
+// SetUserStore sets where users are persisted. Call before RunGameLoop and
+// before any user registers.
+func (m *Manager) SetUserStore(s UserStore) {
+	m.store = s
+}
+
+// saveUser writes the user through to the store. The in-memory user stays
+// authoritative for the running game, so a failed write is logged rather
+// than undoing the change. The caller must hold user.mu.
+func (m *Manager) saveUser(user *User) {
+	if err := m.store.PutUser(user.record()); err != nil {
+		slog.Error("failed to save user", "error", err, "user_id", user.ID)
+	}
+}
+
+// restoreUser loads a user who is not in memory, such as one who was evicted
+// while disconnected or who played before a restart, if token is theirs.
+func (m *Manager) restoreUser(userID, token string) *User {
+	user, err := m.loadUser(userID, func(rec UserRecord) bool {
+		return rec.SessionToken != "" && rec.SessionToken == token
+	})
+	if err != nil {
+		slog.Error("failed to load user", "error", err, "user_id", userID)
+	}
+	return user
+}
+
+// loadUser puts the stored user into memory if allow accepts their record.
+// It returns nil if there is no such user or allow refuses them.
+func (m *Manager) loadUser(userID string, allow func(UserRecord) bool) (*User, error) {
+	// Hold usersMu while loading so a credit to the stored user can't land
+	// between the load and the user going into memory.
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	return m.loadUserLocked(userID, allow)
+}
+
+// loadUserLocked is loadUser for a caller that holds usersMu.
+func (m *Manager) loadUserLocked(userID string, allow func(UserRecord) bool) (*User, error) {
+	if user, ok := m.users[userID]; ok {
+		// Loaded by a concurrent reconnect.
+		return user, nil
+	}
+
+	rec, ok, err := m.store.GetUser(userID)
+	if err != nil || !ok || !allow(rec) {
+		return nil, err
+	}
+	user := &User{
+		ID:           rec.ID,
+		Name:         rec.Name,
+		Balance:      rec.Balance,
+		SessionToken: rec.SessionToken,
+	}
+	m.users[userID] = user
+	m.emit(messages.Event{Type: messages.EventUserJoined, UserID: userID, Amount: rec.Balance})
+	slog.Info("restored user from store", "user_id", userID, "balance", rec.Balance)
+	return user, nil
+}
+
+// checkStoredToken reports whether token is the stored user's session token,
+// without loading them.
+func (m *Manager) checkStoredToken(userID, token string) bool {
+	rec, ok, err := m.store.GetUser(userID)
+	if err != nil {
+		slog.Error("failed to load user", "error", err, "user_id", userID)
+		return false
+	}
+	return ok && rec.SessionToken != "" && rec.SessionToken == token
+}
+
+// AdmitUser seats a stored user at the table, as when they move here from
+// another table or the lobby, in the seat held for them by ReserveSeat if
+// there is one. Returns ErrUserNotFound if the store has no such user, or
+// why the table can't take them.
+func (m *Manager) AdmitUser(userID string) (*User, error) {
+	defer m.CancelReservation(userID)
+	var refused error
+	user, err := m.loadUser(userID, func(UserRecord) bool {
+		// loadUser holds usersMu.
+		if m.reserved[userID] {
+			if m.closed {
+				refused = ErrTableClosed
+			}
+		} else {
+			refused = m.admitting()
+		}
+		return refused == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refused != nil {
+		return nil, refused
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ReleaseUser takes a user away from the table, leaving them in the user
+// store for another table to admit. A user with bets in the current round,
+// or held En Prison, must wait for them to settle: ErrBetsInPlay.
+func (m *Manager) ReleaseUser(userID string) error {
+	// The write lock waits out any bet being placed, which holds the read
+	// lock from finding the user to adding the bet.
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	if err := m.checkNoBets(func(b Bet) bool { return b.UserID == userID }); err != nil {
+		return err
+	}
+
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return ErrUserNotFound
+	}
+	delete(m.users, userID)
+	m.leftTable(userID)
+	return nil
+}
+
+// Close releases every user at the table, as ReleaseUser does, and stops it
+// admitting any more. Anyone waiting for a seat is turned away. Unless force
+// is set it refuses while anyone is seated: ErrTableNotEmpty. Returns the IDs
+// of the users released.
+func (m *Manager) Close(force bool) ([]string, error) {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	if err := m.checkNoBets(func(Bet) bool { return true }); err != nil {
+		return nil, err
+	}
+
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+	if !force && len(m.users) > 0 {
+		return nil, ErrTableNotEmpty
+	}
+	ids := slices.Sorted(maps.Keys(m.users))
+	clear(m.users)
+	clear(m.reserved)
+	m.leftTable(ids...)
+	m.closed = true
+	m.turnAwayQueue()
+	return ids, nil
+}
+
+// leftTable records that users are no longer at the table.
+func (m *Manager) leftTable(userIDs ...string) {
+	if len(userIDs) == 0 {
+		return
+	}
+	events := make([]messages.Event, len(userIDs))
+	for i, userID := range userIDs {
+		events[i] = messages.Event{Type: messages.EventUserLeft, UserID: userID}
+	}
+	m.emit(events...)
+}
+
+// checkNoBets returns ErrBetsInPlay if any bet in the current round, or held
+// En Prison, matches. The caller must hold the sessionMu write lock.
+func (m *Manager) checkNoBets(match func(Bet) bool) error {
+	m.session.mu.Lock()
+	inPlay := slices.ContainsFunc(m.session.Bets, match)
+	m.session.mu.Unlock()
+
+	// The settlement has the En Prison bets the table is holding.
+	s, _, err := m.settlements.GetSettlement()
+	if err != nil {
+		return err
+	}
+	if inPlay || slices.ContainsFunc(s.Carried, match) {
+		return ErrBetsInPlay
+	}
+	return nil
+}
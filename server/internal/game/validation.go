@@ -3,45 +3,67 @@ package game
 import (
 	"errors"
 	"fmt"
-	"strconv"
 )
 
 var (
 	ErrBetAmountZero       = errors.New("bet amount must be greater than 0")
+	ErrBetAmountTooLarge   = errors.New("bet amount too large")
 	ErrUnknownBetType      = errors.New("unknown bet type")
 	ErrInvalidBetValue     = errors.New("invalid bet value")
 	ErrBettingClosed       = errors.New("betting is closed")
 	ErrUserNotFound        = errors.New("user not found")
 	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrBetNotFound         = errors.New("bet not found")
+	ErrBetBelowMinimum     = errors.New("bet below table minimum")
+	ErrBetAboveMaximum     = errors.New("bet above table maximum")
+	ErrPlayerStakeLimit    = errors.New("round stake limit reached")
+	ErrTableLiabilityLimit = errors.New("table liability limit reached")
+	ErrNoPreviousBets      = errors.New("no bets from a previous round")
+	ErrNoBets              = errors.New("no bets to place")
+	ErrTooManyBets         = errors.New("too many bets in one batch")
+	ErrInvalidClientSeed   = errors.New("client seed must be 1-64 letters, digits, '-' or '_'")
+	ErrSeedMismatch        = errors.New("server seed does not match its hash")
+	ErrNotLiveDealer       = errors.New("table is not in live dealer mode")
+	ErrNotAwaitingResult   = errors.New("no round is waiting for a result")
+	ErrNoPendingResult     = errors.New("enter the result before confirming it")
+	ErrResultMismatch      = errors.New("confirmed result does not match the entered one")
+	ErrInvalidPocket       = errors.New("invalid pocket")
+	ErrNoRNGMonitor        = errors.New("table has no RNG monitor")
+	ErrNotPaused           = errors.New("table is not paused")
+	ErrInvalidAmount       = errors.New("amount must not be 0")
+	ErrBetsInPlay          = errors.New("bets are still in play at this table")
+	ErrUnknownTable        = errors.New("unknown table")
+	ErrDuplicateTable      = errors.New("table ID already in use")
+	ErrTableFull           = errors.New("table is full")
+	ErrTableClosed         = errors.New("table is closed")
+	ErrNotSeated           = errors.New("left without a seat at any table")
+	ErrTableNotEmpty       = errors.New("table still has players")
+	ErrInviteRequired      = errors.New("table needs an invite code")
+	ErrWrongPassword       = errors.New("wrong table password")
+	ErrKicked              = errors.New("removed from this table by its owner")
+	ErrNotTableOwner       = errors.New("only the table owner can do that")
+	ErrKickOwner           = errors.New("the table owner can't be kicked")
+	ErrInvalidSettings     = errors.New("invalid table settings")
+	ErrTooManyTables       = errors.New("too many private tables open")
+	ErrLedgerWrite         = errors.New("balance change could not be recorded")
 )
 
-// ValidateBet checks whether the given bet parameters are valid.
-func ValidateBet(betType, betValue string, amount int64) error {
-	if amount <= 0 {
-		return ErrBetAmountZero
-	}
+// BetError is a batch rejection caused by one of its bets. Index is the
+// bet's position in the batch.
+type BetError struct {
+	Index int
+	Err   error
+}
+
+func (e *BetError) Error() string {
+	return fmt.Sprintf("bet %d: %v", e.Index+1, e.Err)
+}
 
-	switch betType {
-	case "straight":
-		n, err := strconv.Atoi(betValue)
-		if err != nil || n < 0 || n > 36 {
-			return fmt.Errorf("%w: straight bet %s (must be 0-36)", ErrInvalidBetValue, betValue)
-		}
-	case "color":
-		if betValue != "red" && betValue != "black" {
-			return fmt.Errorf("%w: color bet %s (must be red or black)", ErrInvalidBetValue, betValue)
-		}
-	case "even_odd":
-		if betValue != "even" && betValue != "odd" {
-			return fmt.Errorf("%w: even_odd bet %s (must be even or odd)", ErrInvalidBetValue, betValue)
-		}
-	case "dozens":
-		if betValue != "first" && betValue != "second" && betValue != "third" {
-			return fmt.Errorf("%w: dozens bet %s (must be first, second, or third)", ErrInvalidBetValue, betValue)
-		}
-	default:
-		return fmt.Errorf("%w: %s", ErrUnknownBetType, betType)
-	}
+func (e *BetError) Unwrap() error {
+	return e.Err
+}
 
-	return nil
+// ValidateBet checks whether the given bet parameters are valid on a standard European table.
+func ValidateBet(betType, betValue string, amount int64) error {
+	return defaultRegistry.ValidateBet(EuropeanWheel, betType, betValue, amount)
 }
@@ -0,0 +1,166 @@
+package game
+
+import (
+	"slices"
+	"strconv"
+
+	"roulette/internal/messages"
+)
+
+// Pocket numbers above 36 encode the extra zeros of American and
+// triple-zero wheels. Use PocketLabel to render them for players.
+const (
+	DoubleZero = 37
+	TripleZero = 38
+)
+
+// Wheel describes a roulette variant: which zero pockets it has and how
+// those zeros sit against the first row of the betting layout.
+type Wheel struct {
+	Variant messages.WheelVariant
+	// Zeros lists the zero pockets in layout order (0, then 00, then 000).
+	Zeros []int
+	// ZeroSplits are the legal two-number bets involving a zero.
+	ZeroSplits [][]int
+	// ZeroStreets are the legal three-number bets involving a zero.
+	ZeroStreets [][]int
+	// TopLine is the zeros plus the first row, the basket / five-number bet.
+	TopLine []int
+	// Order is the physical pocket order around the wheel head, clockwise from 0.
+	Order []int
+}
+
+var (
+	// EuropeanWheel has a single zero and 37 pockets.
+	EuropeanWheel = &Wheel{
+		Variant:     messages.WheelVariantEuropean,
+		Zeros:       []int{0},
+		ZeroSplits:  [][]int{{0, 1}, {0, 2}, {0, 3}},
+		ZeroStreets: [][]int{{0, 1, 2}, {0, 2, 3}},
+		TopLine:     []int{0, 1, 2, 3},
+		Order: []int{
+			0, 32, 15, 19, 4, 21, 2, 25, 17, 34, 6, 27, 13, 36, 11, 30, 8, 23, 10,
+			5, 24, 16, 33, 1, 20, 14, 31, 9, 22, 18, 29, 7, 28, 12, 35, 3, 26,
+		},
+	}
+
+	// AmericanWheel has 0 and 00 and 38 pockets.
+	AmericanWheel = &Wheel{
+		Variant:     messages.WheelVariantAmerican,
+		Zeros:       []int{0, DoubleZero},
+		ZeroSplits:  [][]int{{0, 1}, {0, 2}, {2, DoubleZero}, {3, DoubleZero}, {0, DoubleZero}},
+		ZeroStreets: [][]int{{0, 1, 2}, {0, 2, DoubleZero}, {2, 3, DoubleZero}},
+		TopLine:     []int{0, 1, 2, 3, DoubleZero},
+		Order: []int{
+			0, 28, 9, 26, 30, 11, 7, 20, 32, 17, 5, 22, 34, 15, 3, 24, 36, 13, 1,
+			DoubleZero, 27, 10, 25, 29, 12, 8, 19, 31, 18, 6, 21, 33, 16, 4, 23, 35, 14, 2,
+		},
+	}
+
+	// TripleZeroWheel has 0, 00 and 000 and 39 pockets; each zero sits above one column.
+	// Its head follows the American order with 000 between 2 and 0.
+	TripleZeroWheel = &Wheel{
+		Variant:     messages.WheelVariantTripleZero,
+		Zeros:       []int{0, DoubleZero, TripleZero},
+		ZeroSplits:  [][]int{{0, 1}, {2, DoubleZero}, {3, TripleZero}, {0, DoubleZero}, {DoubleZero, TripleZero}},
+		ZeroStreets: [][]int{{0, DoubleZero, TripleZero}},
+		TopLine:     []int{0, 1, 2, 3, DoubleZero, TripleZero},
+		Order: []int{
+			0, 28, 9, 26, 30, 11, 7, 20, 32, 17, 5, 22, 34, 15, 3, 24, 36, 13, 1,
+			DoubleZero, 27, 10, 25, 29, 12, 8, 19, 31, 18, 6, 21, 33, 16, 4, 23, 35, 14, 2,
+			TripleZero,
+		},
+	}
+)
+
+// WheelFor returns the wheel for a variant name.
+func WheelFor(variant messages.WheelVariant) (*Wheel, bool) {
+	switch variant {
+	case messages.WheelVariantEuropean:
+		return EuropeanWheel, true
+	case messages.WheelVariantAmerican:
+		return AmericanWheel, true
+	case messages.WheelVariantTripleZero:
+		return TripleZeroWheel, true
+	default:
+		return nil, false
+	}
+}
+
+// Size returns the number of pockets on the wheel.
+func (w *Wheel) Size() int {
+	return 36 + len(w.Zeros)
+}
+
+// Pockets returns every pocket on the wheel: the zeros followed by 1-36.
+func (w *Wheel) Pockets() []int {
+	pockets := slices.Clone(w.Zeros)
+	for n := 1; n <= 36; n++ {
+		pockets = append(pockets, n)
+	}
+	return pockets
+}
+
+// Has reports whether n is a pocket on this wheel.
+func (w *Wheel) Has(n int) bool {
+	return (n >= 1 && n <= 36) || w.IsZero(n)
+}
+
+// IsZero reports whether n is one of this wheel's green pockets.
+func (w *Wheel) IsZero(n int) bool {
+	return slices.Contains(w.Zeros, n)
+}
+
+// IsRed reports whether n is a red pocket. Zeros are never red.
+func (w *Wheel) IsRed(n int) bool {
+	return !w.IsZero(n) && RedNumbers[n]
+}
+
+// Neighbours returns pocket n and the k pockets either side of it on the wheel head,
+// in wheel order. It returns nil if n is not on the wheel.
+func (w *Wheel) Neighbours(n, k int) []int {
+	i := slices.Index(w.Order, n)
+	if i < 0 {
+		return nil
+	}
+	size := len(w.Order)
+	pockets := make([]int, 0, 2*k+1)
+	for d := -k; d <= k; d++ {
+		pockets = append(pockets, w.Order[((i+d)%size+size)%size])
+	}
+	return pockets
+}
+
+// ParsePocket parses a pocket label ("0"-"36", "00", "000") that exists on this wheel.
+func (w *Wheel) ParsePocket(label string) (int, bool) {
+	var n int
+	switch label {
+	case "00":
+		n = DoubleZero
+	case "000":
+		n = TripleZero
+	default:
+		var err error
+		n, err = strconv.Atoi(label)
+		// Reject "+5", "05" and raw 37/38 so every pocket has one spelling.
+		if err != nil || strconv.Itoa(n) != label || n > 36 {
+			return 0, false
+		}
+	}
+	if !w.Has(n) {
+		return 0, false
+	}
+	return n, true
+}
+
+// PocketLabel renders a pocket number the way players see it ("00" rather than 37).
+func PocketLabel(n int) string {
+	switch n {
+	case DoubleZero:
+		return "00"
+	case TripleZero:
+		return "000"
+	default:
+		return strconv.Itoa(n)
+	}
+}
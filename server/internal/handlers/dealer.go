@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"roulette/internal/messages"
+)
+
+// maxDealerBody bounds the size of a croupier request.
+const maxDealerBody = 4 << 10
+
+// HandleDealer lets the croupier enter, confirm or void a live round.
+// It needs the table's admin token as a bearer token.
+func (s *Server) HandleDealer(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+
+	var req messages.DealerRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxDealerBody)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ack := m.ApplyDealerStep(req.Step, req.Pocket, req.Reason)
+	w.Header().Set("Content-Type", "application/json")
+	if !ack.OK {
+		w.WriteHeader(http.StatusConflict)
+	}
+	if err := json.NewEncoder(w).Encode(ack); err != nil {
+		slog.Error("failed to write dealer response", "error", err)
+	}
+}
+
+// authorizeAdmin checks the request carries the admin token, which every
+// table shares, as a bearer token, answering 401 if not.
+func (s *Server) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.Tables.Default().Manager.CheckAdminToken(token) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"roulette/internal/messages"
+)
+
+// HandleEvents lists the table's events, oldest first. limit caps the page
+// (default 20, at most 100) and after pages on from an event's Seq. It needs
+// the table's admin token as a bearer token.
+func (s *Server) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	after, limit, ok := parseCursor(w, r, "after")
+	if !ok {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	events, err := m.Events(after, limit)
+	if err != nil {
+		slog.Error("failed to list events", "error", err)
+		http.Error(w, "failed to list events", http.StatusInternalServerError)
+		return
+	}
+	if events == nil {
+		events = []messages.Event{}
+	}
+	writeJSON(w, messages.EventsResponse{Events: events})
+}
+
+// HandleTableState returns the table state built from the events.
+func (s *Server) HandleTableState(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, m.TableState())
+}
+
+// HandleReplayRound replays one round's events from the state the table was
+// in when it opened.
+func (s *Server) HandleReplayRound(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid round ID", http.StatusBadRequest)
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	replay, ok, err := m.ReplayRound(id)
+	if err != nil {
+		slog.Error("failed to replay round", "error", err, "round", id)
+		http.Error(w, "failed to replay round", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "round not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, replay)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"roulette/internal/game"
+	"roulette/internal/messages"
+)
+
+// maxVerifyBody bounds the size of a verification request.
+const maxVerifyBody = 64 << 10
+
+// HandleFairnessVerify recomputes a past spin from its revealed seeds so
+// anyone can check the server did not pick the outcome.
+func (s *Server) HandleFairnessVerify(w http.ResponseWriter, r *http.Request) {
+	var req messages.FairnessVerifyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxVerifyBody)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Variant == "" {
+		req.Variant = messages.WheelVariantEuropean
+	}
+	wheel, ok := game.WheelFor(req.Variant)
+	if !ok {
+		http.Error(w, "unknown wheel variant", http.StatusBadRequest)
+		return
+	}
+
+	var resp messages.FairnessVerifyResponse
+	n, err := game.VerifySpin(wheel, req.ServerSeed, req.ServerSeedHash, req.ClientSeeds, req.Nonce)
+	if err != nil {
+		// The seed doesn't match its commitment; report that rather than fail.
+		resp.Reason = err.Error()
+	} else {
+		label := game.PocketLabel(n)
+		resp.Valid = true
+		resp.WinningNumber = &n
+		resp.WinningPocket = &label
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Error("failed to write fairness verification", "error", err)
+	}
+}
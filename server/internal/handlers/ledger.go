@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"roulette/internal/game"
+	"roulette/internal/messages"
+)
+
+const maxAdjustBody = 1 << 10
+
+// HandleTransactions lists a user's balance transactions, newest first, paged
+// like GET /rounds. It needs the user's session token, or the admin token, as
+// a bearer token. The tables share the ledger, so the list covers them all.
+func (s *Server) HandleTransactions(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	gm := s.Tables.Default().Manager
+	if !gm.CheckAdminToken(token) && !s.Tables.CheckSessionToken(userID, token) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	before, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+
+	txs, err := gm.Transactions(userID, before, limit)
+	if err != nil {
+		slog.Error("failed to list transactions", "error", err, "user_id", userID)
+		http.Error(w, "failed to list transactions", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, messages.TransactionsResponse{Transactions: txs})
+}
+
+// HandleAdjustBalance credits or debits a user's balance as an admin
+// adjustment at the table they are at and returns the transaction it
+// recorded. It needs the admin token as a bearer token.
+func (s *Server) HandleAdjustBalance(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	var req messages.AdjustBalanceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdjustBody)).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	userID := chi.URLParam(r, "id")
+	table := s.Tables.Locate(userID)
+	if table == nil {
+		http.Error(w, game.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	adjustment, err := table.Manager.AdjustBalance(userID, req.Amount, req.Note)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, game.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, game.ErrInsufficientBalance):
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, messages.TransactionsResponse{Transactions: []messages.Transaction{adjustment}})
+}
+
+// HandleReconcile checks the balance of every user at every table against
+// the ledger.
+func (s *Server) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	res := messages.ReconcileResponse{Mismatches: []messages.LedgerMismatch{}}
+	for _, t := range s.Tables.Tables() {
+		tr, err := t.Manager.Reconcile()
+		if err != nil {
+			slog.Error("failed to reconcile the ledger", "error", err, "table_id", t.ID)
+			http.Error(w, "failed to reconcile the ledger", http.StatusInternalServerError)
+			return
+		}
+		res.Checked += tr.Checked
+		res.Mismatches = append(res.Mismatches, tr.Mismatches...)
+	}
+	writeJSON(w, res)
+}
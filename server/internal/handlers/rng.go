@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"roulette/internal/game"
+)
+
+// HandleRNGReport returns the RNG monitor's latest test results.
+// It needs the table's admin token as a bearer token.
+func (s *Server) HandleRNGReport(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	writeRNGReport(w, m)
+}
+
+// HandleRNGResume lets an admin resume a table the RNG monitor paused.
+// It answers 409 Conflict if the table is not paused.
+func (s *Server) HandleRNGResume(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeAdmin(w, r) {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	if err := m.ResumeRNG(); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, game.ErrNoRNGMonitor) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeRNGReport(w, m)
+}
+
+func writeRNGReport(w http.ResponseWriter, m *game.Manager) {
+	report, ok := m.RNGReport()
+	if !ok {
+		http.Error(w, game.ErrNoRNGMonitor.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		slog.Error("failed to write RNG report", "error", err)
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"roulette/internal/messages"
+)
+
+// Page sizes for GET /rounds and the transaction list.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// HandleRounds lists rounds from the history, newest first. limit caps the
+// page (default 20, at most 100) and before pages back from a round ID.
+func (s *Server) HandleRounds(w http.ResponseWriter, r *http.Request) {
+	before, limit, ok := parsePage(w, r)
+	if !ok {
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	rounds, err := m.Rounds(before, limit)
+	if err != nil {
+		slog.Error("failed to list rounds", "error", err)
+		http.Error(w, "failed to list rounds", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, messages.RoundsResponse{Rounds: rounds})
+}
+
+// HandleRound returns one round from the history.
+func (s *Server) HandleRound(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid round ID", http.StatusBadRequest)
+		return
+	}
+	m, ok := s.manager(w, r)
+	if !ok {
+		return
+	}
+	round, ok, err := m.Round(id)
+	if err != nil {
+		slog.Error("failed to load round", "error", err, "round", id)
+		http.Error(w, "failed to load round", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "round not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, round)
+}
+
+// parsePage reads the before and limit query parameters of a paged list,
+// answering 400 if either is invalid.
+func parsePage(w http.ResponseWriter, r *http.Request) (before int64, limit int, ok bool) {
+	return parseCursor(w, r, "before")
+}
+
+// parseCursor reads limit and the ID query parameter named cursor that a
+// paged list starts from, answering 400 if either is invalid.
+func parseCursor(w http.ResponseWriter, r *http.Request, cursor string) (id int64, limit int, ok bool) {
+	limit = defaultPageLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = min(n, maxPageLimit)
+	}
+	if raw := r.URL.Query().Get(cursor); raw != "" {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 1 {
+			http.Error(w, cursor+" must be a positive ID", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		id = n
+	}
+	return id, limit, true
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"roulette/internal/config"
 	"roulette/internal/game"
+	"roulette/internal/messages"
 	"roulette/internal/ws"
 	"time"
 
@@ -14,24 +18,244 @@ import (
 
 type Server struct {
 	Hub            *ws.Hub
-	GameManager    *game.Manager
+	Tables         *game.TableRegistry
 	AllowedOrigins []string
+	store          *game.BoltStore // nil when users are kept in memory
 }
 
-func NewServer(allowedOrigins []string) *Server {
+func NewServer(cfg *config.Config) (*Server, error) {
+	var store *game.BoltStore
+	if cfg.DBPath != "" {
+		var err error
+		if store, err = game.OpenBoltStore(cfg.DBPath); err != nil {
+			return nil, err
+		}
+	}
+
 	hub := ws.NewHub()
 	go hub.Run()
 
-	gm := game.NewManager(hub.BroadcastToAll, hub.SendToUser)
-	gm.SetConnectionChecker(hub)
-	hub.SetGameManager(gm)
-	go gm.RunGameLoop()
+	// Players keep one balance from table to table, so the tables share
+	// the users and the ledger.
+	var users game.UserStore = game.NewMemoryUserStore()
+	var ledger game.LedgerStore = game.NewMemoryLedger()
+	if store != nil {
+		users, ledger = store, store
+	}
+
+	tables := game.NewTableRegistry()
+	for _, tc := range cfg.Tables {
+		if err := addTable(tables, cfg, tc, hub, store, users, ledger); err != nil {
+			if store != nil {
+				store.Close()
+			}
+			return nil, err
+		}
+	}
+	if store != nil {
+		if err := recoverPrivateTables(tables, cfg, hub, store, users, ledger); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	// Private tables play like the first table, without a live dealer.
+	private := cfg.Tables[0]
+	private.LiveDealer = false
+	tables.SetTableFactory(func(id string) (*game.Manager, error) {
+		tc := private
+		tc.ID = id
+		return newTableManager(cfg, tc, hub, store, users, ledger)
+	})
+	tables.SetIdleTimeout(cfg.PrivateTableIdle)
+	if store != nil {
+		// The closed table's loop may not have stopped yet, so its buckets
+		// are dropped at the next startup rather than now.
+		tables.SetClosedHook(func(id string) {
+			if err := store.MarkTableClosed(id); err != nil {
+				slog.Error("failed to mark private table closed", "table_id", id, "error", err)
+			}
+		})
+	}
+	hub.SetTables(tables)
+	tables.RunGameLoops()
 
 	return &Server{
 		Hub:            hub,
-		GameManager:    gm,
-		AllowedOrigins: allowedOrigins,
+		Tables:         tables,
+		AllowedOrigins: cfg.AllowedOrigins,
+		store:          store,
+	}, nil
+}
+
+// addTable sets up a manager for table tc, adds it to tables and finishes
+// any round the table was settling when the server stopped.
+func addTable(tables *game.TableRegistry, cfg *config.Config, tc config.TableConfig, hub *ws.Hub, store *game.BoltStore, users game.UserStore, ledger game.LedgerStore) error {
+	gm, err := newTableManager(cfg, tc, hub, store, users, ledger)
+	if err != nil {
+		return err
+	}
+	// Adding the table sets the ID that recovery finds its bets by.
+	if _, err := tables.Add(tc.ID, tc.Name, gm); err != nil {
+		return err
+	}
+	return gm.RecoverSettlement()
+}
+
+// recoverPrivateTables settles up the tables in the store that are not
+// configured, such as private tables, which don't outlast a restart: rounds
+// open or settling when the server stopped are finished, and bets held En
+// Prison are refunded. Tables that were closed have nothing left to settle.
+// Private tables' buckets are then dropped, as nothing will use them again.
+func recoverPrivateTables(tables *game.TableRegistry, cfg *config.Config, hub *ws.Hub, store *game.BoltStore, users game.UserStore, ledger game.LedgerStore) error {
+	ids, err := store.TableIDs()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, ok := tables.Get(id); ok {
+			continue
+		}
+		closed, err := store.TableClosed(id)
+		if err != nil {
+			return err
+		}
+		if !closed {
+			if err := recoverClosedTable(id, cfg, hub, store, users, ledger); err != nil {
+				return err
+			}
+		}
+		if game.IsPrivateTableID(id) {
+			if err := store.DropTable(id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recoverClosedTable settles up table id, which is no longer configured.
+func recoverClosedTable(id string, cfg *config.Config, hub *ws.Hub, store *game.BoltStore, users game.UserStore, ledger game.LedgerStore) error {
+	tc := cfg.Tables[0]
+	tc.ID = id
+	gm, err := newTableManager(cfg, tc, hub, store, users, ledger)
+	if err != nil {
+		return err
+	}
+	gm.SetTableID(id)
+	err = gm.RecoverClosedTable()
+	gm.Stop()
+	return err
+}
+
+// newTableManager sets up a manager for table tc.
+func newTableManager(cfg *config.Config, tc config.TableConfig, hub *ws.Hub, store *game.BoltStore, users game.UserStore, ledger game.LedgerStore) (*game.Manager, error) {
+	wheel, ok := game.WheelFor(messages.WheelVariant(tc.WheelVariant))
+	if !ok {
+		slog.Warn("unknown wheel variant, falling back to european", "table_id", tc.ID, "variant", tc.WheelVariant)
+		wheel = game.EuropeanWheel
+	}
+
+	zeroRule := messages.ZeroRule(tc.ZeroRule)
+	switch zeroRule {
+	case messages.ZeroRuleNone, messages.ZeroRuleLaPartage, messages.ZeroRuleEnPrison:
+	default:
+		slog.Warn("unknown zero rule, falling back to none", "table_id", tc.ID, "zero_rule", tc.ZeroRule)
+		zeroRule = messages.ZeroRuleNone
+	}
+
+	gm := game.NewManager(func(msg []byte) { hub.BroadcastToTable(tc.ID, msg) }, hub.SendToUser)
+	gm.SetSpectatorBroadcast(func(msg []byte) { hub.BroadcastToSpectators(tc.ID, msg) })
+	gm.SetSeatedHook(func(userID string) { hub.Seated(tc.ID, userID) })
+	gm.SetConnectionChecker(hub)
+	gm.SetUserStore(users)
+	gm.SetLedger(ledger)
+	if store != nil {
+		view, err := store.Table(tc.ID)
+		if err != nil {
+			return nil, err
+		}
+		gm.SetRoundStore(view)
+		gm.SetSettlementStore(view)
+		gm.SetEventLog(view)
+	}
+	gm.SetWheel(wheel)
+	gm.SetZeroRule(zeroRule)
+	gm.SetLimits(tableLimits(tc))
+	gm.SetMaxSeats(tc.MaxSeats)
+	gm.SetSeatGrace(cfg.SeatGrace)
+	gm.SetPhaseDurations(game.PhaseDurations{
+		Betting:  time.Duration(tc.BettingSeconds) * time.Second,
+		Spinning: time.Duration(tc.SpinningSeconds) * time.Second,
+		Result:   time.Duration(tc.ResultSeconds) * time.Second,
+	})
+	if tc.PhysicsWheel {
+		gm.SetPhysics(game.DefaultPhysicsWheel())
+	}
+	gm.SetAdminToken(cfg.AdminToken)
+	if tc.LiveDealer {
+		if cfg.AdminToken == "" {
+			slog.Warn("live dealer mode without ADMIN_TOKEN: results cannot be entered and every round will be voided", "table_id", tc.ID)
+		}
+		gm.SetLiveDealer(cfg.DealerTimeout)
+	}
+	if len(cfg.ScriptedSpins) > 0 && !tc.LiveDealer {
+		rng, err := scriptedRNG(wheel, cfg.ScriptedSpins)
+		if err != nil {
+			return nil, err
+		}
+		slog.Warn("spins are scripted, not random", "table_id", tc.ID, "pockets", cfg.ScriptedSpins)
+		gm.SetRNG(rng)
+	}
+	if cfg.RNGMonitor {
+		gm.SetRNGMonitor(game.NewRNGMonitor(wheel, rngMonitorConfig(cfg)))
+	}
+	return gm, nil
+}
+
+// tableLimits converts a table's configured limits into game limits.
+func tableLimits(tc config.TableConfig) game.TableLimits {
+	limits := game.TableLimits{
+		Bets:           make(map[messages.BetType]game.BetLimit, len(tc.BetLimits)),
+		MaxPlayerStake: tc.MaxPlayerStake,
+		MaxLiability:   tc.MaxLiability,
+	}
+	for betType, l := range tc.BetLimits {
+		limits.Bets[messages.BetType(betType)] = game.BetLimit{Min: l.Min, Max: l.Max}
+	}
+	return limits
+}
+
+// scriptedRNG builds a source that lands on each of the labelled pockets in
+// turn, for development and QA runs.
+func scriptedRNG(wheel *game.Wheel, labels []string) (*game.ScriptedRNG, error) {
+	pockets := make([]int, len(labels))
+	for i, label := range labels {
+		n, ok := wheel.ParsePocket(label)
+		if !ok {
+			return nil, fmt.Errorf("scripted spin %q is not a pocket on a %s wheel", label, wheel.Variant)
+		}
+		pockets[i] = n
+	}
+	return game.NewScriptedRNG(pockets...), nil
+}
+
+// rngMonitorConfig overrides the default monitor settings with any configured ones.
+func rngMonitorConfig(cfg *config.Config) game.RNGMonitorConfig {
+	mc := game.DefaultRNGMonitorConfig()
+	if len(cfg.RNGWindows) > 0 {
+		mc.Windows = cfg.RNGWindows
+	}
+	if cfg.RNGInterval > 0 {
+		mc.Interval = cfg.RNGInterval
+	}
+	if cfg.RNGAlpha > 0 {
+		mc.Alpha = cfg.RNGAlpha
 	}
+	if cfg.RNGTolerance > 0 {
+		mc.Tolerance = cfg.RNGTolerance
+	}
+	return mc
 }
 
 func (s *Server) Routes() http.Handler {
@@ -55,9 +279,43 @@ func (s *Server) Routes() http.Handler {
 	// WebSocket endpoint
 	r.Get("/ws", s.HandleWebSocket)
 
+	// Lobby
+	r.Get("/tables", s.HandleTables)
+
+	// Recompute a past spin from its revealed seeds
+	r.Post("/fairness/verify", s.HandleFairnessVerify)
+
+	// Balance ledger, shared by every table
+	r.Get("/users/{id}/transactions", s.HandleTransactions)
+	r.Post("/admin/users/{id}/adjust", s.HandleAdjustBalance)
+	r.Get("/admin/ledger/reconcile", s.HandleReconcile)
+
+	// Each table's routes, also at the root for the default table
+	s.tableRoutes(r)
+	r.Route("/tables/{table}", s.tableRoutes)
+
 	return r
 }
 
+// tableRoutes mounts the routes that act on one table.
+func (s *Server) tableRoutes(r chi.Router) {
+	// Round history
+	r.Get("/rounds", s.HandleRounds)
+	r.Get("/rounds/{id}", s.HandleRound)
+
+	// Croupier input for live dealer tables
+	r.Post("/admin/dealer", s.HandleDealer)
+
+	// Event log, the table state built from it, and round replays
+	r.Get("/admin/events", s.HandleEvents)
+	r.Get("/admin/state", s.HandleTableState)
+	r.Get("/admin/rounds/{id}/replay", s.HandleReplayRound)
+
+	// RNG bias tests, and resuming a table the monitor paused
+	r.Get("/admin/rng", s.HandleRNGReport)
+	r.Post("/admin/rng/resume", s.HandleRNGResume)
+}
+
 func (s *Server) Start(ctx context.Context, addr string) error {
 	srv := &http.Server{
 		Addr:         addr,
@@ -75,10 +333,16 @@ func (s *Server) Start(ctx context.Context, addr string) error {
 	case err := <-errCh:
 		return err
 	case <-ctx.Done():
-		s.GameManager.Stop()
+		s.Tables.Stop()
 		s.Hub.Stop()
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
-		return srv.Shutdown(shutdownCtx)
+		err := srv.Shutdown(shutdownCtx)
+		if s.store != nil {
+			if closeErr := s.store.Close(); closeErr != nil {
+				slog.Error("failed to close store", "error", closeErr)
+			}
+		}
+		return err
 	}
 }
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"roulette/internal/game"
+	"roulette/internal/messages"
+)
+
+// HandleTables lists the tables for the lobby.
+func (s *Server) HandleTables(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, messages.TablesResponse{Tables: s.Tables.Infos()})
+}
+
+// manager returns the manager of the table in the request's path, or of the
+// default table on the routes without one, answering 404 if there is no
+// such table.
+func (s *Server) manager(w http.ResponseWriter, r *http.Request) (*game.Manager, bool) {
+	t, err := s.Tables.Lookup(chi.URLParam(r, "table"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return t.Manager, true
+}
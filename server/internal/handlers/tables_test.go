@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"roulette/internal/config"
+	"roulette/internal/game"
+	"roulette/internal/messages"
+	"roulette/internal/ws"
+)
+
+const testAdminToken = "secret"
+
+// newTestServer gives a server with two tables, main and side, sharing the
+// users and the ledger. Each has one round in its history: main round 1 and
+// side round 2.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	users := game.NewMemoryUserStore()
+	ledger := game.NewMemoryLedger()
+	tables := game.NewTableRegistry()
+	for i, id := range []string{"main", "side"} {
+		m := game.NewManager(func([]byte) {}, func(string, []byte) {})
+		t.Cleanup(func() { m.Stop() })
+		m.SetUserStore(users)
+		m.SetLedger(ledger)
+		m.SetAdminToken(testAdminToken)
+		rounds := game.NewMemoryRoundStore(10)
+		if err := rounds.PutRound(messages.Round{ID: int64(i + 1)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		m.SetRoundStore(rounds)
+		if _, err := tables.Add(id, id, m); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	return &Server{Tables: tables}
+}
+
+// get requests path from the server's routes as the admin.
+func get(s *Server, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, req)
+	return w
+}
+
+func TestTableRoutes(t *testing.T) {
+	s := newTestServer(t)
+	tests := []struct {
+		path   string
+		status int
+		rounds []int64
+	}{
+		{"/rounds", http.StatusOK, []int64{1}},
+		{"/tables/main/rounds", http.StatusOK, []int64{1}},
+		{"/tables/side/rounds", http.StatusOK, []int64{2}},
+		{"/rounds/2", http.StatusNotFound, nil},
+		{"/tables/side/rounds/2", http.StatusOK, nil},
+		{"/tables/side/rounds/1", http.StatusNotFound, nil},
+		{"/tables/nope/rounds", http.StatusNotFound, nil},
+		{"/tables/nope/admin/state", http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		w := get(s, tt.path)
+		if w.Code != tt.status {
+			t.Errorf("GET %s: expected %d, got %d: %s", tt.path, tt.status, w.Code, w.Body)
+			continue
+		}
+		if tt.rounds == nil {
+			continue
+		}
+		var res messages.RoundsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("GET %s: unexpected error: %v", tt.path, err)
+		}
+		var ids []int64
+		for _, r := range res.Rounds {
+			ids = append(ids, r.ID)
+		}
+		if !slices.Equal(ids, tt.rounds) {
+			t.Errorf("GET %s: expected rounds %v, got %v", tt.path, tt.rounds, ids)
+		}
+	}
+}
+
+func TestTableRoutes_StateIsPerTable(t *testing.T) {
+	s := newTestServer(t)
+	side, _ := s.Tables.Get("side")
+	side.Manager.RegisterUser("u1")
+
+	for path, want := range map[string]int{"/admin/state": 0, "/tables/main/admin/state": 0, "/tables/side/admin/state": 1} {
+		w := get(s, path)
+		var state messages.TableState
+		if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil {
+			t.Fatalf("GET %s: unexpected error: %v (%d %s)", path, err, w.Code, w.Body)
+		}
+		if len(state.Balances) != want {
+			t.Errorf("GET %s: expected %d users at the table, got %v", path, want, state.Balances)
+		}
+	}
+}
+
+func TestHandleReconcile_ChecksEveryTable(t *testing.T) {
+	s := newTestServer(t)
+	for _, id := range []string{"main", "side"} {
+		table, _ := s.Tables.Get(id)
+		table.Manager.RegisterUser("at-" + id)
+	}
+
+	w := get(s, "/admin/ledger/reconcile")
+	var res messages.ReconcileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected error: %v (%d %s)", err, w.Code, w.Body)
+	}
+	if res.Checked != 2 || len(res.Mismatches) != 0 {
+		t.Errorf("expected both tables' users checked and clean, got %+v", res)
+	}
+}
+
+func TestRecoverPrivateTables_DropsPrivateTables(t *testing.T) {
+	store, err := game.OpenBoltStore(filepath.Join(t.TempDir(), "roulette.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	for _, id := range []string{"side", "old", "p-open", "p-closed"} {
+		if _, err := store.Table(id); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := store.MarkTableClosed("p-closed"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tables := game.NewTableRegistry()
+	m := game.NewManager(func([]byte) {}, func(string, []byte) {})
+	t.Cleanup(func() { m.Stop() })
+	if _, err := tables.Add("side", "Side", m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &config.Config{Tables: []config.TableConfig{{ID: "side", WheelVariant: "european", ZeroRule: "none"}}}
+
+	if err := recoverPrivateTables(tables, cfg, ws.NewHub(), store, store, store); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids, err := store.TableIDs()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A table dropped from the config keeps its history.
+	if !slices.Equal(ids, []string{"old", "side"}) {
+		t.Errorf("expected only the configured and unconfigured tables kept, got %v", ids)
+	}
+}
@@ -1,5 +1,7 @@
 package messages
 
+import "time"
+
 // GamePhase represents the current phase of a game round.
 type GamePhase string
 
@@ -7,6 +9,37 @@ const (
 	GamePhaseBetting  GamePhase = "BETTING"
 	GamePhaseSpinning GamePhase = "SPINNING"
 	GamePhaseResult   GamePhase = "RESULT"
+	// GamePhasePaused holds the table between rounds after the RNG monitor
+	// flagged the wheel's outcomes, until an admin resumes it.
+	GamePhasePaused GamePhase = "PAUSED"
+)
+
+// WheelVariant names the roulette wheel a table spins.
+type WheelVariant string
+
+const (
+	WheelVariantEuropean   WheelVariant = "european"
+	WheelVariantAmerican   WheelVariant = "american"
+	WheelVariantTripleZero WheelVariant = "triple_zero"
+)
+
+// ZeroRule is the house rule for even-money bets when a zero comes up.
+type ZeroRule string
+
+const (
+	ZeroRuleNone      ZeroRule = "none"
+	ZeroRuleLaPartage ZeroRule = "la_partage"
+	ZeroRuleEnPrison  ZeroRule = "en_prison"
+)
+
+// PayoutOutcome marks payouts that a zero rule changed.
+type PayoutOutcome string
+
+const (
+	PayoutOutcomeHalved     PayoutOutcome = "halved"     // La Partage refunded half the stake
+	PayoutOutcomeImprisoned PayoutOutcome = "imprisoned" // En Prison holds the bet for the next spin
+	PayoutOutcomeReleased   PayoutOutcome = "released"   // an imprisoned bet won and its stake is returned
+	PayoutOutcomeForfeited  PayoutOutcome = "forfeited"  // an imprisoned bet lost
 )
 
 // BetType represents the type of bet a player can place.
@@ -17,20 +50,63 @@ const (
 	BetTypeColor    BetType = "color"
 	BetTypeEvenOdd  BetType = "even_odd"
 	BetTypeDozens   BetType = "dozens"
+	BetTypeColumns  BetType = "columns"
+	BetTypeHighLow  BetType = "high_low"
+	BetTypeSplit    BetType = "split"
+	BetTypeStreet   BetType = "street"
+	BetTypeCorner   BetType = "corner"
+	BetTypeSixLine  BetType = "six_line"
+	BetTypeTopLine  BetType = "top_line"
+
+	// Call (racetrack) bets expand into the inside bets above.
+	BetTypeVoisins    BetType = "voisins"
+	BetTypeTiers      BetType = "tiers"
+	BetTypeOrphelins  BetType = "orphelins"
+	BetTypeNeighbours BetType = "neighbours"
+
+	// Finale bets cover numbers by their last digit and also expand into inside bets.
+	BetTypeFinaleEnPlein BetType = "finale_en_plein"
+	BetTypeFinaleACheval BetType = "finale_a_cheval"
+)
+
+// BetRejectCode says why a bet was rejected, so clients can explain which limit was hit.
+type BetRejectCode string
+
+const (
+	BetRejectInvalidBet          BetRejectCode = "invalid_bet"
+	BetRejectBettingClosed       BetRejectCode = "betting_closed"
+	BetRejectInsufficientBalance BetRejectCode = "insufficient_balance"
+	BetRejectBelowMinimum        BetRejectCode = "below_minimum"
+	BetRejectAboveMaximum        BetRejectCode = "above_maximum"
+	BetRejectPlayerStakeLimit    BetRejectCode = "player_stake_limit"
+	BetRejectTableLiabilityLimit BetRejectCode = "table_liability_limit"
+	BetRejectBetNotFound         BetRejectCode = "bet_not_found"
+	BetRejectNoPreviousBets      BetRejectCode = "no_previous_bets"
+	BetRejectTooManyBets         BetRejectCode = "too_many_bets"
 )
 
-// Bet represents a single bet placed by a user.
+// Bet represents a single bet placed by a user. ID is assigned by the server.
+// A call bet lists the chips it expanded into as Components; its Amount is the total stake
+// and ChipAmount the stake on each chip.
 type Bet struct {
-	UserID string  `json:"user_id"`
-	Type   BetType `json:"type"`
-	Value  string  `json:"value"`
-	Amount int64   `json:"amount"`
+	ID         string  `json:"id,omitempty"`
+	UserID     string  `json:"user_id"`
+	Type       BetType `json:"type"`
+	Value      string  `json:"value"`
+	Amount     int64   `json:"amount"`
+	ChipAmount int64   `json:"chip_amount,omitempty"`
+	Components []Bet   `json:"components,omitempty"`
 }
 
 // Payout represents the result of a bet after a spin.
+// Refund is stake returned by a zero rule, on top of any winnings.
+// A call bet's payout groups one payout per chip under Components.
 type Payout struct {
-	Bet      Bet   `json:"bet"`
-	Winnings int64 `json:"winnings"`
+	Bet        Bet           `json:"bet"`
+	Winnings   int64         `json:"winnings"`
+	Refund     int64         `json:"refund,omitempty"`
+	Outcome    PayoutOutcome `json:"outcome,omitempty"`
+	Components []Payout      `json:"components,omitempty"`
 }
 
 // Player represents a connected player at the table.
@@ -41,21 +117,93 @@ type Player struct {
 	Connected bool   `json:"connected"`
 }
 
+// TableInfo describes a table in the lobby. MaxSeats is 0 for a table
+// without a seat limit; Queued is how many are waiting for a seat.
+type TableInfo struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Variant    WheelVariant `json:"variant"`
+	ZeroRule   ZeroRule     `json:"zero_rule"`
+	LiveDealer bool         `json:"live_dealer"`
+	State      GamePhase    `json:"state"`
+	Players    int          `json:"players"`
+	Spectators int          `json:"spectators"`
+	MaxSeats   int          `json:"max_seats"`
+	Queued     int          `json:"queued"`
+}
+
+// TablesResponse is the body of GET /tables. It lists the public tables.
+type TablesResponse struct {
+	Tables []TableInfo `json:"tables"`
+}
+
+// TableSettings are what the owner of a private table chooses. A MaxSeats
+// of 0 means no limit, and 0 seconds keeps a phase's default length.
+type TableSettings struct {
+	Password        string `json:"password,omitempty"`
+	MaxSeats        int    `json:"max_seats"`
+	BettingSeconds  int    `json:"betting_seconds"`
+	SpinningSeconds int    `json:"spinning_seconds"`
+	ResultSeconds   int    `json:"result_seconds"`
+}
+
 // --- Server → Client messages ---
 
+// WelcomeMessage seats the player at table TableID. OwnerID is set at a
+// private table.
 type WelcomeMessage struct {
-	Type         string   `json:"type"          tstype:"'welcome'"`
-	UserID       string   `json:"user_id"`
-	SessionToken string   `json:"session_token"`
-	Balance      int64    `json:"balance"`
-	Players      []Player `json:"players"`
+	Type         string       `json:"type"               tstype:"'welcome'"`
+	UserID       string       `json:"user_id"`
+	SessionToken string       `json:"session_token"`
+	TableID      string       `json:"table_id"`
+	OwnerID      string       `json:"owner_id,omitempty"`
+	Balance      int64        `json:"balance"`
+	Players      []Player     `json:"players"`
+	Spectators   int          `json:"spectators"`
+	Variant      WheelVariant `json:"variant"`
+	ZeroRule     ZeroRule     `json:"zero_rule"`
+	LiveDealer   bool         `json:"live_dealer"`
 }
 
+// Pocket numbers above 36 encode extra zeros (37 is "00", 38 is "000");
+// WinningPocket carries the label players should see.
 type GameStateMessage struct {
 	Type          string    `json:"type"           tstype:"'game_state'"`
 	State         GamePhase `json:"state"`
 	WinningNumber *int      `json:"winning_number,omitempty"`
+	WinningPocket *string   `json:"winning_pocket,omitempty"`
 	Countdown     *int      `json:"countdown,omitempty"`
+	// ServerSeedHash commits to the current round's server seed; Nonce is the round number.
+	ServerSeedHash *string `json:"server_seed_hash,omitempty"`
+	Nonce          *int64  `json:"nonce,omitempty"`
+	// Trajectory is sent with SPINNING when the table simulates its wheel.
+	Trajectory *Trajectory `json:"trajectory,omitempty"`
+	// Reason says why the table is PAUSED.
+	Reason *string `json:"reason,omitempty"`
+}
+
+// Trajectory is a server-simulated spin for clients to animate. It ends with
+// the ball in the winning pocket.
+//
+// Angles are in degrees clockwise from a fixed mark on the stator and are
+// cumulative, so clients can interpolate between keyframes and take them
+// modulo 360 to draw. RotorAngle is where the start of the first pocket in
+// Pockets sits; the rest follow clockwise, each 360/len(Pockets) wide.
+// BallRadius is a fraction of the ball track's radius: 1 on the track, 0.8
+// at the diamonds and 0.6 in a pocket.
+type Trajectory struct {
+	Pockets   []string             `json:"pockets"`
+	Drop      float64              `json:"drop"`   // seconds until the ball leaves the track
+	Landed    float64              `json:"landed"` // seconds until the ball settles
+	Keyframes []TrajectoryKeyframe `json:"keyframes"`
+}
+
+// TrajectoryKeyframe is the wheel T seconds after the spin started.
+type TrajectoryKeyframe struct {
+	T          float64 `json:"t"`
+	RotorAngle float64 `json:"rotor_angle"`
+	BallAngle  float64 `json:"ball_angle"`
+	BallRadius float64 `json:"ball_radius"`
 }
 
 type CountdownMessage struct {
@@ -65,33 +213,317 @@ type CountdownMessage struct {
 }
 
 type BetAcceptedMessage struct {
-	Type     string  `json:"type"      tstype:"'bet_accepted'"`
-	BetType  BetType `json:"bet_type"`
-	BetValue string  `json:"bet_value"`
-	Amount   int64   `json:"amount"`
-	Balance  int64   `json:"balance"`
+	Type       string  `json:"type"      tstype:"'bet_accepted'"`
+	BetID      string  `json:"bet_id"`
+	BetType    BetType `json:"bet_type"`
+	BetValue   string  `json:"bet_value"`
+	Amount     int64   `json:"amount"`
+	Components []Bet   `json:"components,omitempty"`
+	Balance    int64   `json:"balance"`
 }
 
 type BetRejectedMessage struct {
-	Type   string `json:"type"   tstype:"'bet_rejected'"`
-	Reason string `json:"reason"`
+	Type   string        `json:"type"   tstype:"'bet_rejected'"`
+	Code   BetRejectCode `json:"code"`
+	Reason string        `json:"reason"`
+}
+
+// BetsAcceptedMessage confirms a place_bets batch. Bets are in the order
+// they were sent, each with its server-assigned ID.
+type BetsAcceptedMessage struct {
+	Type    string `json:"type"    tstype:"'bets_accepted'"`
+	Bets    []Bet  `json:"bets"`
+	Balance int64  `json:"balance"`
+}
+
+// BetsRejectedMessage rejects a whole place_bets batch. Index is the
+// position of the bet that failed, or absent when the batch as a whole was
+// refused (e.g. the summed stake exceeds the balance).
+type BetsRejectedMessage struct {
+	Type   string        `json:"type"            tstype:"'bets_rejected'"`
+	Index  *int          `json:"index,omitempty"`
+	Code   BetRejectCode `json:"code"`
+	Reason string        `json:"reason"`
 }
 
 type ResultMessage struct {
 	Type          string   `json:"type"           tstype:"'result'"`
 	WinningNumber int      `json:"winning_number"`
+	WinningPocket string   `json:"winning_pocket"`
 	Payouts       []Payout `json:"payouts"`
 	TotalWon      int64    `json:"total_won"`
 	Balance       int64    `json:"balance"`
+	// Fairness reveals the round's server seed so the spin can be verified.
+	Fairness *FairnessReveal `json:"fairness,omitempty"`
+}
+
+// FairnessReveal discloses a settled round's seeds. The SHA-256 of ServerSeed
+// (hex) is ServerSeedHash, published when betting opened. The pocket comes
+// from an HMAC-SHA256 keyed with the server seed over the client seeds joined
+// by commas, a colon and the nonce.
+type FairnessReveal struct {
+	ServerSeed     string   `json:"server_seed"`
+	ServerSeedHash string   `json:"server_seed_hash"`
+	ClientSeeds    []string `json:"client_seeds"`
+	Nonce          int64    `json:"nonce"`
+}
+
+// ClientSeedMessage tells a player which client seed they contribute to spins.
+// Reason is set when a requested seed was rejected.
+type ClientSeedMessage struct {
+	Type       string `json:"type"             tstype:"'client_seed'"`
+	ClientSeed string `json:"client_seed"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// FairnessVerifyRequest is the body of POST /fairness/verify.
+type FairnessVerifyRequest struct {
+	Variant        WheelVariant `json:"variant"`
+	ServerSeed     string       `json:"server_seed"`
+	ServerSeedHash string       `json:"server_seed_hash"`
+	ClientSeeds    []string     `json:"client_seeds"`
+	Nonce          int64        `json:"nonce"`
+}
+
+// FairnessVerifyResponse reports whether a revealed seed matches its hash and,
+// if so, the pocket the round must have landed on.
+type FairnessVerifyResponse struct {
+	Valid         bool    `json:"valid"`
+	WinningNumber *int    `json:"winning_number,omitempty"`
+	WinningPocket *string `json:"winning_pocket,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// Round is a round as kept in the history. A voided live round has no
+// winning pocket and its bets were refunded. Payouts include any En Prison
+// bets from the round before that were settled in this one.
+type Round struct {
+	ID            int64        `json:"id"`
+	Variant       WheelVariant `json:"variant"`
+	StartedAt     time.Time    `json:"started_at"`
+	EndedAt       time.Time    `json:"ended_at"`
+	WinningNumber *int         `json:"winning_number,omitempty"`
+	WinningPocket *string      `json:"winning_pocket,omitempty"`
+	Voided        bool         `json:"voided,omitempty"`
+	VoidReason    string       `json:"void_reason,omitempty"`
+	Bets          []Bet        `json:"bets"`
+	Payouts       []Payout     `json:"payouts"`
+}
+
+// RoundsResponse is the body of GET /rounds, newest first. Pass the ID of
+// the last round as before to get the next page.
+type RoundsResponse struct {
+	Rounds []Round `json:"rounds"`
+}
+
+// RoundResult is where one round landed.
+type RoundResult struct {
+	RoundID       int64  `json:"round_id"`
+	WinningNumber int    `json:"winning_number"`
+	WinningPocket string `json:"winning_pocket"`
+}
+
+// HistoryMessage is sent on connect with the latest results, newest first.
+type HistoryMessage struct {
+	Type    string        `json:"type"    tstype:"'history'"`
+	Results []RoundResult `json:"results"`
+}
+
+// TransactionKind says why a balance changed.
+type TransactionKind string
+
+const (
+	TransactionOpening    TransactionKind = "opening"    // starting balance for a new user
+	TransactionBet        TransactionKind = "bet"        // stake placed on a bet
+	TransactionWin        TransactionKind = "win"        // winnings plus the returned stake
+	TransactionRefund     TransactionKind = "refund"     // stake returned: cancelled bet, voided round or zero rule
+	TransactionRefill     TransactionKind = "refill"     // top-up after the balance hit zero
+	TransactionAdjustment TransactionKind = "adjustment" // made by an admin
+)
+
+// Transaction is one entry in the balance ledger. Each moves Amount from
+// the Debit account to the Credit account: a user's ID or "house", so every
+// transaction balances. BalanceBefore and BalanceAfter are the user's.
+// RoundID is a round at table TableID.
+type Transaction struct {
+	ID            int64           `json:"id"`
+	UserID        string          `json:"user_id"`
+	TableID       string          `json:"table_id,omitempty"`
+	RoundID       int64           `json:"round_id,omitempty"`
+	Kind          TransactionKind `json:"kind"`
+	Debit         string          `json:"debit"`
+	Credit        string          `json:"credit"`
+	Amount        int64           `json:"amount"`
+	BalanceBefore int64           `json:"balance_before"`
+	BalanceAfter  int64           `json:"balance_after"`
+	BetID         string          `json:"bet_id,omitempty"`
+	Note          string          `json:"note,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// TransactionsResponse is the body of GET /users/{id}/transactions, newest
+// first. Pass the ID of the last transaction as before to get the next page.
+type TransactionsResponse struct {
+	Transactions []Transaction `json:"transactions"`
+}
+
+// AdjustBalanceRequest is the body of POST /admin/users/{id}/adjust. Amount
+// is added to the balance; a negative amount takes money off.
+type AdjustBalanceRequest struct {
+	Amount int64  `json:"amount"`
+	Note   string `json:"note"`
+}
+
+// LedgerMismatch is a user whose balance differs from the sum of their
+// ledger entries.
+type LedgerMismatch struct {
+	UserID        string `json:"user_id"`
+	Balance       int64  `json:"balance"`
+	LedgerBalance int64  `json:"ledger_balance"`
+}
+
+// ReconcileResponse is the body of GET /admin/ledger/reconcile.
+type ReconcileResponse struct {
+	Checked    int              `json:"checked"`
+	Mismatches []LedgerMismatch `json:"mismatches"`
+}
+
+// EventType names a change to the table's state.
+type EventType string
+
+const (
+	EventPhaseChanged    EventType = "phase_changed"    // the round moved to Phase
+	EventBetPlaced       EventType = "bet_placed"       // Bet was placed
+	EventBetCancelled    EventType = "bet_cancelled"    // Bet was taken back and its stake refunded
+	EventWheelSpun       EventType = "wheel_spun"       // the round landed on WinningNumber
+	EventPayoutCredited  EventType = "payout_credited"  // a win or refund on BetID was paid
+	EventUserRegistered  EventType = "user_registered"  // a new user opened with the starting balance
+	EventUserJoined      EventType = "user_joined"      // a user sat down at the table with Amount
+	EventUserLeft        EventType = "user_left"        // a user left the table
+	EventBalanceRefilled EventType = "balance_refilled" // a broke user was topped up
+	EventBalanceAdjusted EventType = "balance_adjusted" // an admin changed a balance
+)
+
+// Event is one change to the table's state, in the order Seq gives. Amount
+// is what the event adds to UserID's balance, negative for a stake, except
+// for user_joined, where it is the balance they sit down with.
+type Event struct {
+	Seq           int64     `json:"seq"`
+	Type          EventType `json:"type"`
+	Round         int64     `json:"round"`
+	At            time.Time `json:"at"`
+	Phase         GamePhase `json:"phase,omitempty"`
+	UserID        string    `json:"user_id,omitempty"`
+	Bet           *Bet      `json:"bet,omitempty"`
+	BetID         string    `json:"bet_id,omitempty"`
+	WinningNumber *int      `json:"winning_number,omitempty"`
+	Amount        int64     `json:"amount,omitempty"`
+}
+
+// TableState is the table as rebuilt from its events: the current round,
+// the bets in it and the balance of every user at the table. Seq is the
+// last event applied.
+type TableState struct {
+	Seq           int64            `json:"seq"`
+	Round         int64            `json:"round"`
+	Phase         GamePhase        `json:"phase"`
+	WinningNumber *int             `json:"winning_number,omitempty"`
+	Bets          []Bet            `json:"bets"`
+	Balances      map[string]int64 `json:"balances"`
+}
+
+// EventsResponse is the body of GET /admin/events, oldest first. Pass the
+// Seq of the last event as after to get the next page.
+type EventsResponse struct {
+	Events []Event `json:"events"`
+}
+
+// RoundReplay is the body of GET /admin/rounds/{id}/replay: the table as the
+// round opened, its events, and the table they leave behind.
+type RoundReplay struct {
+	Round  int64      `json:"round"`
+	Start  TableState `json:"start"`
+	Events []Event    `json:"events"`
+	End    TableState `json:"end"`
+}
+
+// RNGTest names a statistical test the RNG monitor runs on the outcomes.
+type RNGTest string
+
+const (
+	RNGTestChiSquare RNGTest = "chi_square" // every pocket comes up equally often
+	RNGTestRuns      RNGTest = "runs"       // low and high pockets don't cluster or alternate
+	RNGTestGap       RNGTest = "gap"        // spins between repeats of a pocket are geometric
+)
+
+// RNGTestResult is one test over the most recent Window spins. PValue is the
+// chance an unbiased wheel would look at least this far off; the test fails
+// when it is below the monitor's alpha. Failures counts consecutive failures.
+type RNGTestResult struct {
+	Test      RNGTest `json:"test"`
+	Window    int     `json:"window"`
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"p_value"`
+	Passed    bool    `json:"passed"`
+	Failures  int     `json:"failures"`
+}
+
+// RNGReport is the body of GET /admin/rng: the latest result of each test
+// over each window, and whether the monitor has paused the table.
+type RNGReport struct {
+	Spins     int64           `json:"spins"`
+	Alpha     float64         `json:"alpha"`
+	Tolerance int             `json:"tolerance"`
+	Paused    bool            `json:"paused"`
+	Reason    string          `json:"reason,omitempty"`
+	Results   []RNGTestResult `json:"results"`
 }
 
 type BetPlacedMessage struct {
 	Type       string  `json:"type"        tstype:"'bet_placed'"`
+	BetID      string  `json:"bet_id"`
 	UserID     string  `json:"user_id"`
 	PlayerName string  `json:"player_name"`
 	BetType    BetType `json:"bet_type"`
 	BetValue   string  `json:"bet_value"`
 	Amount     int64   `json:"amount"`
+	Components []Bet   `json:"components,omitempty"`
+}
+
+// BetRemovedMessage tells every client which of a player's bets were taken off the table.
+type BetRemovedMessage struct {
+	Type    string   `json:"type"    tstype:"'bet_removed'"`
+	UserID  string   `json:"user_id"`
+	BetIDs  []string `json:"bet_ids"`
+	Balance int64    `json:"balance"`
+}
+
+// RoundVoidedMessage tells a player the round was called off without a
+// result and their bets in it (Refunded in total) were returned.
+type RoundVoidedMessage struct {
+	Type     string `json:"type"     tstype:"'round_voided'"`
+	Reason   string `json:"reason"`
+	Refunded int64  `json:"refunded"`
+	Balance  int64  `json:"balance"`
+}
+
+// DealerStep is a croupier action in live dealer mode.
+type DealerStep string
+
+const (
+	DealerStepEnter   DealerStep = "enter"
+	DealerStepConfirm DealerStep = "confirm"
+	DealerStepVoid    DealerStep = "void"
+)
+
+// DealerAckMessage answers a croupier action, over WebSocket or HTTP.
+// Pocket is the result that was entered or confirmed.
+type DealerAckMessage struct {
+	Type   string     `json:"type"             tstype:"'dealer_ack'"`
+	Step   DealerStep `json:"step"`
+	OK     bool       `json:"ok"`
+	Pocket string     `json:"pocket,omitempty"`
+	Reason string     `json:"reason,omitempty"`
 }
 
 type PlayerListMessage struct {
@@ -120,6 +552,78 @@ type SessionExpiredMessage struct {
 	Reason string `json:"reason"`
 }
 
+// TablesMessage answers list_tables.
+type TablesMessage struct {
+	Type   string      `json:"type"   tstype:"'tables'"`
+	Tables []TableInfo `json:"tables"`
+}
+
+// TableLeftMessage puts the player back in the lobby: after leave_table, or
+// with a Reason when the table's owner kicked them or the table closed. A
+// player waiting for a seat gets one too if the table closes.
+type TableLeftMessage struct {
+	Type    string `json:"type"             tstype:"'table_left'"`
+	TableID string `json:"table_id"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// PrivateTableMessage tells the owner of a private table how others join it
+// and what it is set to, after create_table and update_table.
+type PrivateTableMessage struct {
+	Type       string        `json:"type"        tstype:"'private_table'"`
+	TableID    string        `json:"table_id"`
+	InviteCode string        `json:"invite_code"`
+	Settings   TableSettings `json:"settings"`
+}
+
+// QueuePositionMessage tells a player turned away from a full table where
+// they stand in line for a seat there, when they join the line and as it
+// moves. They are seated, with a welcome, at the end of a round once a
+// seat is free.
+type QueuePositionMessage struct {
+	Type     string `json:"type"     tstype:"'queue_position'"`
+	TableID  string `json:"table_id"`
+	Position int    `json:"position"`
+}
+
+// WatchingMessage answers watch_table: the spectator is now watching the
+// table, followed by its game_state and history as a player would get.
+type WatchingMessage struct {
+	Type       string       `json:"type"        tstype:"'watching'"`
+	TableID    string       `json:"table_id"`
+	Players    []Player     `json:"players"`
+	Spectators int          `json:"spectators"`
+	Variant    WheelVariant `json:"variant"`
+	ZeroRule   ZeroRule     `json:"zero_rule"`
+	LiveDealer bool         `json:"live_dealer"`
+}
+
+// SpectatorsMessage tells the table how many are watching it. It is sent
+// at the start of a round when the count has changed.
+type SpectatorsMessage struct {
+	Type  string `json:"type"  tstype:"'spectators'"`
+	Count int    `json:"count"`
+}
+
+// RoundResultMessage shows spectators a round's result. Players get their
+// own ResultMessage instead. TotalWon is what the round returned to every
+// player at the table.
+type RoundResultMessage struct {
+	Type          string `json:"type"           tstype:"'round_result'"`
+	WinningNumber int    `json:"winning_number"`
+	WinningPocket string `json:"winning_pocket"`
+	TotalWon      int64  `json:"total_won"`
+	// Fairness reveals the round's server seed so the spin can be verified.
+	Fairness *FairnessReveal `json:"fairness,omitempty"`
+}
+
+// TableRejectedMessage says why the player couldn't join or leave a table.
+type TableRejectedMessage struct {
+	Type    string `json:"type"     tstype:"'table_rejected'"`
+	TableID string `json:"table_id"`
+	Reason  string `json:"reason"`
+}
+
 // --- Client → Server messages ---
 
 type PlaceBetAction struct {
@@ -129,14 +633,147 @@ type PlaceBetAction struct {
 	Amount   int64   `json:"amount"`
 }
 
+// BetSpec is one bet in a place_bets batch.
+type BetSpec struct {
+	BetType  BetType `json:"bet_type"`
+	BetValue string  `json:"bet_value"`
+	Amount   int64   `json:"amount"`
+}
+
+type PlaceBetsAction struct {
+	Action string    `json:"action" tstype:"'place_bets'"`
+	Bets   []BetSpec `json:"bets"`
+}
+
+type SetClientSeedAction struct {
+	Action     string `json:"action"      tstype:"'set_client_seed'"`
+	ClientSeed string `json:"client_seed"`
+}
+
+type CancelBetAction struct {
+	Action string `json:"action" tstype:"'cancel_bet'"`
+	BetID  string `json:"bet_id"`
+}
+
+type UndoLastBetAction struct {
+	Action string `json:"action" tstype:"'undo_last_bet'"`
+}
+
+type ClearBetsAction struct {
+	Action string `json:"action" tstype:"'clear_bets'"`
+}
+
+type RebetAction struct {
+	Action string `json:"action" tstype:"'rebet'"`
+}
+
+type RebetDoubleAction struct {
+	Action string `json:"action" tstype:"'rebet_double'"`
+}
+
+// SetNameAction joins as a new player at TableID, or the first table if
+// it is empty. A private table is joined by InviteCode instead, with its
+// Password if it has one.
 type SetNameAction struct {
-	Action string `json:"action" tstype:"'set_name'"`
-	Name   string `json:"name"`
+	Action     string `json:"action"                tstype:"'set_name'"`
+	Name       string `json:"name"`
+	TableID    string `json:"table_id,omitempty"`
+	InviteCode string `json:"invite_code,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+// DealerRequest is the body of POST /admin/dealer.
+type DealerRequest struct {
+	Step   DealerStep `json:"step"`
+	Pocket string     `json:"pocket"`
+	Reason string     `json:"reason"`
+}
+
+// DealerResultAction enters (step "enter") or confirms (step "confirm") the
+// winning pocket in live dealer mode at table TableID, or else the table the
+// connection is at or watching, or the first table. The two entries must
+// match. The croupier doesn't need a seat.
+type DealerResultAction struct {
+	Action     string     `json:"action"             tstype:"'dealer_result'"`
+	AdminToken string     `json:"admin_token"`
+	TableID    string     `json:"table_id,omitempty"`
+	Step       DealerStep `json:"step"`
+	Pocket     string     `json:"pocket"`
+}
+
+// DealerVoidAction calls off the current live round at the same table as
+// DealerResultAction and refunds its bets.
+type DealerVoidAction struct {
+	Action     string `json:"action"             tstype:"'dealer_void'"`
+	AdminToken string `json:"admin_token"`
+	TableID    string `json:"table_id,omitempty"`
+	Reason     string `json:"reason"`
 }
 
+// ReconnectAction returns a player to the table they were at, or else to
+// TableID, or the first table if it is empty.
 type ReconnectAction struct {
-	Action       string `json:"action"        tstype:"'reconnect'"`
+	Action       string `json:"action"             tstype:"'reconnect'"`
 	UserID       string `json:"user_id"`
 	SessionToken string `json:"session_token"`
 	Name         string `json:"name"`
+	TableID      string `json:"table_id,omitempty"`
+}
+
+type ListTablesAction struct {
+	Action string `json:"action" tstype:"'list_tables'"`
+}
+
+// JoinTableAction moves the player to another table, or seats them from
+// the lobby. A private table is joined by InviteCode instead of TableID,
+// with its Password if it has one.
+type JoinTableAction struct {
+	Action     string `json:"action"                tstype:"'join_table'"`
+	TableID    string `json:"table_id,omitempty"`
+	InviteCode string `json:"invite_code,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+// LeaveTableAction takes the player, or a spectator, back to the lobby, or
+// out of line for a seat. It is refused while a player has bets in play.
+type LeaveTableAction struct {
+	Action string `json:"action" tstype:"'leave_table'"`
+}
+
+// CreateTableAction opens a private table owned by the player and moves
+// them to it.
+type CreateTableAction struct {
+	Action   string        `json:"action"   tstype:"'create_table'"`
+	Name     string        `json:"name"`
+	Settings TableSettings `json:"settings"`
+}
+
+// UpdateTableAction changes the settings of the private table the player
+// owns. New phase lengths apply from the next phase.
+type UpdateTableAction struct {
+	Action   string        `json:"action"   tstype:"'update_table'"`
+	Settings TableSettings `json:"settings"`
+}
+
+// KickPlayerAction sends a player at the owner's private table back to the
+// lobby. They can't rejoin it.
+type KickPlayerAction struct {
+	Action string `json:"action"  tstype:"'kick_player'"`
+	UserID string `json:"user_id"`
+}
+
+// WatchTableAction watches a table as a spectator, without a seat or a
+// balance. A seated player gives up their seat to watch. A private table is
+// watched by InviteCode instead of TableID, with its Password if it has one.
+type WatchTableAction struct {
+	Action     string `json:"action"                tstype:"'watch_table'"`
+	TableID    string `json:"table_id,omitempty"`
+	InviteCode string `json:"invite_code,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+// CloseTableAction closes the owner's private table once no bets are in
+// play, sending everyone at it back to the lobby.
+type CloseTableAction struct {
+	Action string `json:"action" tstype:"'close_table'"`
 }
@@ -3,9 +3,11 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"time"
 
+	"roulette/internal/game"
 	"roulette/internal/messages"
 
 	"github.com/coder/websocket"
@@ -14,24 +16,39 @@ import (
 const (
 	writeWait      = 10 * time.Second
 	pingPeriod     = 54 * time.Second
-	maxMessageSize = 1024
+	maxMessageSize = 8192 // a place_bets batch of game.MaxBatchBets bets
 )
 
 type Client struct {
-	Hub    *Hub
-	conn   *websocket.Conn
-	Send   chan []byte
-	UserID string
+	Hub        *Hub
+	conn       *websocket.Conn
+	Send       chan []byte
+	UserID     string
+	table      *game.Table // the table the player is at; nil in the lobby
+	watching   *game.Table // the table the client is spectating, if any
+	queued     *game.Table // the full table the player is waiting for a seat at, if any
+	registered bool        // whether UserID is a player, seated or not
 }
 
 type ClientMessage struct {
-	Action       string `json:"action"`
-	BetType      string `json:"bet_type"`
-	BetValue     string `json:"bet_value"`
-	Amount       int64  `json:"amount"`
-	Name         string `json:"name"`
-	UserID       string `json:"user_id"`
-	SessionToken string `json:"session_token"`
+	Action       string                 `json:"action"`
+	BetType      string                 `json:"bet_type"`
+	BetValue     string                 `json:"bet_value"`
+	Amount       int64                  `json:"amount"`
+	Bets         []messages.BetSpec     `json:"bets"`
+	Name         string                 `json:"name"`
+	UserID       string                 `json:"user_id"`
+	SessionToken string                 `json:"session_token"`
+	BetID        string                 `json:"bet_id"`
+	ClientSeed   string                 `json:"client_seed"`
+	AdminToken   string                 `json:"admin_token"`
+	TableID      string                 `json:"table_id"`
+	InviteCode   string                 `json:"invite_code"`
+	Password     string                 `json:"password"`
+	Settings     messages.TableSettings `json:"settings"`
+	Step         string                 `json:"step"`
+	Pocket       string                 `json:"pocket"`
+	Reason       string                 `json:"reason"`
 }
 
 func NewClient(hub *Hub, conn *websocket.Conn, userID string) *Client {
@@ -68,10 +85,12 @@ func (c *Client) trySend(data []byte) bool {
 
 func (c *Client) ReadPump() {
 	defer func() {
-		if c.Hub.gameManager != nil {
-			c.Hub.gameManager.NotifyPlayerLeft(c.UserID)
-			c.Hub.gameManager.MarkUserDisconnected(c.UserID)
+		c.leaveQueue()
+		if c.table != nil {
+			c.table.Manager.NotifyPlayerLeft(c.UserID)
+			c.table.Manager.MarkUserDisconnected(c.UserID)
 		}
+		c.stopWatching()
 		c.Hub.Unregister(c)
 		c.conn.CloseNow()
 	}()
@@ -89,17 +108,87 @@ func (c *Client) ReadPump() {
 			continue
 		}
 
-		if c.Hub.gameManager == nil {
+		if c.Hub.tables == nil {
 			continue
 		}
+		// A player seated from the queue is at that table now; one turned
+		// away when it closed is still in the lobby.
+		if c.queued != nil && c.queued.Manager.QueuePosition(c.UserID) == 0 {
+			if c.queued.Manager.GetUser(c.UserID) != nil {
+				c.table = c.queued
+			}
+			c.queued = nil
+		}
+		// A player kicked from their table, or whose table closed, is back
+		// in the lobby.
+		if c.table != nil && c.table.Manager.GetUser(c.UserID) == nil {
+			c.table = nil
+		}
+		// So is a spectator whose table closed.
+		if c.watching != nil {
+			if t, ok := c.Hub.tables.Get(c.watching.ID); !ok || t != c.watching {
+				c.stopWatching()
+			}
+		}
 
 		switch msg.Action {
+		case "list_tables":
+			c.handleListTables()
+			continue
 		case "reconnect":
 			c.handleReconnect(msg)
+			continue
 		case "set_name":
 			c.handleSetName(msg)
+			continue
+		case "join_table":
+			c.handleJoinTable(msg)
+			continue
+		case "create_table":
+			c.handleCreateTable(msg)
+			continue
+		case "watch_table":
+			c.handleWatchTable(msg)
+			continue
+		case "leave_table":
+			c.handleLeaveTable()
+			continue
+		case "dealer_result":
+			c.handleDealerResult(msg)
+			continue
+		case "dealer_void":
+			c.handleDealerStep(messages.DealerStepVoid, msg)
+			continue
+		}
+
+		// Everything else is done at a table.
+		if c.table == nil {
+			continue
+		}
+
+		switch msg.Action {
+		case "update_table":
+			c.handleUpdateTable(msg)
+		case "kick_player":
+			c.handleKickPlayer(msg)
+		case "close_table":
+			c.handleCloseTable()
+		case "set_client_seed":
+			c.handleSetClientSeed(msg)
 		case "place_bet":
 			c.handlePlaceBet(msg)
+		case "place_bets":
+			c.handlePlaceBets(msg)
+		case "cancel_bet":
+			c.handleCancelBet(msg)
+		case "undo_last_bet":
+			c.handleUndoLastBet()
+		case "clear_bets":
+			c.handleClearBets()
+		case "rebet":
+			c.handleRebet(false)
+		case "rebet_double":
+			c.handleRebet(true)
 		}
 	}
 }
@@ -138,14 +227,35 @@ func (c *Client) WritePump() {
 }
 
 func (c *Client) handleReconnect(msg ClientMessage) {
-	if c.Hub.gameManager.ValidateSessionToken(msg.UserID, msg.SessionToken) {
+	c.leaveQueue()
+	// A player still seated somewhere goes back to that table, whichever
+	// they asked for. One who isn't is seated at it again, or waits in line
+	// if it is full.
+	table := c.Hub.tables.Locate(msg.UserID)
+	if table == nil && c.Hub.tables.CheckSessionToken(msg.UserID, msg.SessionToken) {
+		var err error
+		if table, err = c.Hub.tables.Enter(msg.TableID, "", "", msg.UserID); err == nil {
+			_, err = table.Manager.AdmitUser(msg.UserID)
+		}
+		if err != nil {
+			c.UserID = msg.UserID
+			c.registered = true
+			c.seatRefused(msg.TableID, table, "", err)
+			return
+		}
+	}
+
+	if table != nil && table.Manager.ValidateSessionToken(msg.UserID, msg.SessionToken) {
 		c.UserID = msg.UserID
-		c.Hub.gameManager.SetUserName(msg.UserID, msg.Name)
-		c.Hub.gameManager.MarkUserReconnected(msg.UserID)
+		c.table = table
+		c.registered = true
+		table.Manager.SetUserName(msg.UserID, msg.Name)
+		table.Manager.MarkUserReconnected(msg.UserID)
 
-		c.Hub.Register(c)
+		c.stopWatching()
+		c.Hub.Register(c, table.ID)
 		c.sendSessionData()
-		c.Hub.gameManager.NotifyPlayerJoined(c.UserID)
+		table.Manager.NotifyPlayerJoined(c.UserID)
 	} else {
 		// Either the user was cleaned up or the token is invalid.
 		c.trySend(mustJSON(messages.SessionExpiredMessage{
@@ -156,70 +266,543 @@ func (c *Client) handleReconnect(msg ClientMessage) {
 }
 
 func (c *Client) handleSetName(msg ClientMessage) {
-	c.Hub.gameManager.RegisterUser(c.UserID)
-	c.Hub.gameManager.SetUserName(c.UserID, msg.Name)
+	switch {
+	case c.table != nil:
+		// Already seated: only their name changes.
+	case c.registered:
+		// Back from the lobby: sit them down with the balance they left with.
+		if _, ok := c.moveTo(msg); !ok {
+			return
+		}
+	default:
+		table, err := c.Hub.tables.Enter(msg.TableID, msg.InviteCode, msg.Password, c.UserID)
+		if err == nil {
+			_, err = table.Manager.JoinUser(c.UserID)
+		}
+		if err != nil {
+			c.seatRefused(msg.TableID, table, msg.Name, err)
+			return
+		}
+		c.table = table
+		c.registered = true
+	}
+	c.table.Manager.SetUserName(c.UserID, msg.Name)
+
+	c.stopWatching()
+	c.Hub.Register(c, c.table.ID)
+	c.sendSessionData()
+	c.table.Manager.NotifyPlayerJoined(c.UserID)
+}
+
+func (c *Client) handleListTables() {
+	c.trySend(mustJSON(messages.TablesMessage{Type: "tables", Tables: c.Hub.tables.Infos()}))
+}
 
-	c.Hub.Register(c)
+// handleJoinTable moves the player to another table, or seats them from the
+// lobby, with the balance they have.
+func (c *Client) handleJoinTable(msg ClientMessage) {
+	from, ok := c.moveTo(msg)
+	if !ok {
+		return
+	}
+	c.seated(from)
+}
+
+// seated tells the player, and the tables they moved between, that they
+// are now at c.table.
+func (c *Client) seated(from *game.Table) {
+	c.stopWatching()
+	c.Hub.Register(c, c.table.ID)
+	if from != nil {
+		from.Manager.NotifyPlayerLeft(c.UserID)
+	}
 	c.sendSessionData()
-	c.Hub.gameManager.NotifyPlayerJoined(c.UserID)
+	c.table.Manager.NotifyPlayerJoined(c.UserID)
+}
+
+// moveTo takes the player from their table, or the lobby, to the table msg
+// asks for and returns the table they left. If they can't move they are
+// told why and stay where they were, or wait in line from the lobby if the
+// table is full.
+func (c *Client) moveTo(msg ClientMessage) (from *game.Table, ok bool) {
+	to, err := c.Hub.tables.Enter(msg.TableID, msg.InviteCode, msg.Password, c.UserID)
+	if err == nil && to != c.queued {
+		// Going elsewhere gives up their place in line.
+		c.leaveQueue()
+	}
+	if err == nil && to == c.table {
+		return nil, true
+	}
+	if err == nil {
+		err = c.Hub.tables.Move(c.UserID, c.table, to)
+	}
+	if errors.Is(err, game.ErrNotSeated) {
+		c.unseated(err)
+		return nil, false
+	}
+	if err != nil {
+		c.seatRefused(msg.TableID, to, msg.Name, err)
+		return nil, false
+	}
+	from, c.table = c.table, to
+	return from, true
+}
+
+// unseated returns the player to the lobby after a move that lost them
+// their seat at c.table without giving them one elsewhere, telling them why.
+func (c *Client) unseated(err error) {
+	left := c.table
+	c.table = nil
+	c.Hub.Register(c, "")
+	left.Manager.NotifyPlayerLeft(c.UserID)
+	c.trySend(mustJSON(messages.TableLeftMessage{Type: "table_left", TableID: left.ID, Reason: err.Error()}))
+}
+
+// handleCreateTable opens a private table owned by the player and moves
+// them to it.
+func (c *Client) handleCreateTable(msg ClientMessage) {
+	c.leaveQueue()
+	if !c.registered {
+		c.rejectTable("", game.ErrUserNotFound)
+		return
+	}
+	table, err := c.Hub.tables.CreatePrivate(c.UserID, msg.Name, msg.Settings)
+	if err != nil {
+		c.rejectTable("", err)
+		return
+	}
+	from := c.table
+	if err := c.Hub.tables.Move(c.UserID, from, table); err != nil {
+		// Left empty, the table is torn down once it has been idle.
+		if errors.Is(err, game.ErrNotSeated) {
+			c.unseated(err)
+			return
+		}
+		c.rejectTable(table.ID, err)
+		return
+	}
+	c.table = table
+	c.sendPrivateTable()
+	c.seated(from)
+}
+
+func (c *Client) handleUpdateTable(msg ClientMessage) {
+	if err := c.table.UpdateSettings(c.UserID, msg.Settings); err != nil {
+		c.rejectTable(c.table.ID, err)
+		return
+	}
+	c.sendPrivateTable()
+}
+
+// sendPrivateTable tells the owner of a private table its invite code and
+// settings.
+func (c *Client) sendPrivateTable() {
+	c.trySend(mustJSON(messages.PrivateTableMessage{
+		Type:       "private_table",
+		TableID:    c.table.ID,
+		InviteCode: c.table.InviteCode(),
+		Settings:   c.table.Settings(),
+	}))
+}
+
+func (c *Client) handleKickPlayer(msg ClientMessage) {
+	if err := c.table.Kick(c.UserID, msg.UserID); err != nil {
+		c.rejectTable(c.table.ID, err)
+		return
+	}
+	c.Hub.SendToLobby(c.table.ID, []string{msg.UserID}, mustJSON(messages.TableLeftMessage{
+		Type:    "table_left",
+		TableID: c.table.ID,
+		Reason:  "kicked by the table owner",
+	}))
+	c.table.Manager.NotifyPlayerLeft(msg.UserID)
+}
+
+// handleCloseTable closes the owner's private table. The hub sends everyone
+// at it, the owner and spectators included, back to the lobby.
+func (c *Client) handleCloseTable() {
+	if _, err := c.Hub.tables.Close(c.table, c.UserID); err != nil {
+		c.rejectTable(c.table.ID, err)
+		return
+	}
+	c.table = nil
+}
+
+// handleWatchTable has the client watch a table as a spectator, with no
+// seat or balance. A seated player gives up their seat to watch.
+func (c *Client) handleWatchTable(msg ClientMessage) {
+	table, err := c.Hub.tables.Enter(msg.TableID, msg.InviteCode, msg.Password, c.UserID)
+	if err != nil {
+		c.rejectTable(msg.TableID, err)
+		return
+	}
+	c.leaveQueue()
+	if c.table != nil {
+		if err := c.table.Manager.ReleaseUser(c.UserID); err != nil {
+			c.rejectTable(c.table.ID, err)
+			return
+		}
+		c.table.Manager.NotifyPlayerLeft(c.UserID)
+		c.table = nil
+	}
+	if table != c.watching {
+		c.stopWatching()
+		c.watching = table
+		table.Manager.AddSpectator()
+	}
+	c.Hub.Watch(c, table.ID)
+
+	c.trySend(mustJSON(messages.WatchingMessage{
+		Type:       "watching",
+		TableID:    table.ID,
+		Players:    table.Manager.GetAllPlayers(),
+		Spectators: table.Manager.SpectatorCount(),
+		Variant:    table.Manager.Wheel().Variant,
+		ZeroRule:   table.Manager.ZeroRule(),
+		LiveDealer: table.Manager.LiveDealer(),
+	}))
+	c.sendTableState(table)
+}
+
+// stopWatching stops the client spectating, if it is. The caller moves it
+// in the hub.
+func (c *Client) stopWatching() {
+	if c.watching == nil {
+		return
+	}
+	c.watching.Manager.RemoveSpectator()
+	c.watching = nil
+}
+
+// handleLeaveTable takes the player, or a spectator, back to the lobby, or
+// the player out of line for a seat. A player's balance stays in the user
+// store for whichever table they join next.
+func (c *Client) handleLeaveTable() {
+	if queued := c.queued; queued != nil {
+		c.leaveQueue()
+		if c.table == nil {
+			c.trySend(mustJSON(messages.TableLeftMessage{Type: "table_left", TableID: queued.ID}))
+			return
+		}
+		// Seated from the line meanwhile: they leave the table.
+	}
+	if c.watching != nil {
+		left := c.watching
+		c.stopWatching()
+		c.Hub.Register(c, "")
+		c.trySend(mustJSON(messages.TableLeftMessage{Type: "table_left", TableID: left.ID}))
+		return
+	}
+	if c.table == nil {
+		return
+	}
+	if err := c.table.Manager.ReleaseUser(c.UserID); err != nil {
+		c.rejectTable(c.table.ID, err)
+		return
+	}
+	left := c.table
+	c.table = nil
+	c.Hub.Register(c, "")
+	left.Manager.NotifyPlayerLeft(c.UserID)
+	c.trySend(mustJSON(messages.TableLeftMessage{Type: "table_left", TableID: left.ID}))
+}
+
+// seatRefused tells the player why they can't sit at table, or puts them
+// in line for a seat if it is full and they are in the lobby. name is the
+// name a new player asked for.
+func (c *Client) seatRefused(tableID string, table *game.Table, name string, err error) {
+	if errors.Is(err, game.ErrTableFull) && table != nil && c.table == nil {
+		c.queueAt(table, name)
+		return
+	}
+	c.rejectTable(tableID, err)
+}
+
+// queueAt puts the player in line for a seat at the full table. They wait
+// in the lobby, and are seated with a welcome at the end of a round once a
+// seat is free.
+func (c *Client) queueAt(table *game.Table, name string) {
+	position, err := table.Manager.Enqueue(c.UserID, name)
+	if err != nil {
+		c.rejectTable(table.ID, err)
+		return
+	}
+	c.stopWatching()
+	c.Hub.Register(c, "")
+	c.queued = table
+	c.registered = true
+	c.trySend(mustJSON(messages.QueuePositionMessage{
+		Type:     "queue_position",
+		TableID:  table.ID,
+		Position: position,
+	}))
+}
+
+// leaveQueue takes the player out of line for a seat, if they are in one.
+// One seated from it meanwhile is at that table now.
+func (c *Client) leaveQueue() {
+	if c.queued == nil {
+		return
+	}
+	if !c.queued.Manager.Dequeue(c.UserID) && c.queued.Manager.GetUser(c.UserID) != nil {
+		c.table = c.queued
+	}
+	c.queued = nil
+}
+
+func (c *Client) rejectTable(tableID string, err error) {
+	c.trySend(mustJSON(messages.TableRejectedMessage{
+		Type:    "table_rejected",
+		TableID: tableID,
+		Reason:  err.Error(),
+	}))
 }
 
 // sendSessionData handles the Welcome and Game State sync sequence
 func (c *Client) sendSessionData() {
-	user := c.Hub.gameManager.GetUser(c.UserID)
-	if user == nil {
+	msgs, ok := sessionData(c.table, c.UserID)
+	if !ok {
 		slog.Error("failed to sync session: user not found", "user_id", c.UserID)
 		return
 	}
+	for _, msg := range msgs {
+		c.trySend(msg)
+	}
+}
+
+// sendTableState syncs the client with the table's game state and recent
+// results.
+func (c *Client) sendTableState(table *game.Table) {
+	for _, msg := range tableState(table) {
+		c.trySend(msg)
+	}
+}
+
+// sessionData builds the Welcome and Game State sync sequence for a player
+// at table. ok is false if they aren't at it.
+func sessionData(table *game.Table, userID string) (msgs [][]byte, ok bool) {
+	user := table.Manager.GetUser(userID)
+	if user == nil {
+		return nil, false
+	}
 
-	c.trySend(mustJSON(messages.WelcomeMessage{
+	welcome := mustJSON(messages.WelcomeMessage{
 		Type:         "welcome",
-		UserID:       c.UserID,
-		SessionToken: c.Hub.gameManager.GetSessionToken(c.UserID),
+		UserID:       userID,
+		SessionToken: table.Manager.GetSessionToken(userID),
+		TableID:      table.ID,
+		OwnerID:      table.Owner(),
 		Balance:      user.Balance,
-		Players:      c.Hub.gameManager.GetAllPlayers(),
-	}))
+		Players:      table.Manager.GetAllPlayers(),
+		Spectators:   table.Manager.SpectatorCount(),
+		Variant:      table.Manager.Wheel().Variant,
+		ZeroRule:     table.Manager.ZeroRule(),
+		LiveDealer:   table.Manager.LiveDealer(),
+	})
+	return append([][]byte{welcome}, tableState(table)...), true
+}
 
-	state, winNum, count := c.Hub.gameManager.GetCurrentGameState()
-	c.trySend(mustJSON(messages.GameStateMessage{
+// tableState builds the table's game state and recent results for a client
+// joining it.
+func tableState(table *game.Table) [][]byte {
+	state, winNum, count := table.Manager.GetCurrentGameState()
+	var winPocket *string
+	if winNum != nil {
+		label := game.PocketLabel(*winNum)
+		winPocket = &label
+	}
+	gameState := messages.GameStateMessage{
 		Type:          "game_state",
 		State:         state,
 		WinningNumber: winNum,
+		WinningPocket: winPocket,
 		Countdown:     count,
-	}))
+		Trajectory:    table.Manager.Trajectory(),
+	}
+	if hash, nonce := table.Manager.Commitment(); hash != "" {
+		gameState.ServerSeedHash = &hash
+		gameState.Nonce = &nonce
+	}
+	if reason := table.Manager.PauseReason(); state == messages.GamePhasePaused && reason != "" {
+		gameState.Reason = &reason
+	}
+	msgs := [][]byte{mustJSON(gameState)}
+
+	results, err := table.Manager.RecentResults(game.HistoryLength)
+	if err != nil {
+		slog.Error("failed to load history", "error", err, "table_id", table.ID)
+		return msgs
+	}
+	return append(msgs, mustJSON(messages.HistoryMessage{Type: "history", Results: results}))
+}
+
+func (c *Client) handleDealerResult(msg ClientMessage) {
+	step := messages.DealerStep(msg.Step)
+	if step != messages.DealerStepEnter && step != messages.DealerStepConfirm {
+		c.trySend(mustJSON(messages.DealerAckMessage{Type: "dealer_ack", Step: step, Reason: "step must be enter or confirm"}))
+		return
+	}
+	c.handleDealerStep(step, msg)
+}
+
+// handleDealerStep lets a croupier enter, confirm or void a live result over
+// the socket. The admin token travels with every action, and the croupier
+// needn't sit at the table: it is the one the message names, or else the
+// one the connection is at or watching, or the first table.
+func (c *Client) handleDealerStep(step messages.DealerStep, msg ClientMessage) {
+	if !c.Hub.tables.Default().Manager.CheckAdminToken(msg.AdminToken) {
+		c.trySend(mustJSON(messages.DealerAckMessage{Type: "dealer_ack", Step: step, Reason: "unauthorized"}))
+		return
+	}
+	table := c.table
+	if table == nil {
+		table = c.watching
+	}
+	if msg.TableID != "" || table == nil {
+		t, err := c.Hub.tables.Lookup(msg.TableID)
+		if err != nil {
+			c.trySend(mustJSON(messages.DealerAckMessage{Type: "dealer_ack", Step: step, Reason: err.Error()}))
+			return
+		}
+		table = t
+	}
+	c.trySend(mustJSON(table.Manager.ApplyDealerStep(step, msg.Pocket, msg.Reason)))
+}
+
+func (c *Client) handleSetClientSeed(msg ClientMessage) {
+	reply := messages.ClientSeedMessage{Type: "client_seed", ClientSeed: msg.ClientSeed}
+	if err := c.table.Manager.SetClientSeed(c.UserID, msg.ClientSeed); err != nil {
+		reply.ClientSeed = c.table.Manager.ClientSeed(c.UserID)
+		reply.Reason = err.Error()
+	}
+	c.trySend(mustJSON(reply))
 }
 
 // handlePlaceBet encapsulates the betting logic and notifications
 func (c *Client) handlePlaceBet(msg ClientMessage) {
-	newBalance, betErr := c.Hub.gameManager.PlaceBet(c.UserID, msg.BetType, msg.BetValue, msg.Amount)
+	bet, newBalance, betErr := c.table.Manager.PlaceBet(c.UserID, msg.BetType, msg.BetValue, msg.Amount)
+	c.notifyBetsPlaced([]game.Bet{bet}, newBalance, betErr)
+}
+
+func (c *Client) handlePlaceBets(msg ClientMessage) {
+	reqs := make([]game.BetRequest, len(msg.Bets))
+	for i, b := range msg.Bets {
+		reqs[i] = game.BetRequest{Type: string(b.BetType), Value: b.BetValue, Amount: b.Amount}
+	}
+	bets, newBalance, err := c.table.Manager.PlaceBets(c.UserID, reqs)
 
+	if err != nil {
+		rejected := messages.BetsRejectedMessage{
+			Type:   "bets_rejected",
+			Code:   game.RejectCode(err),
+			Reason: err.Error(),
+		}
+		var betErr *game.BetError
+		if errors.As(err, &betErr) {
+			rejected.Index = &betErr.Index
+		}
+		c.trySend(mustJSON(rejected))
+		return
+	}
+
+	c.trySend(mustJSON(messages.BetsAcceptedMessage{
+		Type:    "bets_accepted",
+		Bets:    bets,
+		Balance: newBalance,
+	}))
+	c.broadcastBetsPlaced(bets, newBalance)
+}
+
+func (c *Client) handleRebet(double bool) {
+	bets, newBalance, err := c.table.Manager.Rebet(c.UserID, double)
+	c.notifyBetsPlaced(bets, newBalance, err)
+}
+
+// notifyBetsPlaced rejects a failed placement back to the player, or confirms
+// each placed bet to them and shows it to every client at the table.
+func (c *Client) notifyBetsPlaced(bets []game.Bet, newBalance int64, betErr error) {
 	if betErr != nil {
 		c.trySend(mustJSON(messages.BetRejectedMessage{
 			Type:   "bet_rejected",
+			Code:   game.RejectCode(betErr),
 			Reason: betErr.Error(),
 		}))
 		return
 	}
 
 	// Send confirmation back to the bettor
-	c.trySend(mustJSON(messages.BetAcceptedMessage{
-		Type:     "bet_accepted",
-		BetType:  messages.BetType(msg.BetType),
-		BetValue: msg.BetValue,
-		Amount:   msg.Amount,
-		Balance:  newBalance,
-	}))
+	for _, bet := range bets {
+		c.trySend(mustJSON(messages.BetAcceptedMessage{
+			Type:       "bet_accepted",
+			BetID:      bet.ID,
+			BetType:    bet.Type,
+			BetValue:   bet.Value,
+			Amount:     bet.Amount,
+			Components: bet.Components,
+			Balance:    newBalance,
+		}))
+	}
+	c.broadcastBetsPlaced(bets, newBalance)
+}
 
-	// Broadcast the bet details to everyone else
-	c.Hub.BroadcastToAll(mustJSON(messages.BetPlacedMessage{
-		Type:       "bet_placed",
-		UserID:     c.UserID,
-		PlayerName: c.Hub.gameManager.GetUserName(c.UserID),
-		BetType:    messages.BetType(msg.BetType),
-		BetValue:   msg.BetValue,
-		Amount:     msg.Amount,
-	}))
+// broadcastBetsPlaced shows newly placed bets and the bettor's balance to
+// every client at the table.
+func (c *Client) broadcastBetsPlaced(bets []game.Bet, newBalance int64) {
+	playerName := c.table.Manager.GetUserName(c.UserID)
+	for _, bet := range bets {
+		c.Hub.BroadcastToTable(c.table.ID, mustJSON(messages.BetPlacedMessage{
+			Type:       "bet_placed",
+			BetID:      bet.ID,
+			UserID:     c.UserID,
+			PlayerName: playerName,
+			BetType:    bet.Type,
+			BetValue:   bet.Value,
+			Amount:     bet.Amount,
+			Components: bet.Components,
+		}))
+	}
 
 	// Sync balance update to all client UI lists
-	c.Hub.gameManager.NotifyBalanceUpdated(c.UserID, newBalance)
+	c.table.Manager.NotifyBalanceUpdated(c.UserID, newBalance)
+}
+
+func (c *Client) handleCancelBet(msg ClientMessage) {
+	bet, balance, err := c.table.Manager.CancelBet(c.UserID, msg.BetID)
+	c.notifyBetsRemoved([]game.Bet{bet}, balance, err)
+}
+
+func (c *Client) handleUndoLastBet() {
+	bet, balance, err := c.table.Manager.UndoLastBet(c.UserID)
+	c.notifyBetsRemoved([]game.Bet{bet}, balance, err)
+}
+
+func (c *Client) handleClearBets() {
+	bets, balance, err := c.table.Manager.ClearBets(c.UserID)
+	c.notifyBetsRemoved(bets, balance, err)
+}
+
+// notifyBetsRemoved rejects a failed removal back to the player, or tells
+// every client at the table which bets came off it.
+func (c *Client) notifyBetsRemoved(bets []game.Bet, balance int64, err error) {
+	if err != nil {
+		c.trySend(mustJSON(messages.BetRejectedMessage{
+			Type:   "bet_rejected",
+			Code:   game.RejectCode(err),
+			Reason: err.Error(),
+		}))
+		return
+	}
+
+	ids := make([]string, len(bets))
+	for i, b := range bets {
+		ids[i] = b.ID
+	}
+	c.Hub.BroadcastToTable(c.table.ID, mustJSON(messages.BetRemovedMessage{
+		Type:    "bet_removed",
+		UserID:  c.UserID,
+		BetIDs:  ids,
+		Balance: balance,
+	}))
+
+	c.table.Manager.NotifyBalanceUpdated(c.UserID, balance)
 }
@@ -0,0 +1,226 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"roulette/internal/game"
+	"roulette/internal/messages"
+)
+
+// newTestHub gives a running hub over tables a and b, which share the users
+// and the ledger. Table b has one seat and a live dealer.
+func newTestHub(t *testing.T) (*Hub, *game.Table, *game.Table) {
+	t.Helper()
+	hub := NewHub()
+	go hub.Run()
+	t.Cleanup(hub.Stop)
+
+	users := game.NewMemoryUserStore()
+	ledger := game.NewMemoryLedger()
+	tables := game.NewTableRegistry()
+	for _, id := range []string{"a", "b"} {
+		m := game.NewManager(func([]byte) {}, func(string, []byte) {})
+		t.Cleanup(func() { m.Stop() })
+		m.SetUserStore(users)
+		m.SetLedger(ledger)
+		m.SetAdminToken("secret")
+		if id == "b" {
+			m.SetMaxSeats(1)
+			m.SetLiveDealer(time.Minute)
+		}
+		if _, err := tables.Add(id, id, m); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	hub.SetTables(tables)
+	a, _ := tables.Get("a")
+	b, _ := tables.Get("b")
+	return hub, a, b
+}
+
+// seatedClient connects userID and sits them down at table.
+func seatedClient(t *testing.T, hub *Hub, userID string, table *game.Table) *Client {
+	t.Helper()
+	c := NewClient(hub, nil, userID)
+	c.handleSetName(ClientMessage{Action: "set_name", Name: userID, TableID: table.ID})
+	if c.table != table {
+		t.Fatalf("expected %s seated at %s, got %v", userID, table.ID, c.table)
+	}
+	drain(c)
+	return c
+}
+
+// serverMessage holds the fields of a server message the tests look at.
+type serverMessage struct {
+	Type     string `json:"type"`
+	TableID  string `json:"table_id"`
+	Position int    `json:"position"`
+	Balance  int64  `json:"balance"`
+	Reason   string `json:"reason"`
+}
+
+// drain returns the messages waiting for the client, oldest first.
+func drain(c *Client) []serverMessage {
+	var msgs []serverMessage
+	for {
+		select {
+		case data := <-c.Send:
+			var msg serverMessage
+			json.Unmarshal(data, &msg)
+			msgs = append(msgs, msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// find returns the first of msgs of type typ.
+func find(msgs []serverMessage, typ string) (serverMessage, bool) {
+	for _, msg := range msgs {
+		if msg.Type == typ {
+			return msg, true
+		}
+	}
+	return serverMessage{}, false
+}
+
+func TestClient_JoinTableKeepsBalance(t *testing.T) {
+	hub, a, b := newTestHub(t)
+	c := seatedClient(t, hub, "u1", a)
+	if _, err := a.Manager.AdjustBalance("u1", 250, "bonus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "b"})
+	if c.table != b || a.Manager.GetUser("u1") != nil {
+		t.Fatalf("expected u1 moved from a to b, at %v", c.table)
+	}
+	welcome, ok := find(drain(c), "welcome")
+	if !ok || welcome.TableID != "b" || welcome.Balance != game.StartingBalance+250 {
+		t.Errorf("expected a welcome to b with the balance kept, got %+v", welcome)
+	}
+}
+
+func TestClient_JoinSameTable(t *testing.T) {
+	hub, a, _ := newTestHub(t)
+	c := seatedClient(t, hub, "u1", a)
+
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "a"})
+	msgs := drain(c)
+	if rejected, ok := find(msgs, "table_rejected"); ok {
+		t.Errorf("expected joining the same table to be allowed, got %+v", rejected)
+	}
+	if c.table != a || a.Manager.GetUser("u1") == nil {
+		t.Errorf("expected u1 still at a, at %v", c.table)
+	}
+}
+
+func TestClient_JoinUnknownTable(t *testing.T) {
+	hub, a, _ := newTestHub(t)
+	c := seatedClient(t, hub, "u1", a)
+
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "nope"})
+	if _, ok := find(drain(c), "table_rejected"); !ok {
+		t.Error("expected the join rejected")
+	}
+	if c.table != a || a.Manager.GetUser("u1") == nil {
+		t.Errorf("expected u1 still at a, at %v", c.table)
+	}
+}
+
+func TestClient_FullTable(t *testing.T) {
+	hub, a, b := newTestHub(t)
+	seatedClient(t, hub, "u2", b)
+	c := seatedClient(t, hub, "u1", a)
+
+	// A seated player stays where they are.
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "b"})
+	if _, ok := find(drain(c), "table_rejected"); !ok || c.table != a || c.queued != nil {
+		t.Fatalf("expected the move refused and u1 left at a, at %v, queued at %v", c.table, c.queued)
+	}
+
+	// From the lobby they wait in line.
+	c.handleLeaveTable()
+	drain(c)
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "b"})
+	queued, ok := find(drain(c), "queue_position")
+	if !ok || queued.TableID != "b" || queued.Position != 1 || c.queued != b || c.table != nil {
+		t.Fatalf("expected u1 first in line for b, got %+v", queued)
+	}
+
+	// Asking for the same table again keeps their place.
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "b"})
+	if queued, ok := find(drain(c), "queue_position"); !ok || queued.Position != 1 || c.queued != b {
+		t.Errorf("expected u1 still first in line, got %+v", queued)
+	}
+}
+
+func TestClient_MovingGivesUpPlaceInLine(t *testing.T) {
+	hub, a, b := newTestHub(t)
+	seatedClient(t, hub, "u2", b)
+	c := NewClient(hub, nil, "u1")
+	c.handleSetName(ClientMessage{Action: "set_name", Name: "u1", TableID: "b"})
+	if _, ok := find(drain(c), "queue_position"); !ok || c.queued != b {
+		t.Fatalf("expected a new player queued for the full table, queued at %v", c.queued)
+	}
+
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "a"})
+	if c.table != a || c.queued != nil {
+		t.Errorf("expected u1 seated at a and out of line, at %v, queued at %v", c.table, c.queued)
+	}
+	if pos := b.Manager.QueuePosition("u1"); pos != 0 {
+		t.Errorf("expected u1 out of b's line, got position %d", pos)
+	}
+}
+
+func TestClient_WatchGivesUpSeat(t *testing.T) {
+	hub, a, b := newTestHub(t)
+	c := seatedClient(t, hub, "u1", a)
+
+	c.handleWatchTable(ClientMessage{Action: "watch_table", TableID: "b"})
+	if _, ok := find(drain(c), "watching"); !ok || c.table != nil || c.watching != b {
+		t.Fatalf("expected u1 watching b, at %v, watching %v", c.table, c.watching)
+	}
+	if a.Manager.GetUser("u1") != nil || b.Manager.SpectatorCount() != 1 {
+		t.Errorf("expected u1's seat at a given up and one spectator at b")
+	}
+
+	// Sitting down stops them watching.
+	c.handleJoinTable(ClientMessage{Action: "join_table", TableID: "b"})
+	if c.table != b || c.watching != nil || b.Manager.SpectatorCount() != 0 {
+		t.Errorf("expected u1 seated at b and no longer watching, at %v, watching %v", c.table, c.watching)
+	}
+}
+
+func TestClient_DealerWithoutSeat(t *testing.T) {
+	hub, a, b := newTestHub(t)
+	c := NewClient(hub, nil, "croupier")
+
+	c.handleDealerStep(messages.DealerStepVoid, ClientMessage{Action: "dealer_void", AdminToken: "wrong", TableID: "b"})
+	if ack, ok := find(drain(c), "dealer_ack"); !ok || ack.Reason != "unauthorized" {
+		t.Errorf("expected a wrong token refused, got %+v", ack)
+	}
+
+	// Table b is live, so the void reaches its dealer rather than being
+	// refused for want of a seat; no round is waiting yet.
+	c.handleDealerStep(messages.DealerStepVoid, ClientMessage{Action: "dealer_void", AdminToken: "secret", TableID: "b"})
+	if ack, ok := find(drain(c), "dealer_ack"); !ok || ack.Reason != game.ErrNotAwaitingResult.Error() {
+		t.Errorf("expected the void to reach table b, got %+v", ack)
+	}
+
+	// Without a table ID it goes to the first table, which isn't live.
+	c.handleDealerStep(messages.DealerStepVoid, ClientMessage{Action: "dealer_void", AdminToken: "secret"})
+	if ack, ok := find(drain(c), "dealer_ack"); !ok || ack.Reason != game.ErrNotLiveDealer.Error() {
+		t.Errorf("expected the void to reach table a, got %+v", ack)
+	}
+
+	c.handleDealerStep(messages.DealerStepVoid, ClientMessage{Action: "dealer_void", AdminToken: "secret", TableID: "nope"})
+	if ack, ok := find(drain(c), "dealer_ack"); !ok || ack.Reason == "" {
+		t.Errorf("expected an unknown table refused, got %+v", ack)
+	}
+	if a.Manager.GetUser("croupier") != nil || b.Manager.GetUser("croupier") != nil {
+		t.Error("expected the croupier not seated anywhere")
+	}
+}
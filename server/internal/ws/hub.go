@@ -4,38 +4,72 @@ import (
 	"sync"
 
 	"roulette/internal/game"
+	"roulette/internal/messages"
 )
 
 type Hub struct {
-	clients       map[*Client]bool
+	clients       map[*Client]string // the ID of the table each client is at; "" for the lobby
 	clientsByUser map[string]*Client
-	broadcastAll  chan []byte
-	register      chan *Client
+	spectators    map[*Client]bool // clients watching their table rather than seated at it
+	broadcast     chan tableMessage
+	register      chan registration
 	unregister    chan *Client
 	done          chan struct{}
 	mu            sync.RWMutex
-	gameManager   *game.Manager
+	tables        *game.TableRegistry
+}
+
+// tableMessage is a message for every client at one table, or only for
+// those watching it.
+type tableMessage struct {
+	tableID        string
+	msg            []byte
+	spectatorsOnly bool
+}
+
+// registration seats a client at a table, or in the lobby, or has it watch
+// a table.
+type registration struct {
+	client    *Client
+	tableID   string
+	spectator bool
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		clients:       make(map[*Client]bool),
+		clients:       make(map[*Client]string),
 		clientsByUser: make(map[string]*Client),
-		broadcastAll:  make(chan []byte, 256),
-		register:      make(chan *Client),
+		spectators:    make(map[*Client]bool),
+		broadcast:     make(chan tableMessage, 256),
+		register:      make(chan registration),
 		unregister:    make(chan *Client),
 		done:          make(chan struct{}),
 	}
 }
 
-// SetGameManager sets the game manager on the hub.
-func (h *Hub) SetGameManager(gm *game.Manager) {
-	h.gameManager = gm
+// SetTables sets the tables the hub's clients play at, and sends whoever is
+// at a private table back to the lobby when it is torn down.
+func (h *Hub) SetTables(tables *game.TableRegistry) {
+	h.tables = tables
+	tables.SetRemoveHook(func(t *game.Table, reason string) {
+		h.CloseTable(t.ID, mustJSON(messages.TableLeftMessage{
+			Type:    "table_left",
+			TableID: t.ID,
+			Reason:  reason,
+		}))
+	})
+}
+
+// Register adds a client to the hub at table tableID, or moves it there if
+// it is already registered. An empty tableID puts the client in the lobby.
+func (h *Hub) Register(c *Client, tableID string) {
+	h.register <- registration{client: c, tableID: tableID}
 }
 
-// Register adds a client to the hub.
-func (h *Hub) Register(c *Client) {
-	h.register <- c
+// Watch has a client watch table tableID as a spectator, moving it there
+// if it is already registered.
+func (h *Hub) Watch(c *Client, tableID string) {
+	h.register <- registration{client: c, tableID: tableID, spectator: true}
 }
 
 // Unregister removes a client from the hub.
@@ -43,9 +77,86 @@ func (h *Hub) Unregister(c *Client) {
 	h.unregister <- c
 }
 
-// BroadcastToAll sends a message to all connected clients.
-func (h *Hub) BroadcastToAll(msg []byte) {
-	h.broadcastAll <- msg
+// BroadcastToTable sends a message to every client at table tableID.
+func (h *Hub) BroadcastToTable(tableID string, msg []byte) {
+	h.broadcast <- tableMessage{tableID: tableID, msg: msg}
+}
+
+// BroadcastToSpectators sends a message to every client watching table
+// tableID.
+func (h *Hub) BroadcastToSpectators(tableID string, msg []byte) {
+	h.broadcast <- tableMessage{tableID: tableID, msg: msg, spectatorsOnly: true}
+}
+
+// CloseTable moves every client at table tableID, players and spectators,
+// back to the lobby and sends each of them msg.
+func (h *Hub) CloseTable(tableID string, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client, at := range h.clients {
+		if at != tableID {
+			continue
+		}
+		h.clients[client] = ""
+		delete(h.spectators, client)
+		select {
+		case client.Send <- msg:
+		default:
+			// Client too slow, will be cleaned up
+		}
+	}
+}
+
+// Seated moves the client of a player just seated from the queue at table
+// tableID there and welcomes them.
+func (h *Hub) Seated(tableID, userID string) {
+	table, ok := h.tables.Get(tableID)
+	if !ok {
+		return
+	}
+	msgs, ok := sessionData(table, userID)
+	if !ok {
+		// Gone again before they could be told.
+		return
+	}
+
+	h.mu.Lock()
+	client, ok := h.clientsByUser[userID]
+	if ok {
+		h.clients[client] = tableID
+		delete(h.spectators, client)
+		for _, msg := range msgs {
+			select {
+			case client.Send <- msg:
+			default:
+				// Client too slow, will be cleaned up
+			}
+		}
+	}
+	h.mu.Unlock()
+	if ok {
+		table.Manager.NotifyPlayerJoined(userID)
+	}
+}
+
+// SendToLobby moves the clients of userIDs who are at table tableID back to
+// the lobby and sends each of them msg, as when they are kicked or the table
+// closes.
+func (h *Hub) SendToLobby(tableID string, userIDs []string, msg []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, userID := range userIDs {
+		client, ok := h.clientsByUser[userID]
+		if !ok || h.clients[client] != tableID {
+			continue
+		}
+		h.clients[client] = ""
+		select {
+		case client.Send <- msg:
+		default:
+			// Client too slow, will be cleaned up
+		}
+	}
 }
 
 // SendToUser sends a message to a specific user by their user ID.
@@ -99,13 +210,19 @@ func (h *Hub) Run() {
 				delete(h.clients, client)
 			}
 			h.clientsByUser = make(map[string]*Client)
+			clear(h.spectators)
 			h.mu.Unlock()
 			return
 
-		case client := <-h.register:
+		case reg := <-h.register:
 			h.mu.Lock()
-			h.clients[client] = true
-			h.clientsByUser[client.UserID] = client
+			h.clients[reg.client] = reg.tableID
+			h.clientsByUser[reg.client.UserID] = reg.client
+			if reg.spectator {
+				h.spectators[reg.client] = true
+			} else {
+				delete(h.spectators, reg.client)
+			}
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
@@ -113,17 +230,21 @@ func (h *Hub) Run() {
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
 				delete(h.clientsByUser, client.UserID)
+				delete(h.spectators, client)
 				close(client.Send)
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcastAll:
+		case message := <-h.broadcast:
 			var slowClients []*Client
 
 			h.mu.RLock()
-			for client := range h.clients {
+			for client, tableID := range h.clients {
+				if tableID != message.tableID || message.spectatorsOnly && !h.spectators[client] {
+					continue
+				}
 				select {
-				case client.Send <- message:
+				case client.Send <- message.msg:
 				default:
 					slowClients = append(slowClients, client)
 				}